@@ -0,0 +1,402 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Headers of the chunks identifying the transfer of the message they were split from, which are removed from the
+// reassembled message.
+const (
+	// ChunkTransferIdHeader is the id of the transfer, shared by the chunks of a message
+	ChunkTransferIdHeader = "X-Edgex-Chunk-Transfer-Id"
+	// ChunkIndexHeader is the index of the chunk in the transfer, from 0
+	ChunkIndexHeader = "X-Edgex-Chunk-Index"
+	// ChunkCountHeader is the number of chunks of the transfer
+	ChunkCountHeader = "X-Edgex-Chunk-Count"
+	// ChunkTransferSizeHeader is the size in bytes of the payload of the message
+	ChunkTransferSizeHeader = "X-Edgex-Chunk-Transfer-Size"
+)
+
+const (
+	// DefaultChunkReassemblyTimeout is the time the chunks of a message are waited for by default
+	DefaultChunkReassemblyTimeout = 30 * time.Second
+	// DefaultMaxReassemblySize is the size in bytes of the payloads a subscription reassembles at once by default
+	DefaultMaxReassemblySize = 64 << 20
+
+	// chunkSlotSize is the size in bytes of the slot held for each chunk of a transfer from its first chunk, which is
+	// reserved along with the payload so a forged chunk count can't exceed the MaxReassemblySize
+	chunkSlotSize = 24
+)
+
+var chunkHeaders = []string{ChunkTransferIdHeader, ChunkIndexHeader, ChunkCountHeader, ChunkTransferSizeHeader}
+
+// ChunkingOptions are the Optional properties configuring the chunked transfer of the messages exceeding the size
+// limit of the broker.
+type ChunkingOptions struct {
+	// MaxMessageSize is the size in bytes of the encoded messages from which they are split into chunks, 0 doesn't
+	// split the messages
+	MaxMessageSize int
+	// ChunkReassemblyTimeout is the time the chunks of a message are waited for from its first chunk received
+	ChunkReassemblyTimeout time.Duration
+	// MaxReassemblySize is the size in bytes of the payloads a subscription reassembles at once
+	MaxReassemblySize int
+}
+
+// Chunker splits the messages whose encoding exceeds the MaxMessageSize into chunks small enough to be published,
+// and creates the Reassemblers of the subscriptions. The messages are split after being compressed, encrypted and
+// signed, and reassembled before being verified, decrypted and decompressed. A nil Chunker neither splits nor
+// reassembles the messages.
+type Chunker struct {
+	options ChunkingOptions
+}
+
+// NewChunker creates a Chunker from the ChunkingOptions of the Optional configuration. Returns nil if none of the
+// ChunkingOptions is configured, so the chunks received from the broker are refused rather than reassembled.
+func NewChunker(optional map[string]string) (*Chunker, error) {
+	configured := false
+	for _, property := range []string{MaxMessageSize, ChunkReassemblyTimeout, MaxReassemblySize} {
+		if _, exists := optional[property]; exists {
+			configured = true
+		}
+	}
+	if !configured {
+		return nil, nil
+	}
+
+	options := ChunkingOptions{
+		ChunkReassemblyTimeout: DefaultChunkReassemblyTimeout,
+		MaxReassemblySize:      DefaultMaxReassemblySize,
+	}
+	if err := Load(optional, &options); err != nil {
+		return nil, fmt.Errorf("invalid chunking configuration: %w", err)
+	}
+
+	if options.MaxMessageSize < 0 {
+		return nil, fmt.Errorf("invalid chunking configuration: %s must not be negative", MaxMessageSize)
+	}
+	if options.ChunkReassemblyTimeout <= 0 {
+		return nil, fmt.Errorf("invalid chunking configuration: %s must be positive", ChunkReassemblyTimeout)
+	}
+	if options.MaxReassemblySize <= 0 {
+		return nil, fmt.Errorf("invalid chunking configuration: %s must be positive", MaxReassemblySize)
+	}
+
+	return &Chunker{options: options}, nil
+}
+
+// splits reports whether the Chunker splits the messages exceeding a maximum size.
+func (c *Chunker) splits() bool {
+	return c != nil && c.options.MaxMessageSize > 0
+}
+
+// split returns the chunks of the message whose encoding returned by encodedSize doesn't exceed the MaxMessageSize,
+// or nil if the encoding of the message of size bytes doesn't exceed it. The chunks carry the fields of the message
+// along with a part of its payload.
+func (c *Chunker) split(message types.MessageEnvelope, size int, encodedSize func(types.MessageEnvelope) (int, error)) ([]types.MessageEnvelope, error) {
+	if !c.splits() || size <= c.options.MaxMessageSize {
+		return nil, nil
+	}
+
+	maxSize := c.options.MaxMessageSize
+	payload := message.Payload
+	transferID := uuid.NewString()
+	chunk := func(index int, count int, part []byte) types.MessageEnvelope {
+		chunk := message
+		chunk.Payload = part
+		chunk.Headers = make(map[string][]string, len(message.Headers)+len(chunkHeaders))
+		maps.Copy(chunk.Headers, message.Headers)
+		chunk.Headers[ChunkTransferIdHeader] = []string{transferID}
+		chunk.Headers[ChunkIndexHeader] = []string{strconv.Itoa(index)}
+		chunk.Headers[ChunkCountHeader] = []string{strconv.Itoa(count)}
+		chunk.Headers[ChunkTransferSizeHeader] = []string{strconv.Itoa(len(payload))}
+		return chunk
+	}
+
+	// The size of the chunks is reduced until the encoding of the last chunk, which has the widest headers, fits
+	chunkSize := min(len(payload), maxSize)
+	for {
+		if chunkSize <= 0 {
+			return nil, fmt.Errorf("unable to split message of %d bytes into chunks: the fields of the message exceed the maximum message size of %d bytes", size, maxSize)
+		}
+
+		count := (len(payload) + chunkSize - 1) / chunkSize
+		chunkEncodedSize, err := encodedSize(chunk(count-1, count, payload[:chunkSize]))
+		if err != nil {
+			return nil, err
+		}
+		if chunkEncodedSize <= maxSize {
+			break
+		}
+
+		chunkSize -= chunkEncodedSize - maxSize
+	}
+
+	count := (len(payload) + chunkSize - 1) / chunkSize
+	chunks := make([]types.MessageEnvelope, 0, count)
+	for index := 0; index < count; index++ {
+		end := min((index+1)*chunkSize, len(payload))
+		chunks = append(chunks, chunk(index, count, payload[index*chunkSize:end]))
+	}
+
+	return chunks, nil
+}
+
+// NewReassembler creates a Reassembler for the chunked messages received by the subscription to the topic, or nil
+// if the Chunker is nil.
+func (c *Chunker) NewReassembler(topic string) *Reassembler {
+	if c == nil {
+		return nil
+	}
+
+	return &Reassembler{
+		topic:     topic,
+		timeout:   c.options.ChunkReassemblyTimeout,
+		maxSize:   c.options.MaxReassemblySize,
+		transfers: make(map[string]*transfer),
+	}
+}
+
+// EncodeChunks encodes the message published on the topic, split into chunks by the Chunker of the processor if its
+// encoding exceeds the MaxMessageSize, so each encoded chunk is published as a message of the broker.
+func EncodeChunks(p *EnvelopeProcessor, message types.MessageEnvelope, topic string, encode func(types.MessageEnvelope) ([]byte, error)) ([][]byte, error) {
+	return EncodeChunksWithSize(p, message, topic, encode, func(data []byte) int { return len(data) })
+}
+
+// EncodeChunksWithSize is EncodeChunks for the encodings whose size is returned by size.
+func EncodeChunksWithSize[T any](p *EnvelopeProcessor, message types.MessageEnvelope, topic string, encode func(types.MessageEnvelope) (T, error), size func(T) int) ([]T, error) {
+	encoded, err := encode(message)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := p.chunker.split(message, size(encoded), func(chunk types.MessageEnvelope) (int, error) {
+		encoded, err := encode(chunk)
+		if err != nil {
+			return 0, err
+		}
+		return size(encoded), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chunks == nil {
+		return []T{encoded}, nil
+	}
+
+	encodedChunks := make([]T, 0, len(chunks))
+	for _, chunk := range chunks {
+		encoded, err := encode(chunk)
+		if err != nil {
+			return nil, err
+		}
+		encodedChunks = append(encodedChunks, encoded)
+	}
+
+	recordChunks(topic, len(chunks))
+	return encodedChunks, nil
+}
+
+// SplitChunks returns the message published on the topic split into chunks by the Chunker of the processor if its
+// encoding, whose size is returned by encodedSize, exceeds the MaxMessageSize, or the message alone. It's meant for the
+// clients encoding the messages as they publish them, the messages are only encoded by encodedSize when the Chunker
+// splits the messages.
+func SplitChunks(p *EnvelopeProcessor, message types.MessageEnvelope, topic string, encodedSize func(types.MessageEnvelope) (int, error)) ([]types.MessageEnvelope, error) {
+	if !p.chunker.splits() {
+		return []types.MessageEnvelope{message}, nil
+	}
+
+	size, err := encodedSize(message)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := p.chunker.split(message, size, encodedSize)
+	if err != nil {
+		return nil, err
+	}
+	if chunks == nil {
+		return []types.MessageEnvelope{message}, nil
+	}
+
+	recordChunks(topic, len(chunks))
+	return chunks, nil
+}
+
+// transfer holds the chunks of a message received so far.
+type transfer struct {
+	chunks   [][]byte
+	received int
+	length   int
+	size     int
+	// reserved is the memory reserved for the transfer, its size along with the slots of its chunks
+	reserved int
+	expires  time.Time
+	// dropped transfers are remembered until they expire, so their remaining chunks are discarded
+	dropped bool
+}
+
+// Reassembler reassembles the chunked messages received by a subscription. The chunks of a message are held until
+// all of them are received, within the ChunkReassemblyTimeout from the first one, and the payloads held at once are
+// limited to the MaxReassemblySize. The messages which time out are dropped. A Reassembler is safe for concurrent use.
+// A nil Reassembler refuses the chunks.
+type Reassembler struct {
+	topic     string
+	timeout   time.Duration
+	maxSize   int
+	mutex     sync.Mutex
+	transfers map[string]*transfer
+	// size is the memory reserved for the transfers in progress from their first chunk
+	size int
+}
+
+// Add adds the received message to its transfer if it's a chunk, and replaces it with the reassembled message once
+// all the chunks of the transfer are received. Returns whether the message is complete, i.e. isn't a chunk or is the
+// reassembled message, or an error if the chunk is invalid or its message exceeds the MaxReassemblySize.
+func (r *Reassembler) Add(message *types.MessageEnvelope) (bool, error) {
+	if len(message.Headers[ChunkTransferIdHeader]) == 0 {
+		return true, nil
+	}
+
+	if r == nil {
+		return false, fmt.Errorf("chunk received on topic '%s' refused: chunking isn't configured", message.ReceivedTopic)
+	}
+
+	transferID, index, count, size, err := parseChunkHeaders(message.Headers)
+	if err != nil {
+		return false, fmt.Errorf("invalid chunk received on topic '%s': %w", message.ReceivedTopic, err)
+	}
+	if len(message.Payload) == 0 {
+		return false, fmt.Errorf("invalid chunk received on topic '%s': empty payload", message.ReceivedTopic)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	r.expire(now)
+
+	t, exists := r.transfers[transferID]
+	if !exists {
+		t = &transfer{expires: now.Add(r.timeout)}
+		r.transfers[transferID] = t
+		// The size and count come from the chunk headers, so they are bounded before computing the reserved memory
+		// which could otherwise overflow
+		if size > r.maxSize || count > r.maxSize/chunkSlotSize || size+count*chunkSlotSize > r.maxSize-r.size {
+			t.dropped = true
+			recordChunkTransferDropped(r.topic)
+			return false, fmt.Errorf("chunked message of %d bytes received on topic '%s' dropped: the reassembly memory of %d bytes is exceeded", size, message.ReceivedTopic, r.maxSize)
+		}
+		reserved := size + count*chunkSlotSize
+		t.chunks = make([][]byte, count)
+		t.size = size
+		t.reserved = reserved
+		r.size += reserved
+	}
+
+	if t.dropped {
+		return false, nil
+	}
+
+	if count != len(t.chunks) || size != t.size {
+		r.drop(transferID, t)
+		return false, fmt.Errorf("invalid chunk received on topic '%s': inconsistent with the previous chunks of transfer '%s'", message.ReceivedTopic, transferID)
+	}
+
+	if t.chunks[index] != nil {
+		// The chunk was redelivered by the broker
+		return false, nil
+	}
+
+	t.chunks[index] = message.Payload
+	t.received++
+	t.length += len(message.Payload)
+	if t.length > t.size || (t.received == len(t.chunks) && t.length != t.size) {
+		r.drop(transferID, t)
+		return false, fmt.Errorf("invalid chunk received on topic '%s': the chunks of transfer '%s' don't match its size of %d bytes", message.ReceivedTopic, transferID, t.size)
+	}
+
+	if t.received < len(t.chunks) {
+		return false, nil
+	}
+
+	delete(r.transfers, transferID)
+	r.size -= t.reserved
+
+	message.Payload = slices.Concat(t.chunks...)
+	for _, header := range chunkHeaders {
+		delete(message.Headers, header)
+	}
+	if len(message.Headers) == 0 {
+		message.Headers = nil
+	}
+
+	return true, nil
+}
+
+// expire drops the transfers which timed out, and forgets the dropped transfers once expired.
+func (r *Reassembler) expire(now time.Time) {
+	for transferID, t := range r.transfers {
+		if now.Before(t.expires) {
+			continue
+		}
+
+		if !t.dropped {
+			r.size -= t.reserved
+			recordChunkTransferDropped(r.topic)
+		}
+		delete(r.transfers, transferID)
+	}
+}
+
+// drop releases the chunks of the transfer, whose remaining chunks are discarded until it expires.
+func (r *Reassembler) drop(transferID string, t *transfer) {
+	r.size -= t.reserved
+	r.transfers[transferID] = &transfer{expires: t.expires, dropped: true}
+	recordChunkTransferDropped(r.topic)
+}
+
+func parseChunkHeaders(headers map[string][]string) (transferID string, index int, count int, size int, err error) {
+	value := func(header string) (int, error) {
+		values := headers[header]
+		if len(values) != 1 {
+			return 0, fmt.Errorf("missing %s header", header)
+		}
+		value, err := strconv.Atoi(values[0])
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid %s header '%s'", header, values[0])
+		}
+		return value, nil
+	}
+
+	transferID = headers[ChunkTransferIdHeader][0]
+	if index, err = value(ChunkIndexHeader); err != nil {
+		return
+	}
+	if count, err = value(ChunkCountHeader); err != nil {
+		return
+	}
+	if size, err = value(ChunkTransferSizeHeader); err != nil {
+		return
+	}
+
+	// Every chunk holds a part of the payload, so there are at most as many chunks as bytes
+	if count == 0 || count > size {
+		err = fmt.Errorf("invalid chunk count %d for %d bytes", count, size)
+	} else if index >= count {
+		err = errors.New("chunk index out of range")
+	}
+
+	return
+}
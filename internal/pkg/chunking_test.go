@@ -0,0 +1,423 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func jsonEncode(message types.MessageEnvelope) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+// reassemblyOptional configures the reassembly of the chunks with the default options.
+var reassemblyOptional = map[string]string{ChunkReassemblyTimeout: DefaultChunkReassemblyTimeout.String()}
+
+func newTestProcessor(t *testing.T, optional map[string]string) *EnvelopeProcessor {
+	processor, err := NewEnvelopeProcessor(optional)
+	require.NoError(t, err)
+	return processor
+}
+
+// decodeChunks decodes the encoded chunks with the codec.
+func decodeChunks(t *testing.T, envelopeCodec codec.Codec, encoded [][]byte) []types.MessageEnvelope {
+	chunks := make([]types.MessageEnvelope, 0, len(encoded))
+	for _, data := range encoded {
+		var chunk types.MessageEnvelope
+		require.NoError(t, envelopeCodec.Decode(data, &chunk))
+		chunk.ReceivedTopic = "edgex/firmware"
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func testChunkedMessage(t *testing.T, size int) types.MessageEnvelope {
+	payload := make([]byte, size)
+	_, err := rand.Read(payload)
+	require.NoError(t, err)
+
+	message := types.NewMessageEnvelopeForRequest(payload, map[string]string{"device": "gateway"})
+	message.Headers = map[string][]string{"route": {"firmware"}}
+	return message
+}
+
+func TestNewChunker(t *testing.T) {
+	tests := []struct {
+		name     string
+		optional map[string]string
+		expected *ChunkingOptions
+		wantErr  bool
+	}{
+		{"not configured", map[string]string{}, nil, false},
+		{"defaults", map[string]string{MaxMessageSize: "0"}, &ChunkingOptions{ChunkReassemblyTimeout: DefaultChunkReassemblyTimeout, MaxReassemblySize: DefaultMaxReassemblySize}, false},
+		{"configured", map[string]string{MaxMessageSize: "1048576", ChunkReassemblyTimeout: "1m", MaxReassemblySize: "1024"}, &ChunkingOptions{MaxMessageSize: 1048576, ChunkReassemblyTimeout: time.Minute, MaxReassemblySize: 1024}, false},
+		{"invalid max message size", map[string]string{MaxMessageSize: "1MB"}, nil, true},
+		{"negative max message size", map[string]string{MaxMessageSize: "-1"}, nil, true},
+		{"invalid timeout", map[string]string{ChunkReassemblyTimeout: "30"}, nil, true},
+		{"zero timeout", map[string]string{ChunkReassemblyTimeout: "0s"}, nil, true},
+		{"zero reassembly size", map[string]string{MaxReassemblySize: "0"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker, err := NewChunker(tt.optional)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, chunker)
+				return
+			}
+			require.NotNil(t, chunker)
+			assert.Equal(t, *tt.expected, chunker.options)
+		})
+	}
+}
+
+func TestEncodeChunks(t *testing.T) {
+	for _, name := range []string{codec.JSON, codec.CBOR, codec.Raw} {
+		t.Run(name, func(t *testing.T) {
+			envelopeCodec, err := codec.Get(name)
+			require.NoError(t, err)
+			processor := newTestProcessor(t, map[string]string{MaxMessageSize: "1024"})
+
+			expected := testChunkedMessage(t, 10000)
+			encoded, err := EncodeChunks(processor, expected, "edgex/firmware", envelopeCodec.Encode)
+			require.NoError(t, err)
+			require.Greater(t, len(encoded), 10)
+			for _, data := range encoded {
+				assert.LessOrEqual(t, len(data), 1024)
+			}
+
+			reassembler := processor.NewReassembler("edgex/#")
+			chunks := decodeChunks(t, envelopeCodec, encoded)
+			// The chunks are reassembled whatever the order they are received in
+			chunks[0], chunks[len(chunks)-1] = chunks[len(chunks)-1], chunks[0]
+			for i, chunk := range chunks {
+				complete, err := reassembler.Add(&chunk)
+				require.NoError(t, err)
+				if i < len(chunks)-1 {
+					require.False(t, complete)
+					continue
+				}
+
+				require.True(t, complete)
+				chunk.ReceivedTopic = ""
+				assert.Equal(t, expected, chunk)
+			}
+			assert.Empty(t, reassembler.transfers)
+			assert.Zero(t, reassembler.size)
+		})
+	}
+}
+
+func TestEncodeChunksNotSplit(t *testing.T) {
+	message := testChunkedMessage(t, 100)
+	expected, err := json.Marshal(message)
+	require.NoError(t, err)
+
+	for name, processor := range map[string]*EnvelopeProcessor{
+		"not configured": newTestProcessor(t, nil),
+		"below maximum":  newTestProcessor(t, map[string]string{MaxMessageSize: "1024"}),
+		"zero processor": new(EnvelopeProcessor),
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := EncodeChunks(processor, message, "edgex/events", jsonEncode)
+			require.NoError(t, err)
+			assert.Equal(t, [][]byte{expected}, encoded)
+
+			// The message is passed to the Reassembler as is
+			received := message
+			complete, err := processor.NewReassembler("edgex/#").Add(&received)
+			require.NoError(t, err)
+			assert.True(t, complete)
+			assert.Equal(t, message, received)
+		})
+	}
+}
+
+func TestEncodeChunksTooSmall(t *testing.T) {
+	processor := newTestProcessor(t, map[string]string{MaxMessageSize: "300"})
+
+	message := testChunkedMessage(t, 1000)
+	message.Headers["padding"] = []string{string(bytes.Repeat([]byte{'a'}, 300))}
+	_, err := EncodeChunks(processor, message, "edgex/events", jsonEncode)
+	require.Error(t, err, "the fields of the message don't fit in a chunk")
+}
+
+func TestSplitChunks(t *testing.T) {
+	encodedSize := func(message types.MessageEnvelope) (int, error) {
+		encoded, err := json.Marshal(message)
+		return len(encoded), err
+	}
+	message := testChunkedMessage(t, 5000)
+
+	chunks, err := SplitChunks(newTestProcessor(t, nil), message, "edgex/events", func(types.MessageEnvelope) (int, error) {
+		require.Fail(t, "the message must not be encoded when it isn't split")
+		return 0, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []types.MessageEnvelope{message}, chunks)
+
+	processor := newTestProcessor(t, map[string]string{MaxMessageSize: "2048"})
+	chunks, err = SplitChunks(processor, message, "edgex/events", encodedSize)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 3)
+
+	reassembler := processor.NewReassembler("edgex/#")
+	for i, chunk := range chunks {
+		size, err := encodedSize(chunk)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, size, 2048)
+		assert.Equal(t, strconv.Itoa(i), chunk.Headers[ChunkIndexHeader][0])
+
+		complete, err := reassembler.Add(&chunk)
+		require.NoError(t, err)
+		assert.Equal(t, i == len(chunks)-1, complete)
+		if complete {
+			assert.Equal(t, message, chunk)
+		}
+	}
+}
+
+// testChunks returns the message split into chunks of the size.
+func testChunks(t *testing.T, message types.MessageEnvelope, size int) []types.MessageEnvelope {
+	chunker := &Chunker{options: ChunkingOptions{MaxMessageSize: size}}
+	chunks, err := chunker.split(message, len(message.Payload)+1, func(chunk types.MessageEnvelope) (int, error) {
+		return len(chunk.Payload), nil
+	})
+	require.NoError(t, err)
+	return chunks
+}
+
+func TestReassemblerDuplicates(t *testing.T) {
+	message := testChunkedMessage(t, 100)
+	chunks := testChunks(t, message, 40)
+	require.Len(t, chunks, 3)
+
+	reassembler := newTestProcessor(t, reassemblyOptional).NewReassembler("edgex/#")
+	for _, chunk := range []types.MessageEnvelope{chunks[0], chunks[0], chunks[1], chunks[1]} {
+		complete, err := reassembler.Add(&chunk)
+		require.NoError(t, err)
+		require.False(t, complete)
+	}
+
+	complete, err := reassembler.Add(&chunks[2])
+	require.NoError(t, err)
+	require.True(t, complete)
+	assert.Equal(t, message.Payload, chunks[2].Payload)
+}
+
+func TestReassemblerTimeout(t *testing.T) {
+	registry := useRegistry(t)
+
+	reassembler := newTestProcessor(t, map[string]string{ChunkReassemblyTimeout: "50ms"}).NewReassembler("edgex/#")
+	chunks := testChunks(t, testChunkedMessage(t, 100), 50)
+
+	complete, err := reassembler.Add(&chunks[0])
+	require.NoError(t, err)
+	require.False(t, complete)
+	assert.Equal(t, 100+2*chunkSlotSize, reassembler.size)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The transfer timed out, so its last chunk starts a transfer which never completes
+	complete, err = reassembler.Add(&chunks[1])
+	require.NoError(t, err)
+	require.False(t, complete)
+	assert.Len(t, reassembler.transfers, 1)
+	assert.Equal(t, float64(1), metricValues(registry)[metrics.ChunkTransfersDropped])
+}
+
+func TestReassemblerMaxSize(t *testing.T) {
+	registry := useRegistry(t)
+
+	reassembler := newTestProcessor(t, map[string]string{MaxReassemblySize: "150"}).NewReassembler("edgex/#")
+	first := testChunks(t, testChunkedMessage(t, 100), 50)
+	second := testChunks(t, testChunkedMessage(t, 100), 50)
+
+	complete, err := reassembler.Add(&first[0])
+	require.NoError(t, err)
+	require.False(t, complete)
+
+	// The second message doesn't fit while the first one is reassembled
+	_, err = reassembler.Add(&second[0])
+	require.Error(t, err)
+	complete, err = reassembler.Add(&second[1])
+	require.NoError(t, err, "the remaining chunks of the dropped message are discarded")
+	require.False(t, complete)
+
+	complete, err = reassembler.Add(&first[1])
+	require.NoError(t, err)
+	require.True(t, complete)
+	assert.Zero(t, reassembler.size)
+	assert.Equal(t, float64(1), metricValues(registry)[metrics.ChunkTransfersDropped])
+}
+
+func TestReassemblerForgedCount(t *testing.T) {
+	chunk := testChunks(t, testChunkedMessage(t, 100), 50)[0]
+	chunk.Headers = map[string][]string{
+		ChunkTransferIdHeader:   {"forged"},
+		ChunkIndexHeader:        {"0"},
+		ChunkCountHeader:        {strconv.Itoa(DefaultMaxReassemblySize)},
+		ChunkTransferSizeHeader: {strconv.Itoa(DefaultMaxReassemblySize)},
+	}
+
+	// The slots of the chunks count against the MaxReassemblySize, so they aren't allocated
+	reassembler := newTestProcessor(t, reassemblyOptional).NewReassembler("edgex/#")
+	complete, err := reassembler.Add(&chunk)
+	require.Error(t, err)
+	assert.False(t, complete)
+	assert.Nil(t, reassembler.transfers["forged"].chunks)
+	assert.Zero(t, reassembler.size)
+}
+
+func TestReassemblerForgedHugeHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		size  int
+	}{
+		// size + count*chunkSlotSize overflows to a value below the MaxReassemblySize
+		{"overflowing reserved memory", 737869762948382065, 737869762948382065},
+		{"maximum size", 1, math.MaxInt},
+		{"maximum count", math.MaxInt, math.MaxInt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk := testChunks(t, testChunkedMessage(t, 100), 50)[0]
+			chunk.Headers = map[string][]string{
+				ChunkTransferIdHeader:   {"forged"},
+				ChunkIndexHeader:        {"0"},
+				ChunkCountHeader:        {strconv.Itoa(tt.count)},
+				ChunkTransferSizeHeader: {strconv.Itoa(tt.size)},
+			}
+
+			reassembler := newTestProcessor(t, reassemblyOptional).NewReassembler("edgex/#")
+			complete, err := reassembler.Add(&chunk)
+			require.ErrorContains(t, err, "the reassembly memory of")
+			assert.False(t, complete)
+			assert.Nil(t, reassembler.transfers["forged"].chunks)
+			assert.Zero(t, reassembler.size)
+		})
+	}
+}
+
+func TestReassemblerNotConfigured(t *testing.T) {
+	reassembler := newTestProcessor(t, nil).NewReassembler("edgex/#")
+	require.Nil(t, reassembler)
+
+	chunks := testChunks(t, testChunkedMessage(t, 100), 50)
+	complete, err := reassembler.Add(&chunks[0])
+	require.Error(t, err)
+	assert.False(t, complete)
+
+	message := testChunkedMessage(t, 100)
+	complete, err = reassembler.Add(&message)
+	require.NoError(t, err)
+	assert.True(t, complete)
+}
+
+func TestReassemblerInvalid(t *testing.T) {
+	chunks := testChunks(t, testChunkedMessage(t, 100), 50)
+	withHeader := func(header string, value string) types.MessageEnvelope {
+		chunk := chunks[0]
+		chunk.Headers = make(map[string][]string)
+		for key, values := range chunks[0].Headers {
+			chunk.Headers[key] = values
+		}
+		chunk.Headers[header] = []string{value}
+		return chunk
+	}
+	empty := chunks[0]
+	empty.Payload = nil
+
+	tests := []struct {
+		name  string
+		chunk types.MessageEnvelope
+	}{
+		{"invalid index", withHeader(ChunkIndexHeader, "first")},
+		{"index out of range", withHeader(ChunkIndexHeader, "2")},
+		{"negative index", withHeader(ChunkIndexHeader, "-1")},
+		{"zero count", withHeader(ChunkCountHeader, "0")},
+		{"count exceeding size", withHeader(ChunkCountHeader, "101")},
+		{"missing size", withHeader(ChunkTransferSizeHeader, "")},
+		{"empty payload", empty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reassembler := newTestProcessor(t, reassemblyOptional).NewReassembler("edgex/#")
+			complete, err := reassembler.Add(&tt.chunk)
+			require.Error(t, err)
+			assert.False(t, complete)
+		})
+	}
+
+	t.Run("inconsistent chunks", func(t *testing.T) {
+		reassembler := newTestProcessor(t, reassemblyOptional).NewReassembler("edgex/#")
+		_, err := reassembler.Add(&chunks[0])
+		require.NoError(t, err)
+
+		inconsistent := chunks[1]
+		inconsistent.Payload = append(bytes.Clone(inconsistent.Payload), 0)
+		_, err = reassembler.Add(&inconsistent)
+		require.Error(t, err)
+		assert.Zero(t, reassembler.size)
+	})
+}
+
+func TestChunkedEnvelopeProcessor(t *testing.T) {
+	registry := useRegistry(t)
+
+	optional := map[string]string{
+		MaxMessageSize:          "1024",
+		Compression:             GzipCompression,
+		EncryptionKeyId:         "k1",
+		EncryptionKeyPEMBlock:   encryptionKeyPEM("", 32, 1),
+		SigningIdentity:         "core-command",
+		SigningKeyPEMBlock:      hmacKeyPEM("", 32, 2),
+		VerificationKeyPEMBlock: hmacKeyPEM("core-command", 32, 2),
+		SignatureRequired:       "true",
+	}
+	processor := newTestProcessor(t, optional)
+
+	expected := testChunkedMessage(t, 4096)
+	message := expected
+	require.NoError(t, processor.Outgoing(&message, "edgex/firmware"))
+	encoded, err := EncodeChunks(processor, message, "edgex/firmware", jsonEncode)
+	require.NoError(t, err)
+	require.Greater(t, len(encoded), 1)
+	assert.Equal(t, float64(len(encoded)), metricValues(registry)[metrics.ChunksPublished])
+
+	reassembler := processor.NewReassembler("edgex/#")
+	jsonCodec, err := codec.Get(codec.JSON)
+	require.NoError(t, err)
+	for _, chunk := range decodeChunks(t, jsonCodec, encoded) {
+		complete, err := reassembler.Add(&chunk)
+		require.NoError(t, err)
+		if !complete {
+			continue
+		}
+
+		// The signature covers the reassembled message, whose chunk headers are removed
+		require.NoError(t, processor.Incoming(&chunk))
+		assert.Equal(t, expected.Payload, chunk.Payload)
+		assert.Equal(t, expected.Headers, chunk.Headers)
+		assert.Equal(t, "core-command", chunk.Signer)
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// GzipCompression is the name of the gzip compression
+	GzipCompression = "gzip"
+	// ZstdCompression is the name of the Zstandard compression
+	ZstdCompression = "zstd"
+	// NoCompression disables the compression
+	NoCompression = "none"
+
+	// DefaultCompressionThreshold is the size in bytes from which the payloads are compressed by default
+	DefaultCompressionThreshold = 1024
+	// maxDecompressedSize limits the size of the decompressed payloads, so a small compressed payload can't exhaust
+	// the memory of the receivers
+	maxDecompressedSize = 256 << 20
+)
+
+// compressedBinaryMagic prefixes the compressed binary data, followed by the length of the name of the compression
+// as a byte, the name, then the compressed data. The leading zero byte makes it unlikely to be legacy binary data.
+var compressedBinaryMagic = []byte("\x00EXZ1")
+
+var errDecompressedTooLarge = errors.New("decompressed payload too large")
+
+// compressionAlgorithm compresses and decompresses the payloads.
+type compressionAlgorithm interface {
+	compress(data []byte) ([]byte, error)
+	decompress(data []byte) ([]byte, error)
+}
+
+var compressionAlgorithms = map[string]compressionAlgorithm{
+	GzipCompression: gzipAlgorithm{maxSize: maxDecompressedSize},
+	ZstdCompression: &zstdAlgorithm{maxSize: maxDecompressedSize},
+}
+
+// gzipAlgorithm refuses to decompress payloads larger than maxSize.
+type gzipAlgorithm struct {
+	maxSize int
+}
+
+func (gzipAlgorithm) compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (g gzipAlgorithm) decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(g.maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > g.maxSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", errDecompressedTooLarge, g.maxSize)
+	}
+
+	return decompressed, nil
+}
+
+// zstdAlgorithm shares an encoder and a decoder, which are safe for concurrent use, created on first use. The decoder
+// refuses to decompress payloads larger than maxSize.
+type zstdAlgorithm struct {
+	maxSize     int
+	encoderOnce sync.Once
+	encoder     *zstd.Encoder
+	decoderOnce sync.Once
+	decoder     *zstd.Decoder
+}
+
+func (z *zstdAlgorithm) compress(data []byte) ([]byte, error) {
+	z.encoderOnce.Do(func() {
+		// The options are valid, so creating the encoder can't fail
+		z.encoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	})
+
+	return z.encoder.EncodeAll(data, nil), nil
+}
+
+func (z *zstdAlgorithm) decompress(data []byte) ([]byte, error) {
+	z.decoderOnce.Do(func() {
+		// The options are valid, so creating the decoder can't fail
+		z.decoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(uint64(z.maxSize)))
+	})
+
+	decompressed, err := z.decoder.DecodeAll(data, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", errDecompressedTooLarge, z.maxSize)
+	}
+
+	return decompressed, err
+}
+
+// CompressionOptions are the Optional properties configuring the compression of the payloads.
+type CompressionOptions struct {
+	// Compression is the name of the compression of the published payloads, gzip or zstd
+	Compression string
+	// CompressionThreshold is the size in bytes from which the published payloads are compressed
+	CompressionThreshold int
+}
+
+// Compressor compresses the payload of the published messages and binary data from a size threshold, as long as it
+// makes them smaller. The received payloads are decompressed whatever the configured compression, so the payloads of
+// publishers configured with another compression or without compression are received as is.
+type Compressor struct {
+	name      string
+	algorithm compressionAlgorithm
+	threshold int
+}
+
+// NewCompressor creates a Compressor from the CompressionOptions of the Optional configuration. Returns nil if no
+// compression is configured, which doesn't compress the published payloads.
+func NewCompressor(optional map[string]string) (*Compressor, error) {
+	options := CompressionOptions{CompressionThreshold: DefaultCompressionThreshold}
+	if err := Load(optional, &options); err != nil {
+		return nil, fmt.Errorf("invalid compression configuration: %w", err)
+	}
+
+	name := strings.ToLower(options.Compression)
+	if name == "" || name == NoCompression {
+		return nil, nil
+	}
+
+	algorithm, exists := compressionAlgorithms[name]
+	if !exists {
+		return nil, fmt.Errorf("invalid compression configuration: unknown compression '%s'", options.Compression)
+	}
+
+	if options.CompressionThreshold < 0 {
+		return nil, fmt.Errorf("invalid compression configuration: %s must not be negative", CompressionThreshold)
+	}
+
+	return &Compressor{name: name, algorithm: algorithm, threshold: options.CompressionThreshold}, nil
+}
+
+// Compress compresses the payload of the message published on the topic if it reaches the threshold, and sets the
+// ContentEncoding of the message. The message is unchanged if the Compressor is nil, the payload is already
+// encoded, or compressing doesn't make it smaller.
+func (c *Compressor) Compress(message *types.MessageEnvelope, topic string) error {
+	if c == nil || message.ContentEncoding != "" {
+		return nil
+	}
+
+	compressed, err := c.compress(message.Payload, topic)
+	if err != nil || compressed == nil {
+		return err
+	}
+
+	message.Payload = compressed
+	message.ContentEncoding = c.name
+
+	return nil
+}
+
+// CompressBinary returns the binary data published on the topic, compressed and framed with compressedBinaryMagic if
+// it reaches the threshold. The data is returned as is if the Compressor is nil or compressing doesn't make it smaller.
+func (c *Compressor) CompressBinary(data []byte, topic string) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+
+	compressed, err := c.compress(data, topic)
+	if err != nil || compressed == nil {
+		return data, err
+	}
+
+	framed := make([]byte, 0, len(compressedBinaryMagic)+1+len(c.name)+len(compressed))
+	framed = append(framed, compressedBinaryMagic...)
+	framed = append(framed, byte(len(c.name)))
+	framed = append(framed, c.name...)
+	framed = append(framed, compressed...)
+
+	return framed, nil
+}
+
+// compress returns the compressed data, nil if it is below the threshold or compressing doesn't make it smaller.
+func (c *Compressor) compress(data []byte, topic string) ([]byte, error) {
+	if len(data) < c.threshold || len(data) == 0 {
+		return nil, nil
+	}
+
+	compressed, err := c.algorithm.compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compress payload with %s: %w", c.name, err)
+	}
+
+	if len(compressed) >= len(data) {
+		return nil, nil
+	}
+
+	recordCompression(topic, len(data), len(compressed))
+	return compressed, nil
+}
+
+// Decompress decompresses the payload of the received message according to its ContentEncoding, and clears the
+// ContentEncoding. Returns an error if the compression is unknown or the payload can't be decompressed.
+func Decompress(message *types.MessageEnvelope) error {
+	if message.ContentEncoding == "" {
+		return nil
+	}
+
+	payload, err := decompress(message.ContentEncoding, message.Payload)
+	if err != nil {
+		return err
+	}
+
+	message.Payload = payload
+	message.ContentEncoding = ""
+
+	return nil
+}
+
+// DecompressBinary returns the received binary data decompressed if it was compressed by CompressBinary, as is
+// otherwise.
+func DecompressBinary(data []byte) ([]byte, error) {
+	framed, found := bytes.CutPrefix(data, compressedBinaryMagic)
+	if !found {
+		return data, nil
+	}
+
+	if len(framed) == 0 || len(framed) < 1+int(framed[0]) {
+		return nil, errors.New("unable to decompress binary data: invalid compression frame")
+	}
+
+	name := string(framed[1 : 1+framed[0]])
+	return decompress(name, framed[1+len(name):])
+}
+
+func decompress(name string, data []byte) ([]byte, error) {
+	algorithm, exists := compressionAlgorithms[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unable to decompress payload: unknown compression '%s'", name)
+	}
+
+	decompressed, err := algorithm.decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress %s payload: %w", name, err)
+	}
+
+	return decompressed, nil
+}
@@ -0,0 +1,253 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func newTestCompressor(t *testing.T, optional map[string]string) *Compressor {
+	compressor, err := NewCompressor(optional)
+	require.NoError(t, err)
+	require.NotNil(t, compressor)
+	return compressor
+}
+
+func TestNewCompressor(t *testing.T) {
+	tests := []struct {
+		name          string
+		optional      map[string]string
+		wantNil       bool
+		wantThreshold int
+		wantErr       bool
+	}{
+		{"not configured", map[string]string{}, true, 0, false},
+		{"none", map[string]string{Compression: NoCompression}, true, 0, false},
+		{"gzip", map[string]string{Compression: GzipCompression}, false, DefaultCompressionThreshold, false},
+		{"zstd", map[string]string{Compression: "ZSTD", CompressionThreshold: "4096"}, false, 4096, false},
+		{"no threshold", map[string]string{Compression: ZstdCompression, CompressionThreshold: "0"}, false, 0, false},
+		{"unknown compression", map[string]string{Compression: "lz4"}, false, 0, true},
+		{"invalid threshold", map[string]string{Compression: GzipCompression, CompressionThreshold: "large"}, false, 0, true},
+		{"negative threshold", map[string]string{Compression: GzipCompression, CompressionThreshold: "-1"}, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressor, err := NewCompressor(tt.optional)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantNil, compressor == nil)
+			if compressor != nil {
+				assert.Equal(t, tt.wantThreshold, compressor.threshold)
+			}
+		})
+	}
+}
+
+func TestCompressorCompressDecompress(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"reading":1}`), 200)
+
+	for _, name := range []string{GzipCompression, ZstdCompression} {
+		t.Run(name, func(t *testing.T) {
+			compressor := newTestCompressor(t, map[string]string{Compression: name})
+
+			message := types.MessageEnvelope{Payload: payload}
+			require.NoError(t, compressor.Compress(&message, "edgex/events"))
+			assert.Equal(t, name, message.ContentEncoding)
+			assert.Less(t, len(message.Payload), len(payload))
+
+			require.NoError(t, Decompress(&message))
+			assert.Empty(t, message.ContentEncoding)
+			assert.Equal(t, payload, message.Payload)
+		})
+	}
+}
+
+func TestCompressorUnchanged(t *testing.T) {
+	compressor := newTestCompressor(t, map[string]string{Compression: GzipCompression, CompressionThreshold: "64"})
+
+	incompressible := make([]byte, 256)
+	_, err := rand.Read(incompressible)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		message types.MessageEnvelope
+	}{
+		{"below threshold", types.MessageEnvelope{Payload: bytes.Repeat([]byte{'a'}, 63)}},
+		{"incompressible", types.MessageEnvelope{Payload: incompressible}},
+		{"already encoded", types.MessageEnvelope{Payload: bytes.Repeat([]byte{'a'}, 256), ContentEncoding: ZstdCompression}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.message
+			require.NoError(t, compressor.Compress(&message, "edgex/events"))
+			assert.Equal(t, tt.message, message)
+		})
+	}
+}
+
+func TestCompressorNil(t *testing.T) {
+	var compressor *Compressor
+
+	message := types.MessageEnvelope{Payload: bytes.Repeat([]byte{'a'}, 4096)}
+	require.NoError(t, compressor.Compress(&message, "edgex/events"))
+	assert.Empty(t, message.ContentEncoding)
+
+	data, err := compressor.CompressBinary(message.Payload, "edgex/binary")
+	require.NoError(t, err)
+	assert.Equal(t, message.Payload, data)
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		message types.MessageEnvelope
+	}{
+		{"unknown compression", types.MessageEnvelope{Payload: []byte("data"), ContentEncoding: "lz4"}},
+		{"invalid gzip", types.MessageEnvelope{Payload: []byte("data"), ContentEncoding: GzipCompression}},
+		{"invalid zstd", types.MessageEnvelope{Payload: []byte("data"), ContentEncoding: ZstdCompression}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.message
+			require.Error(t, Decompress(&message))
+		})
+	}
+}
+
+func TestCompressorBinary(t *testing.T) {
+	data := bytes.Repeat([]byte{0x01, 0x02, 0x03}, 1000)
+
+	for _, name := range []string{GzipCompression, ZstdCompression} {
+		t.Run(name, func(t *testing.T) {
+			compressor := newTestCompressor(t, map[string]string{Compression: name})
+
+			compressed, err := compressor.CompressBinary(data, "edgex/binary")
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(compressed, compressedBinaryMagic))
+			assert.Less(t, len(compressed), len(data))
+
+			decompressed, err := DecompressBinary(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+
+	t.Run("below threshold", func(t *testing.T) {
+		compressor := newTestCompressor(t, map[string]string{Compression: GzipCompression})
+
+		small := data[:100]
+		compressed, err := compressor.CompressBinary(small, "edgex/binary")
+		require.NoError(t, err)
+		assert.Equal(t, small, compressed)
+	})
+
+	t.Run("uncompressed", func(t *testing.T) {
+		decompressed, err := DecompressBinary(data)
+		require.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	})
+}
+
+func TestDecompressBinaryInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty frame", compressedBinaryMagic},
+		{"truncated name", append(append([]byte(nil), compressedBinaryMagic...), 4, 'z')},
+		{"unknown compression", append(append([]byte(nil), compressedBinaryMagic...), append([]byte{3}, "lz4data"...)...)},
+		{"invalid data", append(append([]byte(nil), compressedBinaryMagic...), append([]byte{4}, "zstddata"...)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecompressBinary(tt.data)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+	data := bytes.Repeat([]byte{'a'}, 4096)
+
+	tests := []struct {
+		name      string
+		algorithm func(maxSize int) compressionAlgorithm
+	}{
+		{GzipCompression, func(maxSize int) compressionAlgorithm { return gzipAlgorithm{maxSize: maxSize} }},
+		{ZstdCompression, func(maxSize int) compressionAlgorithm { return &zstdAlgorithm{maxSize: maxSize} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := tt.algorithm(len(data)).compress(data)
+			require.NoError(t, err)
+
+			decompressed, err := tt.algorithm(len(data)).decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+
+			_, err = tt.algorithm(len(data) - 1).decompress(compressed)
+			require.ErrorIs(t, err, errDecompressedTooLarge)
+		})
+	}
+}
+
+func TestCompressionMetrics(t *testing.T) {
+	registry := useRegistry(t)
+
+	compressor := newTestCompressor(t, map[string]string{Compression: ZstdCompression})
+	payload := bytes.Repeat([]byte{'a'}, 2048)
+
+	message := types.MessageEnvelope{Payload: payload}
+	require.NoError(t, compressor.Compress(&message, "edgex/events"))
+	small := types.MessageEnvelope{Payload: payload[:10]}
+	require.NoError(t, compressor.Compress(&small, "edgex/events"))
+
+	values := metricValues(registry)
+	assert.Equal(t, float64(len(payload)), values[metrics.CompressionInputBytes])
+	assert.Equal(t, float64(len(message.Payload)), values[metrics.CompressionOutputBytes])
+	assert.Equal(t, float64(1), values[metrics.CompressionRatio])
+}
+
+func TestEnvelopeProcessorCompression(t *testing.T) {
+	processor, err := NewEnvelopeProcessor(map[string]string{
+		Compression:           GzipCompression,
+		EncryptionKeyId:       "k1",
+		EncryptionKeyPEMBlock: encryptionKeyPEM("", 32, 1),
+	})
+	require.NoError(t, err)
+
+	payload := bytes.Repeat([]byte(`{"reading":1}`), 200)
+	message := types.MessageEnvelope{Payload: payload}
+	require.NoError(t, processor.Outgoing(&message, "test"))
+	assert.Equal(t, GzipCompression, message.ContentEncoding)
+	assert.Equal(t, "k1", message.EncryptionKeyID)
+	// The payload is compressed before being encrypted
+	assert.Less(t, len(message.Payload), len(payload))
+
+	message.ReceivedTopic = "test"
+	require.NoError(t, processor.Incoming(&message))
+	assert.Empty(t, message.ContentEncoding)
+	assert.Equal(t, payload, message.Payload)
+
+	data, err := processor.OutgoingBinary(payload, "test")
+	require.NoError(t, err)
+	data, err = processor.IncomingBinary(data)
+	require.NoError(t, err)
+	assert.Equal(t, payload, data)
+
+	_, err = NewEnvelopeProcessor(map[string]string{Compression: "lz4"})
+	require.Error(t, err)
+}
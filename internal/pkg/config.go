@@ -23,6 +23,7 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"time"
 )
 
 var TlsSchemes = []string{"tcps", "ssl", "tls", "redis", "nats"}
@@ -166,6 +167,16 @@ func Load(config map[string]string, des interface{}) error {
 			continue
 		}
 
+		// time.Duration is an int64 kind, so it must be handled before the kinds
+		if valueField.Type() == reflect.TypeOf(time.Duration(0)) {
+			duration, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			valueField.SetInt(int64(duration))
+			continue
+		}
+
 		switch valueField.Kind() {
 		case reflect.Int:
 			intVal, err := strconv.Atoi(val)
@@ -181,6 +192,12 @@ func Load(config map[string]string, des interface{}) error {
 				return err
 			}
 			valueField.SetBool(boolVal)
+		case reflect.Float64:
+			floatVal, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return err
+			}
+			valueField.SetFloat(floatVal)
 		default:
 			return fmt.Errorf("none supported value type %v ,%v", valueField.Kind(), typeField.Name)
 		}
@@ -0,0 +1,164 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// ConnectionEventsBufferSize is the number of events buffered for the Events channel of a ConnectionTracker. The oldest
+// event is dropped when the buffer is full, so a slow consumer always receives the latest events.
+const ConnectionEventsBufferSize = 32
+
+// ConnectionTracker tracks the state of the connection of a message client and emits the changes as events. The
+// backends report the connection changes from the callbacks of their broker client.
+type ConnectionTracker struct {
+	state types.ConnectionState
+	// lost is set once the connection was lost, until it is re-established or Disconnect is called
+	lost   bool
+	events chan types.ConnectionEvent
+	mutex  sync.Mutex
+}
+
+// NewConnectionTracker creates a ConnectionTracker in the Disconnected state.
+func NewConnectionTracker() *ConnectionTracker {
+	return &ConnectionTracker{
+		state:  types.Disconnected,
+		events: make(chan types.ConnectionEvent, ConnectionEventsBufferSize),
+	}
+}
+
+// State returns the current state of the connection.
+func (t *ConnectionTracker) State() types.ConnectionState {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.state
+}
+
+// IsConnected reports whether the state of the connection is Connected.
+func (t *ConnectionTracker) IsConnected() bool {
+	return t.State() == types.Connected
+}
+
+// Events returns the channel the connection events are emitted on. The channel is shared by all the callers.
+func (t *ConnectionTracker) Events() <-chan types.ConnectionEvent {
+	return t.events
+}
+
+// SetConnected records that the connection is established, emitting a ReconnectedEvent if the connection was lost
+// before or a ConnectedEvent otherwise. It has no effect if the connection is already established.
+func (t *ConnectionTracker) SetConnected() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.state == types.Connected {
+		return
+	}
+
+	t.state = types.Connected
+	recordConnection(1)
+	if t.lost {
+		t.lost = false
+		t.emit(types.ConnectionEvent{Type: types.ReconnectedEvent})
+		return
+	}
+
+	t.emit(types.ConnectionEvent{Type: types.ConnectedEvent})
+}
+
+// SetConnectionLost records that the established connection was lost because of err, or that the attempts to
+// re-establish it were given up. It has no effect if the client is disconnected, i.e. the connection was closed by
+// Disconnect.
+func (t *ConnectionTracker) SetConnectionLost(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.state == types.Disconnected {
+		return
+	}
+
+	t.setNotConnected(types.Disconnected)
+	t.lost = true
+	t.emit(types.ConnectionEvent{Type: types.DisconnectedEvent, Err: err})
+}
+
+// SetConnectFailed records that Connect couldn't establish the connection because of err, for the clients which
+// establish it later on as needed. It emits a DisconnectedEvent like a lost connection, so a ReconnectedEvent is
+// emitted once the connection is established.
+func (t *ConnectionTracker) SetConnectFailed(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.setNotConnected(types.Disconnected)
+	t.lost = true
+	t.emit(types.ConnectionEvent{Type: types.DisconnectedEvent, Err: err})
+}
+
+// SetReconnecting records that the client attempts to re-establish the lost connection. It has no effect unless the
+// connection was lost, i.e. for the retries of the initial Connect.
+func (t *ConnectionTracker) SetReconnecting() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.lost || t.state == types.Connected {
+		return
+	}
+
+	t.setNotConnected(types.Reconnecting)
+	t.emit(types.ConnectionEvent{Type: types.ReconnectingEvent})
+}
+
+// SetDisconnected records that the connection was closed by Disconnect.
+func (t *ConnectionTracker) SetDisconnected() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	wasConnected := t.state != types.Disconnected || t.lost
+	t.setNotConnected(types.Disconnected)
+	t.lost = false
+
+	if wasConnected {
+		t.emit(types.ConnectionEvent{Type: types.DisconnectedEvent})
+	}
+}
+
+// SubscriptionRestored emits a SubscriptionRestoredEvent for the topic, err is set if it couldn't be restored.
+func (t *ConnectionTracker) SubscriptionRestored(topic string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.emit(types.ConnectionEvent{Type: types.SubscriptionRestoredEvent, Topic: topic, Err: err})
+}
+
+// setNotConnected changes the state to one other than Connected, recording the disconnection if the connection was
+// established. Must be called with the mutex locked.
+func (t *ConnectionTracker) setNotConnected(state types.ConnectionState) {
+	if t.state == types.Connected {
+		recordConnection(-1)
+	}
+
+	t.state = state
+}
+
+// emit sends the event without blocking, dropping the oldest event if the buffer is full. Must be called with the
+// mutex locked, which guarantees this is the only sender.
+func (t *ConnectionTracker) emit(event types.ConnectionEvent) {
+	event.Time = time.Now()
+
+	for {
+		select {
+		case t.events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-t.events:
+		default:
+		}
+	}
+}
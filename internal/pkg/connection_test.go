@@ -0,0 +1,118 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// receiveEvents returns the events emitted so far.
+func receiveEvents(tracker *ConnectionTracker) []types.ConnectionEvent {
+	var events []types.ConnectionEvent
+	for {
+		select {
+		case event := <-tracker.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func eventTypes(events []types.ConnectionEvent) []types.ConnectionEventType {
+	eventTypes := make([]types.ConnectionEventType, 0, len(events))
+	for _, event := range events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	return eventTypes
+}
+
+func TestConnectionTracker(t *testing.T) {
+	tracker := NewConnectionTracker()
+	assert.Equal(t, types.Disconnected, tracker.State())
+	assert.False(t, tracker.IsConnected())
+
+	lostErr := errors.New("connection reset")
+
+	tracker.SetReconnecting() // ignored, the connection was never established
+	tracker.SetConnected()
+	tracker.SetConnected() // ignored, already connected
+	assert.True(t, tracker.IsConnected())
+
+	tracker.SetConnectionLost(lostErr)
+	assert.Equal(t, types.Disconnected, tracker.State())
+	tracker.SetReconnecting()
+	assert.Equal(t, types.Reconnecting, tracker.State())
+	tracker.SetConnected()
+	tracker.SubscriptionRestored("edgex/events/#", nil)
+	assert.Equal(t, types.Connected, tracker.State())
+
+	tracker.SetDisconnected()
+	tracker.SetConnectionLost(lostErr) // ignored, disconnected by the client
+	assert.Equal(t, types.Disconnected, tracker.State())
+
+	tracker.SetConnected()
+
+	events := receiveEvents(tracker)
+	assert.Equal(t, []types.ConnectionEventType{
+		types.ConnectedEvent,
+		types.DisconnectedEvent,
+		types.ReconnectingEvent,
+		types.ReconnectedEvent,
+		types.SubscriptionRestoredEvent,
+		types.DisconnectedEvent,
+		types.ConnectedEvent,
+	}, eventTypes(events))
+
+	assert.Equal(t, lostErr, events[1].Err)
+	assert.NoError(t, events[5].Err, "Disconnect has no cause")
+	assert.Equal(t, "edgex/events/#", events[4].Topic)
+	assert.False(t, events[0].Time.IsZero())
+}
+
+func TestConnectionTrackerReconnectFailed(t *testing.T) {
+	tracker := NewConnectionTracker()
+	tracker.SetConnected()
+	tracker.SetConnectionLost(nil)
+	tracker.SetReconnecting()
+
+	closedErr := errors.New("no servers available")
+	tracker.SetConnectionLost(closedErr)
+	assert.Equal(t, types.Disconnected, tracker.State())
+
+	events := receiveEvents(tracker)
+	require.Len(t, events, 4)
+	assert.Equal(t, types.DisconnectedEvent, events[3].Type)
+	assert.Equal(t, closedErr, events[3].Err)
+}
+
+func TestConnectionTrackerConnectFailed(t *testing.T) {
+	tracker := NewConnectionTracker()
+
+	connectErr := errors.New("connection refused")
+	tracker.SetConnectFailed(connectErr)
+	assert.Equal(t, types.Disconnected, tracker.State())
+	tracker.SetConnected()
+	assert.True(t, tracker.IsConnected())
+
+	events := receiveEvents(tracker)
+	assert.Equal(t, []types.ConnectionEventType{types.DisconnectedEvent, types.ReconnectedEvent}, eventTypes(events))
+	assert.Equal(t, connectErr, events[0].Err)
+}
+
+func TestConnectionTrackerDropsOldestEvents(t *testing.T) {
+	tracker := NewConnectionTracker()
+	for i := 0; i < ConnectionEventsBufferSize+5; i++ {
+		tracker.SubscriptionRestored(string(rune('a'+i%26)), nil)
+	}
+
+	events := receiveEvents(tracker)
+	require.Len(t, events, ConnectionEventsBufferSize)
+	assert.Equal(t, string(rune('a'+(ConnectionEventsBufferSize+4)%26)), events[len(events)-1].Topic, "the latest event must be kept")
+}
@@ -21,6 +21,40 @@ const (
 	CertPEMBlock   = "CertPEMBlock"
 	CaPEMBlock     = "CaPEMBlock"
 
+	// Format is the name of the codec the messages are encoded with
+	Format = "Format"
+
+	// Chunked transfer configuration names
+	MaxMessageSize         = "MaxMessageSize"
+	ChunkReassemblyTimeout = "ChunkReassemblyTimeout"
+	MaxReassemblySize      = "MaxReassemblySize"
+	// Payload compression configuration names
+	Compression          = "Compression"
+	CompressionThreshold = "CompressionThreshold"
+
+	// Payload encryption configuration names
+	EncryptionKeyId       = "EncryptionKeyId"
+	EncryptionKeyFile     = "EncryptionKeyFile"
+	EncryptionKeyPEMBlock = "EncryptionKeyPEMBlock"
+	EncryptionStrict      = "EncryptionStrict"
+
+	// Message signing configuration names
+	SigningIdentity         = "SigningIdentity"
+	SigningKeyFile          = "SigningKeyFile"
+	SigningKeyPEMBlock      = "SigningKeyPEMBlock"
+	VerificationKeyFile     = "VerificationKeyFile"
+	VerificationKeyPEMBlock = "VerificationKeyPEMBlock"
+	SignatureRequired       = "SignatureRequired"
+
+	// DeadLetterTopic is the topic the received messages which can't be processed are published to
+	DeadLetterTopic = "DeadLetterTopic"
+
+	// Retry policy configuration names
+	RetryMaxAttempts = "RetryMaxAttempts"
+	RetryBaseBackoff = "RetryBaseBackoff"
+	RetryMaxBackoff  = "RetryMaxBackoff"
+	RetryJitter      = "RetryJitter"
+
 	// MQTT Specifics
 	Qos          = "Qos"
 	KeepAlive    = "KeepAlive"
@@ -29,7 +63,6 @@ const (
 
 	// NATS specifics
 	RetryOnFailedConnect = "RetryOnFailedConnect"
-	Format               = "Format"
 	QueueGroup           = "QueueGroup"
 	ExactlyOnce          = "ExactlyOnce"
 
@@ -0,0 +1,87 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// RunWithContext runs the operation and waits for it to complete or for the context to be done, whichever
+// comes first. The operation keeps running in the background if the context is done first.
+func RunWithContext(ctx context.Context, operation func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- operation()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SubscribeWithContext subscribes to the topics with subscribeWithHandle and unsubscribes the handles it created once
+// the context is done, so the other subscriptions to the same topics are kept. The handles already created are
+// unsubscribed if subscribing to one of the topics fails.
+func SubscribeWithContext(
+	ctx context.Context,
+	subscribeWithHandle func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error),
+	topics []types.TopicChannel,
+	messageErrors chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	subscriptions := make([]types.Subscription, 0, len(topics))
+	for _, topic := range topics {
+		subscription, err := subscribeWithHandle(topic, messageErrors)
+		if err != nil {
+			_ = unsubscribeAll(subscriptions)
+			return err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	UnsubscribeWhenDone(ctx, subscriptions, messageErrors)
+
+	return nil
+}
+
+// UnsubscribeWhenDone unsubscribes the subscription handles once the context is done. Any error from the
+// unsubscribe is sent to the messageErrors channel if a receiver is ready for it.
+func UnsubscribeWhenDone(ctx context.Context, subscriptions []types.Subscription, messageErrors chan error) {
+	// A context which is never done, i.e. context.Background(), would leave the go func waiting forever.
+	if ctx.Done() == nil {
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := unsubscribeAll(subscriptions); err != nil {
+			select {
+			case messageErrors <- err:
+			default:
+			}
+		}
+	}()
+}
+
+func unsubscribeAll(subscriptions []types.Subscription) error {
+	var errs []error
+	for _, subscription := range subscriptions {
+		if err := subscription.Unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
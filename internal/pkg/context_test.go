@@ -0,0 +1,90 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func TestRunWithContext(t *testing.T) {
+	expectedErr := errors.New("operation failed")
+
+	err := RunWithContext(context.Background(), func() error { return nil })
+	require.NoError(t, err)
+
+	err = RunWithContext(context.Background(), func() error { return expectedErr })
+	require.ErrorIs(t, err, expectedErr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	blocked := make(chan struct{})
+	defer close(blocked)
+	err = RunWithContext(ctx, func() error {
+		<-blocked
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	invoked := false
+	err = RunWithContext(ctx, func() error {
+		invoked = true
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, invoked)
+}
+
+func TestSubscribeWithContext(t *testing.T) {
+	broker := newFakeBroker()
+
+	// Subscriber of the same topic, which is kept once the context is done
+	other := make(chan types.MessageEnvelope, 2)
+	_, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test1", Messages: other}, nil)
+	require.NoError(t, err)
+
+	messages := make(chan types.MessageEnvelope, 2)
+	topics := []types.TopicChannel{{Topic: "test1", Messages: messages}, {Topic: "test2", Messages: messages}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, SubscribeWithContext(ctx, broker.subscribeWithHandle, topics, nil))
+	broker.publish("test2", types.MessageEnvelope{CorrelationID: "1"})
+	assert.Equal(t, "1", receive(t, messages).CorrelationID)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		broker.mutex.Lock()
+		defer broker.mutex.Unlock()
+		return broker.unsubscribed == 1
+	}, time.Second, 10*time.Millisecond, "only the topic without other subscriber must be unsubscribed from the broker")
+
+	broker.publish("test1", types.MessageEnvelope{CorrelationID: "2"})
+	assert.Equal(t, "2", receive(t, other).CorrelationID)
+	select {
+	case message := <-messages:
+		require.Failf(t, "message received once the context was done", "CorrelationID %s", message.CorrelationID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	err = SubscribeWithContext(ctx, broker.subscribeWithHandle, topics, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The handles created before the failing topic are unsubscribed
+	broker.subscribeErr = errors.New("subscribe failed")
+	err = SubscribeWithContext(context.Background(), broker.subscribeWithHandle, topics, nil)
+	require.EqualError(t, err, "subscribe failed")
+	broker.publish("test1", types.MessageEnvelope{CorrelationID: "3"})
+	assert.Equal(t, "3", receive(t, other).CorrelationID)
+	select {
+	case message := <-messages:
+		require.Failf(t, "message received by the failed subscription", "CorrelationID %s", message.CorrelationID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
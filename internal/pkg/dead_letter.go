@@ -0,0 +1,66 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	commonDTO "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/google/uuid"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// DeadLetterer publishes the received messages which couldn't be processed to the dead letter topic, along with the
+// topic they were received on and the reason they couldn't be processed.
+type DeadLetterer struct {
+	topic   string
+	publish func(message types.MessageEnvelope, topic string) error
+}
+
+// NewDeadLetterer creates a DeadLetterer publishing to the DeadLetterTopic of the Optional configuration with the
+// publish function. Returns nil if no DeadLetterTopic is configured, which doesn't dead-letter any message.
+func NewDeadLetterer(optional map[string]string, publish func(message types.MessageEnvelope, topic string) error) *DeadLetterer {
+	topic := optional[DeadLetterTopic]
+	if topic == "" {
+		return nil
+	}
+
+	return &DeadLetterer{topic: topic, publish: publish}
+}
+
+// DeadLetter publishes the payload received on the source topic to the dead letter topic as a types.DeadLetter. It has
+// no effect if the DeadLetterer is nil, or the source topic is the dead letter topic so a message which can't be
+// processed isn't dead-lettered over and over.
+func (d *DeadLetterer) DeadLetter(sourceTopic string, payload []byte, contentType string, reason error) error {
+	if d == nil || sourceTopic == d.topic {
+		return nil
+	}
+
+	data, err := json.Marshal(types.DeadLetter{
+		Topic:       sourceTopic,
+		Reason:      reason.Error(),
+		Timestamp:   time.Now().UTC(),
+		ContentType: contentType,
+		Payload:     payload,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to dead-letter message received on topic '%s': %w", sourceTopic, err)
+	}
+
+	envelope := types.MessageEnvelope{
+		Versionable:   commonDTO.NewVersionable(),
+		CorrelationID: uuid.NewString(),
+		ContentType:   common.ContentTypeJSON,
+		Payload:       data,
+	}
+
+	if err = d.publish(envelope, d.topic); err != nil {
+		return fmt.Errorf("unable to dead-letter message received on topic '%s' to topic '%s': %w", sourceTopic, d.topic, err)
+	}
+
+	return nil
+}
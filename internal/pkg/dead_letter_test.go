@@ -0,0 +1,59 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// publishRecorder records the messages published by a DeadLetterer.
+type publishRecorder struct {
+	messages []types.MessageEnvelope
+	topics   []string
+	err      error
+}
+
+func (r *publishRecorder) publish(message types.MessageEnvelope, topic string) error {
+	r.messages = append(r.messages, message)
+	r.topics = append(r.topics, topic)
+	return r.err
+}
+
+func TestDeadLetterer(t *testing.T) {
+	recorder := &publishRecorder{}
+	assert.Nil(t, NewDeadLetterer(map[string]string{}, recorder.publish), "no DeadLetterer must be created when no DeadLetterTopic is configured")
+
+	var disabled *DeadLetterer
+	require.NoError(t, disabled.DeadLetter("edgex/events", []byte("{"), "", errors.New("invalid")))
+
+	deadLetterer := NewDeadLetterer(map[string]string{DeadLetterTopic: "edgex/dead-letters"}, recorder.publish)
+	require.NoError(t, deadLetterer.DeadLetter("edgex/events", []byte("{"), "", errors.New("unexpected end of JSON input")))
+
+	require.Len(t, recorder.messages, 1)
+	assert.Equal(t, "edgex/dead-letters", recorder.topics[0])
+	assert.Equal(t, common.ContentTypeJSON, recorder.messages[0].ContentType)
+	assert.NotEmpty(t, recorder.messages[0].CorrelationID)
+
+	var deadLetter types.DeadLetter
+	require.NoError(t, json.Unmarshal(recorder.messages[0].Payload, &deadLetter))
+	assert.Equal(t, "edgex/events", deadLetter.Topic)
+	assert.Equal(t, "unexpected end of JSON input", deadLetter.Reason)
+	assert.Equal(t, []byte("{"), deadLetter.Payload)
+	assert.False(t, deadLetter.Timestamp.IsZero())
+
+	require.NoError(t, deadLetterer.DeadLetter("edgex/dead-letters", []byte("{"), "", errors.New("invalid")))
+	assert.Len(t, recorder.messages, 1, "messages received on the dead letter topic must not be dead-lettered")
+
+	recorder.err = errors.New("not connected")
+	err := deadLetterer.DeadLetter("edgex/events", []byte("{"), "", errors.New("invalid"))
+	require.ErrorIs(t, err, recorder.err)
+	assert.ErrorContains(t, err, "edgex/dead-letters")
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+var errNotDelivered = errors.New("message dropped or subscription removed before it was delivered")
+
+// Deliverer delivers the messages received for a subscription to its TopicChannel, applying the overflow policy of
+// the TopicChannel when the subscriber doesn't receive them fast enough.
+type Deliverer struct {
+	topic   types.TopicChannel
+	errors  chan<- error
+	dropped atomic.Uint64
+}
+
+// NewDeliverer creates a Deliverer for the TopicChannel which reports the OverflowError policy errors to messageErrors.
+func NewDeliverer(topic types.TopicChannel, messageErrors chan<- error) *Deliverer {
+	return &Deliverer{topic: topic, errors: messageErrors}
+}
+
+// Deliver sends the message to the TopicChannel, or drops it according to the overflow policy. Returns true once the
+// message is sent, false if it was dropped or done was closed while waiting for the subscriber. A nil done waits for
+// the subscriber for as long as needed.
+func (d *Deliverer) Deliver(message types.MessageEnvelope, done <-chan struct{}) bool {
+	switch d.topic.Backpressure.Policy {
+	case types.OverflowDropNewest, types.OverflowError:
+		select {
+		case d.topic.Messages <- message:
+			return true
+		default:
+			d.drop(message)
+			return false
+		}
+
+	case types.OverflowDropOldest:
+		for {
+			select {
+			case d.topic.Messages <- message:
+				return true
+			default:
+			}
+
+			select {
+			case oldest := <-d.topic.Messages:
+				d.drop(oldest)
+			default:
+				// Nothing is buffered to make room for the message
+				if cap(d.topic.Messages) == 0 {
+					d.drop(message)
+					return false
+				}
+			}
+		}
+
+	default:
+		select {
+		case d.topic.Messages <- message:
+			return true
+		case <-done:
+			return false
+		}
+	}
+}
+
+// Receive delivers a message received from the broker like Deliver, recording its metrics and tracing its delivery.
+func (d *Deliverer) Receive(message types.MessageEnvelope, done <-chan struct{}) bool {
+	RecordReceive(d.topic.Topic, len(message.Payload))
+
+	span := StartSpan(tracing.Deliver, d.topic.Topic, &message)
+	if !d.Deliver(message, done) {
+		span.End(errNotDelivered)
+		return false
+	}
+
+	span.End(nil)
+	return true
+}
+
+// Dropped returns the number of messages dropped by the overflow policy.
+func (d *Deliverer) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+func (d *Deliverer) drop(message types.MessageEnvelope) {
+	d.dropped.Add(1)
+	RecordDrop(d.topic.Topic)
+
+	if d.topic.Backpressure.OnOverflow != nil {
+		d.topic.Backpressure.OnOverflow(message)
+	}
+
+	if d.topic.Backpressure.Policy != types.OverflowError || d.errors == nil {
+		return
+	}
+
+	// The subscriber is already behind, so the error is dropped too rather than waited for if it can't be buffered
+	select {
+	case d.errors <- fmt.Errorf("message received on topic '%s' dropped: %w", message.ReceivedTopic, types.ErrSubscriptionOverflow):
+	default:
+	}
+}
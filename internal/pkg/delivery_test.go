@@ -0,0 +1,94 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func correlationIDs(messages chan types.MessageEnvelope) []string {
+	var ids []string
+	for len(messages) > 0 {
+		ids = append(ids, (<-messages).CorrelationID)
+	}
+	return ids
+}
+
+func TestDelivererOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   types.OverflowPolicy
+		expected []string
+	}{
+		{"drop newest", types.OverflowDropNewest, []string{"1", "2"}},
+		{"drop oldest", types.OverflowDropOldest, []string{"3", "4"}},
+		{"error", types.OverflowError, []string{"1", "2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var overflowed []string
+			messages := make(chan types.MessageEnvelope, 2)
+			messageErrors := make(chan error, 1)
+			deliverer := NewDeliverer(types.TopicChannel{
+				Topic:    "test",
+				Messages: messages,
+				Backpressure: types.Backpressure{
+					Policy:     test.policy,
+					OnOverflow: func(dropped types.MessageEnvelope) { overflowed = append(overflowed, dropped.CorrelationID) },
+				},
+			}, messageErrors)
+
+			for _, id := range []string{"1", "2", "3", "4"} {
+				deliverer.Deliver(types.MessageEnvelope{CorrelationID: id, ReceivedTopic: "test"}, nil)
+			}
+
+			assert.Equal(t, test.expected, correlationIDs(messages))
+			assert.Equal(t, uint64(2), deliverer.Dropped())
+			assert.Len(t, overflowed, 2)
+
+			if test.policy != types.OverflowError {
+				assert.Empty(t, messageErrors)
+				return
+			}
+			// The second error is dropped as the errors channel is full
+			require.Len(t, messageErrors, 1)
+			err := <-messageErrors
+			assert.True(t, errors.Is(err, types.ErrSubscriptionOverflow))
+			assert.ErrorContains(t, err, "topic 'test'")
+		})
+	}
+}
+
+func TestDelivererDropOldestUnbuffered(t *testing.T) {
+	deliverer := NewDeliverer(types.TopicChannel{
+		Topic:        "test",
+		Messages:     make(chan types.MessageEnvelope),
+		Backpressure: types.Backpressure{Policy: types.OverflowDropOldest},
+	}, nil)
+
+	assert.False(t, deliverer.Deliver(types.MessageEnvelope{}, nil))
+	assert.Equal(t, uint64(1), deliverer.Dropped())
+}
+
+func TestDelivererBlock(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	deliverer := NewDeliverer(types.TopicChannel{Topic: "test", Messages: messages}, nil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-messages
+	}()
+	assert.True(t, deliverer.Deliver(types.MessageEnvelope{}, nil), "the message must be waited for")
+
+	done := make(chan struct{})
+	close(done)
+	assert.False(t, deliverer.Deliver(types.MessageEnvelope{}, done))
+	assert.Equal(t, uint64(0), deliverer.Dropped())
+}
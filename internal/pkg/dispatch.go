@@ -0,0 +1,123 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// HandlerSubscription dispatches the messages received for a topic to a MessageHandler.
+type HandlerSubscription struct {
+	topicChannel types.TopicChannel
+	errors       chan error
+	handler      types.MessageHandler
+	options      types.SubscribeOptions
+	deadLetterer *DeadLetterer
+}
+
+// NewHandlerSubscription creates a HandlerSubscription for the specified topic. Start must be called once the
+// subscription for the TopicChannel has been created.
+func NewHandlerSubscription(topic string, handler types.MessageHandler, options types.SubscribeOptions) *HandlerSubscription {
+	workers := max(options.Concurrency, 1)
+
+	return &HandlerSubscription{
+		topicChannel: types.TopicChannel{
+			Topic:        topic,
+			Messages:     make(chan types.MessageEnvelope, max(options.BufferSize, workers)),
+			Backpressure: options.Backpressure,
+		},
+		errors:  make(chan error, workers),
+		handler: handler,
+		options: options,
+	}
+}
+
+// TopicChannel returns the TopicChannel the messages for the subscription must be sent to.
+func (h *HandlerSubscription) TopicChannel() types.TopicChannel {
+	return h.topicChannel
+}
+
+// Errors returns the channel the errors for the subscription must be sent to.
+func (h *HandlerSubscription) Errors() chan error {
+	return h.errors
+}
+
+// Start spins up the workers which invoke the handler for the received messages until done is closed.
+// Messages which are not yet dispatched at that point are discarded.
+func (h *HandlerSubscription) Start(done <-chan struct{}) {
+	for i := 0; i < max(h.options.Concurrency, 1); i++ {
+		go h.work(done)
+	}
+}
+
+func (h *HandlerSubscription) work(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case message := <-h.topicChannel.Messages:
+			h.handle(message)
+		case err := <-h.errors:
+			h.handleError(err)
+		}
+	}
+}
+
+// handle invokes the handler within a Process span, whose trace context is propagated to the handler with the message.
+func (h *HandlerSubscription) handle(message types.MessageEnvelope) {
+	span := StartSpan(tracing.Process, h.topicChannel.Topic, &message)
+	err := h.handler(message)
+	span.End(err)
+
+	if err != nil {
+		h.deadLetter(message, err)
+		h.handleError(fmt.Errorf("failed to handle message received on topic '%s': %w", message.ReceivedTopic, err))
+	}
+}
+
+// deadLetter publishes the message to the dead letter topic if the handler returned a types.DeadLetterError.
+func (h *HandlerSubscription) deadLetter(message types.MessageEnvelope, err error) {
+	var deadLetterErr *types.DeadLetterError
+	if !errors.As(err, &deadLetterErr) {
+		return
+	}
+
+	if err = h.deadLetterer.DeadLetter(message.ReceivedTopic, message.Payload, message.ContentType, deadLetterErr.Err); err != nil {
+		h.handleError(err)
+	}
+}
+
+func (h *HandlerSubscription) handleError(err error) {
+	if h.options.ErrorHandler != nil {
+		h.options.ErrorHandler(err)
+	}
+}
+
+// SubscribeFunc subscribes to the topic with the specified subscribe function and dispatches the received messages
+// to the handler until the returned subscription is removed. The messages for which the handler returns a
+// types.DeadLetterError are published with the deadLetterer, which may be nil.
+func SubscribeFunc(
+	subscribe func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error),
+	topic string,
+	handler types.MessageHandler,
+	options types.SubscribeOptions,
+	deadLetterer *DeadLetterer) (types.Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("unable to subscribe to topic '%s': handler is required", topic)
+	}
+
+	subscription := NewHandlerSubscription(topic, handler, options)
+	subscription.deadLetterer = deadLetterer
+	handle, err := subscribe(subscription.TopicChannel(), subscription.Errors())
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Start(handle.Done())
+
+	return handle, nil
+}
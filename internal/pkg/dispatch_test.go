@@ -0,0 +1,237 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// fakeBroker records the subscriptions made through a SubscriptionManager so tests can deliver messages to them.
+type fakeBroker struct {
+	manager      *SubscriptionManager
+	topics       map[string]types.TopicChannel
+	errors       map[string]chan error
+	subscribed   int
+	unsubscribed int
+	subscribeErr error
+	mutex        sync.Mutex
+	// roundTrip simulates the time taken by the broker to acknowledge a subscribe or unsubscribe
+	roundTrip time.Duration
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		manager: NewSubscriptionManager(),
+		topics:  make(map[string]types.TopicChannel),
+		errors:  make(map[string]chan error),
+	}
+}
+
+func (b *fakeBroker) subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	time.Sleep(b.roundTrip)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribeErr != nil {
+		return b.subscribeErr
+	}
+	for _, topic := range topics {
+		b.topics[topic.Topic] = topic
+		b.errors[topic.Topic] = messageErrors
+		b.subscribed++
+	}
+	return nil
+}
+
+func (b *fakeBroker) unsubscribe(topics ...string) error {
+	time.Sleep(b.roundTrip)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, topic := range topics {
+		delete(b.topics, topic)
+		delete(b.errors, topic)
+		b.unsubscribed++
+	}
+	return nil
+}
+
+func (b *fakeBroker) subscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	return b.manager.Subscribe(topic, messageErrors, b.subscribe, b.unsubscribe)
+}
+
+// publish sends the message to all the subscriptions matching the topic
+func (b *fakeBroker) publish(topic string, message types.MessageEnvelope) {
+	b.mutex.Lock()
+	var matching []types.TopicChannel
+	for filter, topicChannel := range b.topics {
+		if TopicMatches(filter, topic) {
+			matching = append(matching, topicChannel)
+		}
+	}
+	b.mutex.Unlock()
+
+	message.ReceivedTopic = topic
+	for _, topicChannel := range matching {
+		topicChannel.Messages <- message
+	}
+}
+
+func (b *fakeBroker) publishError(topic string, err error) {
+	b.mutex.Lock()
+	errs := b.errors[topic]
+	b.mutex.Unlock()
+
+	errs <- err
+}
+
+func TestSubscribeFunc(t *testing.T) {
+	broker := newFakeBroker()
+
+	received := make(chan types.MessageEnvelope, 3)
+	handlerErrors := make(chan error, 3)
+	handler := func(message types.MessageEnvelope) error {
+		received <- message
+		if message.CorrelationID == "bad" {
+			return errors.New("handler failed")
+		}
+		return nil
+	}
+	options := types.SubscribeOptions{ErrorHandler: func(err error) { handlerErrors <- err }}
+
+	subscription, err := SubscribeFunc(broker.subscribeWithHandle, "test", handler, options, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test", subscription.Topic())
+
+	for _, id := range []string{"1", "2", "bad"} {
+		broker.publish("test", types.MessageEnvelope{CorrelationID: id})
+	}
+	for _, id := range []string{"1", "2", "bad"} {
+		assert.Equal(t, id, (<-received).CorrelationID, "messages must be handled serially in order")
+	}
+	assert.ErrorContains(t, <-handlerErrors, "handler failed")
+
+	broker.publishError("test", errors.New("receive failed"))
+	assert.EqualError(t, <-handlerErrors, "receive failed")
+
+	require.NoError(t, subscription.Unsubscribe())
+	assert.Equal(t, 1, broker.unsubscribed)
+}
+
+func TestSubscribeFuncConcurrency(t *testing.T) {
+	broker := newFakeBroker()
+
+	var active, maxActive atomic.Int32
+	wg := sync.WaitGroup{}
+	wg.Add(6)
+	handler := func(message types.MessageEnvelope) error {
+		defer wg.Done()
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			previous := maxActive.Load()
+			if current <= previous || maxActive.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	subscription, err := SubscribeFunc(broker.subscribeWithHandle, "test", handler, types.SubscribeOptions{Concurrency: 3}, nil)
+	require.NoError(t, err)
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	for i := 0; i < 6; i++ {
+		broker.publish("test", types.MessageEnvelope{})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), maxActive.Load())
+}
+
+func TestSubscribeFuncErrors(t *testing.T) {
+	broker := newFakeBroker()
+
+	_, err := SubscribeFunc(broker.subscribeWithHandle, "test", nil, types.SubscribeOptions{}, nil)
+	require.Error(t, err)
+
+	broker.subscribeErr = errors.New("subscribe failed")
+	handler := func(message types.MessageEnvelope) error { return nil }
+	_, err = SubscribeFunc(broker.subscribeWithHandle, "test", handler, types.SubscribeOptions{}, nil)
+	require.EqualError(t, err, "subscribe failed")
+}
+
+func TestSubscribeFuncStopped(t *testing.T) {
+	broker := newFakeBroker()
+
+	handled := make(chan struct{}, 1)
+	handler := func(message types.MessageEnvelope) error {
+		handled <- struct{}{}
+		return nil
+	}
+
+	subscription, err := SubscribeFunc(broker.subscribeWithHandle, "test", handler, types.SubscribeOptions{}, nil)
+	require.NoError(t, err)
+
+	// Removing the topic, i.e. the Unsubscribe of the message client, stops the handler
+	broker.manager.Remove("test")
+	<-subscription.Done()
+
+	select {
+	case <-handled:
+		require.Fail(t, "handler invoked after the subscription was removed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeFuncDeadLetter(t *testing.T) {
+	broker := newFakeBroker()
+	recorder := &publishRecorder{}
+	deadLetterer := NewDeadLetterer(map[string]string{DeadLetterTopic: "dead-letters"}, recorder.publish)
+
+	handlerErrors := make(chan error, 2)
+	handler := func(message types.MessageEnvelope) error {
+		if message.CorrelationID == "invalid" {
+			return types.NewDeadLetterError(errors.New("invalid reading"))
+		}
+		return errors.New("temporary failure")
+	}
+
+	subscription, err := SubscribeFunc(broker.subscribeWithHandle, "test", handler,
+		types.SubscribeOptions{ErrorHandler: func(err error) { handlerErrors <- err }}, deadLetterer)
+	require.NoError(t, err)
+
+	broker.publish("test", types.MessageEnvelope{CorrelationID: "invalid", ContentType: common.ContentTypeJSON, Payload: []byte("{}")})
+	err = <-handlerErrors
+	var deadLetterErr *types.DeadLetterError
+	require.ErrorAs(t, err, &deadLetterErr, "the error must still be passed to the ErrorHandler")
+
+	broker.publish("test", types.MessageEnvelope{CorrelationID: "other"})
+	<-handlerErrors
+	require.NoError(t, subscription.Unsubscribe())
+
+	require.Len(t, recorder.messages, 1, "only the messages for which a DeadLetterError is returned must be dead-lettered")
+	var deadLetter types.DeadLetter
+	require.NoError(t, json.Unmarshal(recorder.messages[0].Payload, &deadLetter))
+	assert.Equal(t, types.DeadLetter{
+		Topic:       "test",
+		Reason:      "invalid reading",
+		Timestamp:   deadLetter.Timestamp,
+		ContentType: common.ContentTypeJSON,
+		Payload:     []byte("{}"),
+	}, deadLetter)
+}
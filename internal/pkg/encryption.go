@@ -0,0 +1,190 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// encryptionKeyPEMType is the type of the PEM blocks holding the AES keys
+	encryptionKeyPEMType = "AES KEY"
+	// encryptionKeyIdPEMHeader is the header of the PEM blocks holding the id of their key. A block without it holds
+	// the key with the EncryptionKeyId.
+	encryptionKeyIdPEMHeader = "Key-Id"
+)
+
+var errPlaintextMessage = errors.New("plaintext message refused in strict encryption mode")
+
+// EncryptionOptions are the Optional properties configuring the encryption of the payloads.
+type EncryptionOptions struct {
+	// EncryptionKeyId is the id of the key the published payloads are encrypted with
+	EncryptionKeyId string
+	// EncryptionKeyFile is the file holding the PEM encoded keys
+	EncryptionKeyFile string
+	// EncryptionKeyPEMBlock holds the PEM encoded keys, which takes precedence over EncryptionKeyFile
+	EncryptionKeyPEMBlock string
+	// EncryptionStrict refuses the received messages which aren't encrypted
+	EncryptionStrict bool
+}
+
+// Encryptor encrypts the payload of the published messages with AES-GCM and decrypts the payload of the received
+// ones, so they can only be read by the clients holding the key whatever the access to the broker. The key of the
+// published payloads is one of the keys the received payloads are decrypted with, so the keys can be rotated by
+// adding the new key to every client before encrypting with it.
+type Encryptor struct {
+	keyID  string
+	keys   map[string]cipher.AEAD
+	strict bool
+}
+
+// NewEncryptor creates an Encryptor from the EncryptionOptions of the Optional configuration. The keys are loaded
+// from the PEM blocks of type "AES KEY" holding a 16, 24 or 32 bytes key, and named by their Key-Id header. Returns
+// nil if no key is configured, which neither encrypts nor decrypts the payloads.
+func NewEncryptor(optional map[string]string) (*Encryptor, error) {
+	options := EncryptionOptions{}
+	if err := Load(optional, &options); err != nil {
+		return nil, fmt.Errorf("invalid encryption configuration: %w", err)
+	}
+
+	pemData, err := loadPEM(options.EncryptionKeyPEMBlock, options.EncryptionKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load encryption keys: %w", err)
+	}
+
+	if len(pemData) == 0 {
+		if options.EncryptionKeyId != "" || options.EncryptionStrict {
+			return nil, NewMissingConfigurationErr(EncryptionKeyFile, "the encryption keys must be configured to encrypt the payloads")
+		}
+		return nil, nil
+	}
+
+	if options.EncryptionKeyId == "" {
+		return nil, NewMissingConfigurationErr(EncryptionKeyId, "the id of the key encrypting the payloads must be configured")
+	}
+
+	keys, err := parseEncryptionKeys(pemData, options.EncryptionKeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists := keys[options.EncryptionKeyId]; !exists {
+		return nil, fmt.Errorf("invalid encryption configuration: no key with id '%s'", options.EncryptionKeyId)
+	}
+
+	return &Encryptor{keyID: options.EncryptionKeyId, keys: keys, strict: options.EncryptionStrict}, nil
+}
+
+func parseEncryptionKeys(pemData []byte, defaultKeyID string) (map[string]cipher.AEAD, error) {
+	keys := make(map[string]cipher.AEAD)
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		if block.Type != encryptionKeyPEMType {
+			continue
+		}
+
+		keyID := block.Headers[encryptionKeyIdPEMHeader]
+		if keyID == "" {
+			keyID = defaultKeyID
+		}
+		if _, exists := keys[keyID]; exists {
+			return nil, fmt.Errorf("invalid encryption keys: duplicate key id '%s'", keyID)
+		}
+
+		blockCipher, err := aes.NewCipher(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key '%s': %w", keyID, err)
+		}
+		aead, err := cipher.NewGCM(blockCipher)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key '%s': %w", keyID, err)
+		}
+		keys[keyID] = aead
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("invalid encryption keys: no PEM block of type '%s' found", encryptionKeyPEMType)
+	}
+
+	return keys, nil
+}
+
+// Encrypt replaces the payload of the message with the nonce followed by the payload encrypted with the key of the
+// Encryptor, and sets the EncryptionKeyID of the message. The message is unchanged if the Encryptor is nil or the
+// payload is already encrypted.
+func (e *Encryptor) Encrypt(message *types.MessageEnvelope) error {
+	if e == nil || message.EncryptionKeyID != "" {
+		return nil
+	}
+
+	aead := e.keys[e.keyID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(message.Payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("unable to encrypt payload: %w", err)
+	}
+
+	message.EncryptionKeyID = e.keyID
+	message.Payload = aead.Seal(nonce, nonce, message.Payload, additionalData(*message))
+
+	return nil
+}
+
+// Decrypt replaces the encrypted payload of the message with the plaintext one, and clears the EncryptionKeyID of the
+// message. Returns an error if the payload can't be decrypted with the keys of the Encryptor, or if it isn't encrypted
+// and the Encryptor is strict.
+func (e *Encryptor) Decrypt(message *types.MessageEnvelope) error {
+	if message.EncryptionKeyID == "" {
+		if e != nil && e.strict {
+			return fmt.Errorf("message received on topic '%s': %w", message.ReceivedTopic, errPlaintextMessage)
+		}
+		return nil
+	}
+
+	if e == nil {
+		return fmt.Errorf("unable to decrypt payload encrypted with key '%s': encryption isn't configured", message.EncryptionKeyID)
+	}
+
+	aead, exists := e.keys[message.EncryptionKeyID]
+	if !exists {
+		return fmt.Errorf("unable to decrypt payload: unknown key '%s'", message.EncryptionKeyID)
+	}
+
+	if len(message.Payload) < aead.NonceSize() {
+		return fmt.Errorf("unable to decrypt payload encrypted with key '%s': payload too short", message.EncryptionKeyID)
+	}
+
+	nonce, ciphertext := message.Payload[:aead.NonceSize()], message.Payload[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, additionalData(*message))
+	if err != nil {
+		return fmt.Errorf("unable to decrypt payload encrypted with key '%s': %w", message.EncryptionKeyID, err)
+	}
+
+	message.Payload = payload
+	message.EncryptionKeyID = ""
+
+	return nil
+}
+
+// additionalData authenticates the fields describing the payload along with it, so they can't be altered without
+// failing the decryption.
+func additionalData(message types.MessageEnvelope) []byte {
+	var data []byte
+	for _, field := range []string{message.EncryptionKeyID, message.CorrelationID, message.ContentType, message.ContentEncoding} {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+
+	return data
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// encryptionKeyPEM returns the PEM block of a key of the size filled with the fill byte, named keyID unless empty.
+func encryptionKeyPEM(keyID string, size int, fill byte) string {
+	block := &pem.Block{Type: encryptionKeyPEMType, Bytes: bytes.Repeat([]byte{fill}, size)}
+	if keyID != "" {
+		block.Headers = map[string]string{encryptionKeyIdPEMHeader: keyID}
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+func newTestEncryptor(t *testing.T, optional map[string]string) *Encryptor {
+	encryptor, err := NewEncryptor(optional)
+	require.NoError(t, err)
+	require.NotNil(t, encryptor)
+	return encryptor
+}
+
+func TestNewEncryptor(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys.pem")
+	require.NoError(t, os.WriteFile(keyFile, []byte(encryptionKeyPEM("", 32, 1)), 0600))
+
+	tests := []struct {
+		name     string
+		optional map[string]string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"not configured", map[string]string{}, true, false},
+		{"PEM block", map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: encryptionKeyPEM("", 16, 1)}, false, false},
+		{"key file", map[string]string{EncryptionKeyId: "k1", EncryptionKeyFile: keyFile}, false, false},
+		{"several keys", map[string]string{EncryptionKeyId: "k2", EncryptionKeyPEMBlock: encryptionKeyPEM("k1", 24, 1) + encryptionKeyPEM("k2", 32, 2)}, false, false},
+		{"missing key file", map[string]string{EncryptionKeyId: "k1", EncryptionKeyFile: filepath.Join(t.TempDir(), "missing.pem")}, false, true},
+		{"missing keys", map[string]string{EncryptionKeyId: "k1"}, false, true},
+		{"missing keys in strict mode", map[string]string{EncryptionStrict: "true"}, false, true},
+		{"missing key id", map[string]string{EncryptionKeyPEMBlock: encryptionKeyPEM("", 32, 1)}, false, true},
+		{"unknown key id", map[string]string{EncryptionKeyId: "k3", EncryptionKeyPEMBlock: encryptionKeyPEM("k1", 32, 1)}, false, true},
+		{"duplicate key id", map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: encryptionKeyPEM("k1", 32, 1) + encryptionKeyPEM("", 32, 2)}, false, true},
+		{"invalid key size", map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: encryptionKeyPEM("", 20, 1)}, false, true},
+		{"no AES key", map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}))}, false, true},
+		{"invalid strict mode", map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: encryptionKeyPEM("", 32, 1), EncryptionStrict: "maybe"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptor, err := NewEncryptor(tt.optional)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, encryptor == nil)
+		})
+	}
+}
+
+func TestEncryptorEncryptDecrypt(t *testing.T) {
+	encryptor := newTestEncryptor(t, map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: encryptionKeyPEM("", 32, 1)})
+
+	payload := []byte(`{"reading":1}`)
+	message := types.MessageEnvelope{CorrelationID: "123", ContentType: "application/json", Payload: payload}
+	require.NoError(t, encryptor.Encrypt(&message))
+	assert.Equal(t, "k1", message.EncryptionKeyID)
+	assert.NotContains(t, string(message.Payload), "reading")
+	assert.Equal(t, []byte(`{"reading":1}`), payload, "the payload must not be modified in place")
+
+	encrypted := message
+	require.NoError(t, encryptor.Encrypt(&encrypted))
+	assert.Equal(t, message.Payload, encrypted.Payload, "an encrypted payload must not be encrypted again")
+
+	require.NoError(t, encryptor.Decrypt(&encrypted))
+	assert.Equal(t, payload, encrypted.Payload)
+	assert.Empty(t, encrypted.EncryptionKeyID)
+
+	tampered := message
+	tampered.ContentType = "text/plain"
+	require.Error(t, encryptor.Decrypt(&tampered), "the fields describing the payload must be authenticated")
+
+	tampered = message
+	tampered.Payload = append([]byte(nil), message.Payload...)
+	tampered.Payload[len(tampered.Payload)-1] ^= 0xff
+	require.Error(t, encryptor.Decrypt(&tampered))
+
+	tampered = message
+	tampered.Payload = tampered.Payload[:4]
+	require.Error(t, encryptor.Decrypt(&tampered))
+
+	unknown := message
+	unknown.EncryptionKeyID = "k2"
+	require.Error(t, encryptor.Decrypt(&unknown))
+
+	plaintext := types.MessageEnvelope{Payload: payload}
+	require.NoError(t, encryptor.Decrypt(&plaintext), "plaintext messages must be accepted unless in strict mode")
+	assert.Equal(t, payload, plaintext.Payload)
+}
+
+func TestEncryptorRotation(t *testing.T) {
+	oldKey := encryptionKeyPEM("k1", 32, 1)
+	newKey := encryptionKeyPEM("k2", 32, 2)
+	oldEncryptor := newTestEncryptor(t, map[string]string{EncryptionKeyId: "k1", EncryptionKeyPEMBlock: oldKey})
+	rotatedEncryptor := newTestEncryptor(t, map[string]string{EncryptionKeyId: "k2", EncryptionKeyPEMBlock: oldKey + newKey})
+
+	message := types.MessageEnvelope{Payload: []byte("data")}
+	require.NoError(t, oldEncryptor.Encrypt(&message))
+	require.NoError(t, rotatedEncryptor.Decrypt(&message), "the rotated keys must still be accepted")
+	assert.Equal(t, []byte("data"), message.Payload)
+
+	require.NoError(t, rotatedEncryptor.Encrypt(&message))
+	assert.Equal(t, "k2", message.EncryptionKeyID)
+	require.Error(t, oldEncryptor.Decrypt(&message))
+}
+
+func TestEncryptorStrict(t *testing.T) {
+	encryptor := newTestEncryptor(t, map[string]string{
+		EncryptionKeyId:       "k1",
+		EncryptionKeyPEMBlock: encryptionKeyPEM("", 32, 1),
+		EncryptionStrict:      "true",
+	})
+
+	plaintext := types.MessageEnvelope{ReceivedTopic: "edgex/events", Payload: []byte("data")}
+	err := encryptor.Decrypt(&plaintext)
+	require.ErrorIs(t, err, errPlaintextMessage)
+	assert.Contains(t, err.Error(), "edgex/events")
+
+	message := types.MessageEnvelope{Payload: []byte("data")}
+	require.NoError(t, encryptor.Encrypt(&message))
+	require.NoError(t, encryptor.Decrypt(&message))
+}
+
+func TestEncryptorNil(t *testing.T) {
+	var encryptor *Encryptor
+
+	message := types.MessageEnvelope{Payload: []byte("data")}
+	require.NoError(t, encryptor.Encrypt(&message))
+	assert.Equal(t, types.MessageEnvelope{Payload: []byte("data")}, message)
+	require.NoError(t, encryptor.Decrypt(&message))
+
+	message.EncryptionKeyID = "k1"
+	require.Error(t, encryptor.Decrypt(&message), "the encrypted payloads must not be delivered as is")
+}
@@ -14,7 +14,10 @@
 
 package pkg
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // CertificateErr represents an error associated with interacting with a Certificate.
 type CertificateErr struct {
@@ -25,6 +28,11 @@ func (ce CertificateErr) Error() string {
 	return fmt.Sprintf("Unable to process certificate properties: %s", ce.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (ce CertificateErr) Retryable() bool {
+	return false
+}
+
 // NewCertificateErr constructs a new CertificateErr
 func NewCertificateErr(message string) CertificateErr {
 	return CertificateErr{description: message}
@@ -39,6 +47,11 @@ func (bue BrokerURLErr) Error() string {
 	return fmt.Sprintf("Unable to process broker URL: %s", bue.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (bue BrokerURLErr) Retryable() bool {
+	return false
+}
+
 // NewBrokerURLErr constructs a new BrokerURLErr
 func NewBrokerURLErr(description string) BrokerURLErr {
 	return BrokerURLErr{description: description}
@@ -52,6 +65,11 @@ func (p PublishHostURLErr) Error() string {
 	return fmt.Sprintf("Unable to use PublishHost URL: %s", p.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (p PublishHostURLErr) Retryable() bool {
+	return false
+}
+
 func NewPublishHostURLErr(message string) PublishHostURLErr {
 	return PublishHostURLErr{description: message}
 }
@@ -64,6 +82,11 @@ func (p SubscribeHostURLErr) Error() string {
 	return fmt.Sprintf("Unable to use SubscribeHost URL: %s", p.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (p SubscribeHostURLErr) Retryable() bool {
+	return false
+}
+
 func NewSubscribeHostURLErr(message string) SubscribeHostURLErr {
 	return SubscribeHostURLErr{description: message}
 }
@@ -77,6 +100,11 @@ func (mce MissingConfigurationErr) Error() string {
 	return fmt.Sprintf("Missing configuration '%s' : %s", mce.missingConfiguration, mce.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (mce MissingConfigurationErr) Retryable() bool {
+	return false
+}
+
 func NewMissingConfigurationErr(missingConfiguration string, message string) MissingConfigurationErr {
 	return MissingConfigurationErr{
 		missingConfiguration: missingConfiguration,
@@ -93,9 +121,64 @@ func (ite InvalidTopicErr) Error() string {
 	return fmt.Sprintf("Invalid topic '%s': %s", ite.topic, ite.description)
 }
 
+// Retryable reports that the operation fails the same way when retried.
+func (ite InvalidTopicErr) Retryable() bool {
+	return false
+}
+
 func NewInvalidTopicErr(topic string, description string) InvalidTopicErr {
 	return InvalidTopicErr{
 		topic:       topic,
 		description: description,
 	}
 }
+
+// ConnectionErr represents an operation which failed because of the connection to the broker, i.e. the broker is
+// unreachable. The operation may succeed once retried.
+type ConnectionErr struct {
+	err error
+}
+
+func (ce ConnectionErr) Error() string {
+	return fmt.Sprintf("Unable to reach the broker: %v", ce.err)
+}
+
+func (ce ConnectionErr) Unwrap() error {
+	return ce.err
+}
+
+// Retryable reports that the operation may succeed once retried.
+func (ce ConnectionErr) Retryable() bool {
+	return true
+}
+
+// NewConnectionErr wraps the error of an operation which failed because of the connection to the broker.
+func NewConnectionErr(err error) ConnectionErr {
+	return ConnectionErr{err: err}
+}
+
+// RequestTimeoutErr represents a request for which no response was received in time. It wraps
+// context.DeadlineExceeded.
+type RequestTimeoutErr struct {
+	responseTopic string
+}
+
+func (rte RequestTimeoutErr) Error() string {
+	return fmt.Sprintf("timed out waiting for response on %s topic", rte.responseTopic)
+}
+
+func (rte RequestTimeoutErr) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Retryable reports that the request isn't sent again, as it may have been handled with only its response lost and
+// handling a request again, i.e. a device set command, isn't necessarily idempotent. A caller whose request is
+// idempotent decides itself whether to retry it.
+func (rte RequestTimeoutErr) Retryable() bool {
+	return false
+}
+
+// NewRequestTimeoutErr constructs a new RequestTimeoutErr for the response topic.
+func NewRequestTimeoutErr(responseTopic string) RequestTimeoutErr {
+	return RequestTimeoutErr{responseTopic: responseTopic}
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package memory
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+var (
+	// brokers holds the in-process brokers by name, so that all the clients configured with the same broker name
+	// exchange messages with each other.
+	brokers      = make(map[string]*broker)
+	brokersMutex sync.Mutex
+)
+
+// broker routes the published messages to the matching subscriptions of all its clients.
+type broker struct {
+	subscriptions map[*subscription]struct{}
+	mutex         sync.RWMutex
+}
+
+// message is a published message as it would go over the wire of a real broker.
+type message struct {
+	topic string
+	data  []byte
+}
+
+// subscription delivers the messages for a topic filter to a TopicChannel. Messages are queued so publishing never
+// waits for the subscribers, and are delivered in the order they were published.
+type subscription struct {
+	filter       string
+	binary       bool
+	deliverer    *pkg.Deliverer
+	deadLetterer *pkg.DeadLetterer
+	processor    *pkg.EnvelopeProcessor
+	reassembler  *pkg.Reassembler
+	errors       chan<- error
+	queue        []message
+	mutex        sync.Mutex
+	notify       chan struct{}
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+// getBroker returns the broker with the specified name, creating it on first use.
+func getBroker(name string) *broker {
+	brokersMutex.Lock()
+	defer brokersMutex.Unlock()
+
+	b, exists := brokers[name]
+	if !exists {
+		b = &broker{subscriptions: make(map[*subscription]struct{})}
+		brokers[name] = b
+	}
+
+	return b
+}
+
+func (b *broker) publish(topic string, data []byte) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscriptions {
+		if pkg.TopicMatches(s.filter, topic) {
+			s.enqueue(message{topic: topic, data: data})
+		}
+	}
+}
+
+func (b *broker) subscribe(s *subscription) {
+	b.mutex.Lock()
+	b.subscriptions[s] = struct{}{}
+	b.mutex.Unlock()
+
+	go s.run()
+}
+
+func (b *broker) unsubscribe(s *subscription) {
+	b.mutex.Lock()
+	delete(b.subscriptions, s)
+	b.mutex.Unlock()
+
+	s.stop()
+}
+
+func newSubscription(topic types.TopicChannel, messageErrors chan error, binary bool, deadLetterer *pkg.DeadLetterer, processor *pkg.EnvelopeProcessor) *subscription {
+	return &subscription{
+		filter:       topic.Topic,
+		binary:       binary,
+		deliverer:    pkg.NewDeliverer(topic, messageErrors),
+		deadLetterer: deadLetterer,
+		processor:    processor,
+		reassembler:  processor.NewReassembler(topic.Topic),
+		errors:       messageErrors,
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+}
+
+func (s *subscription) enqueue(msg message) {
+	s.mutex.Lock()
+	s.queue = append(s.queue, msg)
+	s.mutex.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *subscription) run() {
+	for {
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+
+		s.mutex.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mutex.Unlock()
+
+		for _, msg := range queue {
+			if !s.deliver(msg) {
+				return
+			}
+		}
+	}
+}
+
+// deliver sends the message to the TopicChannel according to its overflow policy, or the error decoding or
+// processing it to the errors channel. Returns false if the subscription was stopped while waiting for the receiver.
+func (s *subscription) deliver(msg message) bool {
+	var envelope types.MessageEnvelope
+	complete, err := s.decode(msg, &envelope)
+	if err != nil {
+		pkg.RecordDecodeError(s.filter)
+		if dlErr := s.deadLetterer.DeadLetter(msg.topic, msg.data, "", err); dlErr != nil {
+			err = errors.Join(err, dlErr)
+		}
+		if s.errors == nil {
+			return true
+		}
+
+		select {
+		case s.errors <- err:
+			return true
+		case <-s.done:
+			return false
+		}
+	}
+
+	if !complete {
+		// The message is a chunk of a transfer which isn't complete yet
+		return true
+	}
+
+	envelope.ReceivedTopic = msg.topic
+
+	if s.deliverer.Receive(envelope, s.done) {
+		return true
+	}
+
+	// The message may have been dropped by the overflow policy rather than the subscription stopped
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// decode decodes the message into the envelope, reassembling the chunked messages. Returns whether the envelope is
+// complete, i.e. it isn't a chunk of a transfer which isn't complete yet.
+func (s *subscription) decode(msg message, envelope *types.MessageEnvelope) (bool, error) {
+	if s.binary {
+		data, err := s.processor.IncomingBinary(msg.data)
+		if err != nil {
+			return false, err
+		}
+
+		// Use MessageEnvelope.Payload to store the binary data instead of unmarshalling binary to MessageEnvelope
+		*envelope = types.NewMessageEnvelopeForRequest(data, nil)
+		envelope.ReceivedTopic = msg.topic
+		return true, nil
+	}
+
+	if err := codec.Decode(msg.data, envelope); err != nil {
+		return false, err
+	}
+
+	envelope.ReceivedTopic = msg.topic
+	complete, err := s.reassembler.Add(envelope)
+	if err != nil || !complete {
+		return false, err
+	}
+
+	return true, s.processor.Incoming(envelope)
+}
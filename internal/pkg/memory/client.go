@@ -0,0 +1,278 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Client is a MessageClient implementation which exchanges messages in process, without any broker. Clients configured
+// with the same Broker Host exchange messages with each other. The topics follow the MQTT scheme including the "+" and
+// "#" wildcards.
+type Client struct {
+	brokerName          string
+	broker              *broker
+	subscriptions       map[string]*subscription
+	mutex               sync.Mutex
+	subscriptionManager *pkg.SubscriptionManager
+	requester           *pkg.Requester
+	connection          *pkg.ConnectionTracker
+	deadLetterer        *pkg.DeadLetterer
+	codec               codec.Codec
+	processor           *pkg.EnvelopeProcessor
+}
+
+// NewClient creates a new in-memory Client based on the provided configuration. The messages are encoded with the
+// codec named by the Format of the Optional properties, JSON by default.
+func NewClient(config types.MessageBusConfig) (*Client, error) {
+	envelopeCodec, err := codec.Get(config.Optional[pkg.Format])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", pkg.Format, err)
+	}
+
+	processor, err := pkg.NewEnvelopeProcessor(config.Optional)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		brokerName:          config.Broker.Host,
+		subscriptions:       make(map[string]*subscription),
+		subscriptionManager: pkg.NewSubscriptionManager(),
+		connection:          pkg.NewConnectionTracker(),
+		codec:               envelopeCodec,
+		processor:           processor,
+	}
+	client.requester = pkg.NewRequester(client.SubscribeWithHandle)
+	client.deadLetterer = pkg.NewDeadLetterer(config.Optional, client.Publish)
+
+	return client, nil
+}
+
+// Connect attaches the client to the in-process broker.
+// This must be called before any other functionality provided by the Client.
+func (c *Client) Connect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broker == nil {
+		c.broker = getBroker(c.brokerName)
+	}
+	c.connection.SetConnected()
+
+	return nil
+}
+
+// Publish sends the message to all the subscriptions matching the topic.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) (err error) {
+	span := pkg.StartSpan(tracing.Publish, topic, &message)
+	defer func() { span.End(err) }()
+
+	if err = c.processor.Outgoing(&message, topic); err != nil {
+		return err
+	}
+
+	encoded, err := pkg.EncodeChunks(c.processor, message, topic, c.codec.Encode)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(encoded, len(message.Payload), topic)
+}
+
+// PublishBinaryData sends the binary data to all the subscriptions matching the topic.
+func (c *Client) PublishBinaryData(data []byte, topic string) error {
+	data, err := c.processor.OutgoingBinary(data, topic)
+	if err != nil {
+		return err
+	}
+
+	// Copy the data as a broker would, so the caller is free to re-use it
+	return c.publish([][]byte{append([]byte(nil), data...)}, len(data), topic)
+}
+
+// publish sends the encoded messages, the chunks of a message or the message alone, to the broker, recording the
+// metrics of a message with a payload of payloadSize bytes.
+func (c *Client) publish(encoded [][]byte, payloadSize int, topic string) error {
+	started := time.Now()
+	var err error
+	for _, data := range encoded {
+		if err = c.send(data, topic); err != nil {
+			break
+		}
+	}
+	pkg.RecordPublish(topic, payloadSize, started, err)
+
+	return err
+}
+
+func (c *Client) send(data []byte, topic string) error {
+	b, err := c.connectedBroker()
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.ValidateTopic(topic); err != nil {
+		return err
+	}
+
+	b.publish(topic, data)
+
+	return nil
+}
+
+// Subscribe creates a subscription for the specified topics. Subscribing again to a topic replaces its subscription.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, false)
+}
+
+// SubscribeBinaryData creates a subscription for the specified topics which wraps the received data in the Payload of
+// a MessageEnvelope.
+func (c *Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, true)
+}
+
+func (c *Client) subscribe(topics []types.TopicChannel, messageErrors chan error, binary bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broker == nil {
+		return errNotConnected
+	}
+
+	for _, topic := range topics {
+		if err := pkg.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+	}
+
+	for _, topic := range topics {
+		if existing, exists := c.subscriptions[topic.Topic]; exists {
+			c.broker.unsubscribe(existing)
+		}
+
+		s := newSubscription(topic, messageErrors, binary, c.deadLetterer, c.processor)
+		c.subscriptions[topic.Topic] = s
+		c.broker.subscribe(s)
+	}
+
+	return nil
+}
+
+// SubscribeWithHandle creates a subscription for the specified topic and returns its handle. The subscription is
+// shared by all the handles for the topic.
+func (c *Client) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	return c.subscriptionManager.Subscribe(topic, messageErrors, c.Subscribe, c.unsubscribe)
+}
+
+// SubscribeFunc creates a subscription for the specified topic which invokes the handler for every received message.
+func (c *Client) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	return pkg.SubscribeFunc(c.SubscribeWithHandle, topic, handler, options, c.deadLetterer)
+}
+
+// Request publishes a request and waits for a response. The response topic prefix is subscribed by the first request
+// using it and stays subscribed for the following requests.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.requester.Request(ctx, c.Publish, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestMany publishes a request and collects the responses received within the window, stopping early once
+// maxResponses responses are received. A maxResponses of 0 collects all the responses received within the window.
+func (c *Client) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	return c.requester.RequestMany(ctx, c.Publish, message, requestTopic, responseTopicPrefix, maxResponses)
+}
+
+// Unsubscribe to unsubscribe from the specified topics.
+func (c *Client) Unsubscribe(topics ...string) error {
+	if err := c.unsubscribe(topics...); err != nil {
+		return err
+	}
+
+	c.subscriptionManager.Remove(topics...)
+
+	return nil
+}
+
+func (c *Client) unsubscribe(topics ...string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broker == nil {
+		return errNotConnected
+	}
+
+	for _, topic := range topics {
+		if existing, exists := c.subscriptions[topic]; exists {
+			c.broker.unsubscribe(existing)
+			delete(c.subscriptions, topic)
+		}
+	}
+
+	return nil
+}
+
+// Disconnect removes all the subscriptions of the client and detaches it from the in-process broker.
+func (c *Client) Disconnect() error {
+	c.mutex.Lock()
+
+	if c.broker == nil {
+		c.mutex.Unlock()
+		return errNotConnected
+	}
+
+	for topic, existing := range c.subscriptions {
+		c.broker.unsubscribe(existing)
+		delete(c.subscriptions, topic)
+	}
+	c.broker = nil
+	c.connection.SetDisconnected()
+
+	c.mutex.Unlock()
+
+	c.subscriptionManager.RemoveAll()
+
+	return nil
+}
+
+// IsConnected reports whether the client is attached to the in-process broker.
+func (c *Client) IsConnected() bool {
+	return c.connection.IsConnected()
+}
+
+// State returns Connected while the client is attached to the in-process broker, the connection is never lost.
+func (c *Client) State() types.ConnectionState {
+	return c.connection.State()
+}
+
+// Events returns the channel the Connected and Disconnected events of Connect and Disconnect are emitted on.
+func (c *Client) Events() <-chan types.ConnectionEvent {
+	return c.connection.Events()
+}
+
+var errNotConnected = errors.New("memory client is not connected")
+
+func (c *Client) connectedBroker() (*broker, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.broker == nil {
+		return nil, errNotConnected
+	}
+
+	return c.broker, nil
+}
@@ -0,0 +1,735 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// newConnectedClient creates a connected client on a broker which is private to the test.
+func newConnectedClient(t *testing.T) *Client {
+	return newConfiguredClient(t, nil)
+}
+
+// newConfiguredClient creates a connected client with the optional properties on a broker which is private to the test.
+func newConfiguredClient(t *testing.T, optional map[string]string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: t.Name()}, Optional: optional})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	return client
+}
+
+func receive(t *testing.T, messages chan types.MessageEnvelope) types.MessageEnvelope {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+	return types.MessageEnvelope{}
+}
+
+// expectError waits for the error reported on the channel and returns it.
+func expectError(t *testing.T, errs chan error) error {
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(time.Second):
+		require.Fail(t, "error not received")
+	}
+	return nil
+}
+
+func assertNotReceived(t *testing.T, messages chan types.MessageEnvelope) {
+	select {
+	case message := <-messages:
+		require.Failf(t, "unexpected message received", "topic %s", message.ReceivedTopic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	publisher := newConnectedClient(t)
+	subscriber := newConnectedClient(t)
+
+	exact := make(chan types.MessageEnvelope, 1)
+	single := make(chan types.MessageEnvelope, 1)
+	multi := make(chan types.MessageEnvelope, 1)
+	other := make(chan types.MessageEnvelope, 1)
+	err := subscriber.Subscribe([]types.TopicChannel{
+		{Topic: "edgex/events/core", Messages: exact},
+		{Topic: "edgex/+/core", Messages: single},
+		{Topic: "edgex/#", Messages: multi},
+		{Topic: "edgex/+", Messages: other},
+	}, make(chan error))
+	require.NoError(t, err)
+
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data"), ContentType: "text/plain"}
+	require.NoError(t, publisher.Publish(expected, "edgex/events/core"))
+
+	for _, messages := range []chan types.MessageEnvelope{exact, single, multi} {
+		actual := receive(t, messages)
+		assert.Equal(t, "edgex/events/core", actual.ReceivedTopic)
+		assert.Equal(t, expected.CorrelationID, actual.CorrelationID)
+		assert.Equal(t, expected.Payload, actual.Payload)
+	}
+	assertNotReceived(t, other)
+}
+
+func TestClientSeparateBrokers(t *testing.T) {
+	client := newConnectedClient(t)
+	otherClient, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: t.Name() + "-other"}})
+	require.NoError(t, err)
+	require.NoError(t, otherClient.Connect())
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, otherClient.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	assertNotReceived(t, messages)
+}
+
+func TestClientPublishOrder(t *testing.T) {
+	client := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+
+	// Publishing must not wait for the subscriber to receive the messages
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: id}, "test"))
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		assert.Equal(t, id, receive(t, messages).CorrelationID)
+	}
+}
+
+func TestClientBinaryData(t *testing.T) {
+	client := newConnectedClient(t)
+
+	binaryMessages := make(chan types.MessageEnvelope, 1)
+	envelopeMessages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	require.NoError(t, client.SubscribeBinaryData([]types.TopicChannel{{Topic: "binary/#", Messages: binaryMessages}}, messageErrors))
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "binary/data", Messages: envelopeMessages}}, messageErrors))
+
+	data := []byte{0x01, 0x02, 0x03}
+	require.NoError(t, client.PublishBinaryData(data, "binary/data"))
+	data[0] = 0xff
+
+	actual := receive(t, binaryMessages)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, actual.Payload)
+	assert.Equal(t, "binary/data", actual.ReceivedTopic)
+
+	// Like on a real broker, the binary data can't be decoded by a subscription expecting a MessageEnvelope
+	select {
+	case err := <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "decode error not received")
+	}
+	assertNotReceived(t, envelopeMessages)
+}
+
+func TestClientInvalidTopics(t *testing.T) {
+	client := newConnectedClient(t)
+
+	assert.Error(t, client.Publish(types.MessageEnvelope{}, ""))
+	assert.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/+"))
+	assert.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/#"))
+	assert.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#/core"}}, nil))
+	assert.Error(t, client.Subscribe([]types.TopicChannel{{Topic: ""}}, nil))
+}
+
+func TestClientNotConnected(t *testing.T) {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: t.Name()}})
+	require.NoError(t, err)
+
+	assert.Error(t, client.Publish(types.MessageEnvelope{}, "test"))
+	assert.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "test"}}, nil))
+	assert.Error(t, client.Disconnect())
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	client := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+	require.NoError(t, client.Unsubscribe("test", "unknown"))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	assertNotReceived(t, messages)
+}
+
+func TestClientSubscribeReplaces(t *testing.T) {
+	client := newConnectedClient(t)
+
+	first := make(chan types.MessageEnvelope, 1)
+	second := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: first}}, nil))
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: second}}, nil))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	receive(t, second)
+	assertNotReceived(t, first)
+}
+
+func TestClientDisconnect(t *testing.T) {
+	publisher := newConnectedClient(t)
+	subscriber := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+	subscription, err := subscriber.SubscribeWithHandle(types.TopicChannel{Topic: "handle", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, subscriber.Disconnect())
+	<-subscription.Done()
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test"))
+	assertNotReceived(t, messages)
+	assert.Error(t, subscriber.Publish(types.MessageEnvelope{}, "test"))
+
+	assert.False(t, subscriber.IsConnected())
+
+	// The client can connect again, but its subscriptions are not restored
+	require.NoError(t, subscriber.Connect())
+	assert.True(t, subscriber.IsConnected())
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test"))
+	assertNotReceived(t, messages)
+}
+
+func TestClientSubscribeFunc(t *testing.T) {
+	client := newConnectedClient(t)
+
+	received := make(chan types.MessageEnvelope, 1)
+	subscription, err := client.SubscribeFunc("test/#", func(message types.MessageEnvelope) error {
+		received <- message
+		return nil
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "123"}, "test/func"))
+	actual := receive(t, received)
+	assert.Equal(t, "123", actual.CorrelationID)
+	assert.Equal(t, "test/func", actual.ReceivedTopic)
+
+	require.NoError(t, subscription.Unsubscribe())
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test/func"))
+	assertNotReceived(t, received)
+}
+
+func TestClientRequest(t *testing.T) {
+	requester := newConnectedClient(t)
+	responder := newConnectedClient(t)
+
+	_, err := responder.SubscribeFunc("request", func(message types.MessageEnvelope) error {
+		response, err := types.NewMessageEnvelopeForResponse([]byte("pong"), message.RequestID, message.CorrelationID, message.ContentType)
+		if err != nil {
+			return err
+		}
+		return responder.Publish(response, "response/"+message.RequestID)
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	request := types.NewMessageEnvelopeForRequest([]byte("ping"), nil)
+	response, err := requester.Request(request, "request", "response", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(response.Payload))
+	assert.Equal(t, request.RequestID, response.RequestID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err = requester.RequestWithContext(ctx, request, "request", "response")
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(response.Payload))
+
+	_, err = requester.Request(request, "unanswered", "response", 50*time.Millisecond)
+	require.Error(t, err)
+}
+
+func TestClientSubscribeWithContext(t *testing.T) {
+	client := newConnectedClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.SubscribeWithContext(ctx, []types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	receive(t, messages)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		client.mutex.Lock()
+		defer client.mutex.Unlock()
+		return len(client.subscriptions) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// The other subscriptions to the topic are kept once the context is done
+	other := make(chan types.MessageEnvelope, 1)
+	subscription, err := client.SubscribeWithHandle(types.TopicChannel{Topic: "test", Messages: other}, nil)
+	require.NoError(t, err)
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	require.NoError(t, client.SubscribeWithContext(ctx, []types.TopicChannel{{Topic: "test", Messages: messages}}, nil))
+	cancel()
+	require.Eventually(t, func() bool {
+		assert.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "probe"}, "test"))
+		select {
+		case <-messages:
+			return false
+		case <-time.After(10 * time.Millisecond):
+			return true
+		}
+	}, time.Second, 20*time.Millisecond)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "kept"}, "test"))
+	for receive(t, other).CorrelationID != "kept" {
+	}
+}
+
+func TestClientMessages(t *testing.T) {
+	client := newConnectedClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		require.Eventually(t, func() bool {
+			client.mutex.Lock()
+			defer client.mutex.Unlock()
+			return len(client.subscriptions) == 1
+		}, time.Second, time.Millisecond)
+		for _, topic := range []string{"edgex/events/core", "edgex/events/device"} {
+			_ = client.Publish(types.MessageEnvelope{}, topic)
+		}
+	}()
+
+	var received []string
+	for message, err := range client.Messages(ctx, "edgex/events/#") {
+		require.NoError(t, err)
+		received = append(received, message.ReceivedTopic)
+		if len(received) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"edgex/events/core", "edgex/events/device"}, received)
+	assert.Empty(t, client.subscriptions)
+}
+
+func TestClientRequestMany(t *testing.T) {
+	requester := newConnectedClient(t)
+
+	for i := 0; i < 3; i++ {
+		responder := newConnectedClient(t)
+		_, err := responder.SubscribeFunc("request", func(message types.MessageEnvelope) error {
+			response, err := types.NewMessageEnvelopeForResponse(nil, message.RequestID, message.CorrelationID, message.ContentType)
+			if err != nil {
+				return err
+			}
+			return responder.Publish(response, "response/"+message.RequestID)
+		}, types.SubscribeOptions{})
+		require.NoError(t, err)
+	}
+
+	request := types.NewMessageEnvelopeForRequest(nil, nil)
+	responses, err := requester.RequestMany(request, "request", "response", 100*time.Millisecond, 0)
+	require.NoError(t, err)
+	assert.Len(t, responses, 3)
+
+	responses, err = requester.RequestMany(types.NewMessageEnvelopeForRequest(nil, nil), "request", "response", time.Second, 1)
+	require.NoError(t, err)
+	assert.Len(t, responses, 1)
+}
+
+func TestClientSubscribeFuncOverflow(t *testing.T) {
+	client := newConnectedClient(t)
+
+	release := make(chan struct{})
+	var overflowed atomic.Int32
+	subscription, err := client.SubscribeFunc("test/overflow", func(message types.MessageEnvelope) error {
+		<-release
+		return nil
+	}, types.SubscribeOptions{
+		BufferSize: 2,
+		Backpressure: types.Backpressure{
+			Policy:     types.OverflowDropNewest,
+			OnOverflow: func(dropped types.MessageEnvelope) { overflowed.Add(1) },
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, client.Publish(types.MessageEnvelope{}, "test/overflow"))
+	}
+
+	require.Eventually(t, func() bool {
+		stats := subscription.Stats()
+		return stats.Received+stats.Dropped == 10
+	}, time.Second, 10*time.Millisecond)
+
+	// At most one message is handled while two are buffered, the others are dropped
+	dropped := subscription.Stats().Dropped
+	assert.GreaterOrEqual(t, dropped, uint64(7))
+	assert.Equal(t, int32(dropped), overflowed.Load())
+	close(release)
+	require.NoError(t, subscription.Unsubscribe())
+}
+
+func TestClientDeadLetterTopic(t *testing.T) {
+	client, err := NewClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: t.Name()},
+		Optional: map[string]string{pkg.DeadLetterTopic: "edgex/dead-letters"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	deadLetters := make(chan types.MessageEnvelope, 1)
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/dead-letters", Messages: deadLetters}}, nil))
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, messageErrors))
+
+	require.NoError(t, client.PublishBinaryData([]byte("not an envelope"), "edgex/events"))
+
+	select {
+	case err := <-messageErrors:
+		assert.Error(t, err, "the decode error must still be reported")
+	case <-time.After(time.Second):
+		require.Fail(t, "decode error not received")
+	}
+
+	var deadLetter types.DeadLetter
+	require.NoError(t, json.Unmarshal(receive(t, deadLetters).Payload, &deadLetter))
+	assert.Equal(t, "edgex/events", deadLetter.Topic)
+	assert.Equal(t, []byte("not an envelope"), deadLetter.Payload)
+	assert.NotEmpty(t, deadLetter.Reason)
+	assertNotReceived(t, messages)
+}
+
+func TestClientFormat(t *testing.T) {
+	_, err := NewClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: t.Name()},
+		Optional: map[string]string{pkg.Format: "xml"},
+	})
+	require.Error(t, err, "an unknown format must be rejected")
+
+	subscriber := newConnectedClient(t)
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, nil))
+
+	// The subscriber decodes the messages with the codec they were encoded with whatever its own format
+	for _, format := range []string{codec.JSON, codec.CBOR, codec.Raw} {
+		publisher, err := NewClient(types.MessageBusConfig{
+			Broker:   types.HostInfo{Host: t.Name()},
+			Optional: map[string]string{pkg.Format: format},
+		})
+		require.NoError(t, err)
+		require.NoError(t, publisher.Connect())
+
+		expected := types.MessageEnvelope{
+			CorrelationID: format,
+			Payload:       []byte{0x00, 0xff},
+			ContentType:   "application/octet-stream",
+			Headers:       map[string][]string{"format": {format}},
+		}
+		require.NoError(t, publisher.Publish(expected, "edgex/events"))
+
+		actual := receive(t, messages)
+		assert.Equal(t, expected.CorrelationID, actual.CorrelationID)
+		assert.Equal(t, expected.Payload, actual.Payload)
+		assert.Equal(t, expected.Headers, actual.Headers)
+	}
+}
+
+func TestClientEncryption(t *testing.T) {
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "AES KEY", Bytes: bytes.Repeat([]byte{1}, 32)}))
+	encrypted := map[string]string{pkg.EncryptionKeyId: "k1", pkg.EncryptionKeyPEMBlock: keyPEM}
+	publisher := newConfiguredClient(t, encrypted)
+	subscriber := newConfiguredClient(t, encrypted)
+	strictSubscriber := newConfiguredClient(t, map[string]string{pkg.EncryptionKeyId: "k1", pkg.EncryptionKeyPEMBlock: keyPEM, pkg.EncryptionStrict: "true"})
+	plaintextClient := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	strictMessages := make(chan types.MessageEnvelope, 1)
+	plaintextMessages := make(chan types.MessageEnvelope, 1)
+	strictErrors := make(chan error, 1)
+	plaintextErrors := make(chan error, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, nil))
+	require.NoError(t, strictSubscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: strictMessages}}, strictErrors))
+	require.NoError(t, plaintextClient.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: plaintextMessages}}, plaintextErrors))
+
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: []byte("secret"), ContentType: "text/plain"}
+	require.NoError(t, publisher.Publish(expected, "edgex/events"))
+
+	for _, messages := range []chan types.MessageEnvelope{messages, strictMessages} {
+		actual := receive(t, messages)
+		assert.Equal(t, expected.Payload, actual.Payload)
+		assert.Empty(t, actual.EncryptionKeyID)
+	}
+
+	// The client without the key can't read the payload
+	assert.Error(t, expectError(t, plaintextErrors))
+	assertNotReceived(t, plaintextMessages)
+
+	// The strict client refuses the plaintext messages the others accept
+	require.NoError(t, plaintextClient.Publish(expected, "edgex/events"))
+	assert.Equal(t, expected.Payload, receive(t, messages).Payload)
+	assert.Equal(t, expected.Payload, receive(t, plaintextMessages).Payload)
+	assert.Error(t, expectError(t, strictErrors))
+	assertNotReceived(t, strictMessages)
+
+	_, err := NewClient(types.MessageBusConfig{Optional: map[string]string{pkg.EncryptionKeyId: "k1"}})
+	require.Error(t, err, "the encryption keys must be configured along with the key id")
+}
+
+func TestClientSigning(t *testing.T) {
+	secretPEM := func(fill byte, headers map[string]string) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "HMAC KEY", Headers: headers, Bytes: bytes.Repeat([]byte{fill}, 32)}))
+	}
+	coreCommand := newConfiguredClient(t, map[string]string{pkg.SigningIdentity: "core-command", pkg.SigningKeyPEMBlock: secretPEM(1, nil)})
+	impostor := newConfiguredClient(t, map[string]string{pkg.SigningIdentity: "core-command", pkg.SigningKeyPEMBlock: secretPEM(2, nil)})
+	unsigned := newConnectedClient(t)
+	deviceService := newConfiguredClient(t, map[string]string{
+		pkg.VerificationKeyPEMBlock: secretPEM(1, map[string]string{"Signer": "core-command"}),
+		pkg.SignatureRequired:       "true",
+		pkg.DeadLetterTopic:         "edgex/dead-letters",
+	})
+
+	deadLetters := make(chan types.MessageEnvelope, 2)
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 2)
+	require.NoError(t, unsigned.Subscribe([]types.TopicChannel{{Topic: "edgex/dead-letters", Messages: deadLetters}}, nil))
+	require.NoError(t, deviceService.Subscribe([]types.TopicChannel{{Topic: "edgex/core/command/request/#", Messages: messages}}, messageErrors))
+
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: []byte(`{"command":"set"}`), ContentType: "application/json"}
+	require.NoError(t, coreCommand.Publish(expected, "edgex/core/command/request/device"))
+
+	actual := receive(t, messages)
+	assert.Equal(t, expected.Payload, actual.Payload)
+	assert.Equal(t, "core-command", actual.Signer, "the verified signer must be exposed")
+
+	// Both the forged and the unsigned messages are rejected to the errors and the dead letter topic
+	require.NoError(t, impostor.Publish(expected, "edgex/core/command/request/device"))
+	require.NoError(t, unsigned.Publish(expected, "edgex/core/command/request/device"))
+	for range 2 {
+		assert.Error(t, expectError(t, messageErrors))
+
+		var deadLetter types.DeadLetter
+		require.NoError(t, json.Unmarshal(receive(t, deadLetters).Payload, &deadLetter))
+		assert.Equal(t, "edgex/core/command/request/device", deadLetter.Topic)
+	}
+	assertNotReceived(t, messages)
+}
+
+func TestClientMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.SetDefault(registry)
+	defer metrics.SetDefault(metrics.DefaultRegistry)
+
+	client := newConnectedClient(t)
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, messageErrors))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{Payload: []byte("event")}, "edgex/events"))
+	receive(t, messages)
+	require.NoError(t, client.PublishBinaryData([]byte("not an envelope"), "edgex/events"))
+	select {
+	case <-messageErrors:
+	case <-time.After(time.Second):
+		require.Fail(t, "decode error not received")
+	}
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/#"))
+
+	values := make(map[string]float64)
+	for _, metric := range registry.Snapshot() {
+		key := metric.Name + "/" + metric.Labels[metrics.TopicLabel]
+		if metric.Kind == metrics.Histogram {
+			values[key] = float64(metric.Count)
+			continue
+		}
+		values[key] = metric.Value
+	}
+
+	assert.Equal(t, map[string]float64{
+		metrics.MessagesPublished + "/edgex/events": 2,
+		metrics.BytesPublished + "/edgex/events":    float64(len("event") + len("not an envelope")),
+		metrics.PublishDuration + "/edgex/events":   2,
+		metrics.PublishErrors + "/edgex/#":          1,
+		metrics.PublishDuration + "/edgex/#":        1,
+		metrics.MessagesReceived + "/edgex/#":       1,
+		metrics.BytesReceived + "/edgex/#":          float64(len("event")),
+		metrics.DecodeErrors + "/edgex/#":           1,
+		metrics.Connections + "/":                   1,
+	}, values)
+}
+
+// recordingTracer records the spans it starts, whose span IDs are sequential in the trace of their parent.
+type recordingTracer struct {
+	spans []*recordedSpan
+	mutex sync.Mutex
+}
+
+type recordedSpan struct {
+	operation    tracing.Operation
+	topic        string
+	parent       types.TraceContext
+	traceContext types.TraceContext
+	ended        atomic.Bool
+}
+
+func (s *recordedSpan) TraceContext() types.TraceContext { return s.traceContext }
+
+func (s *recordedSpan) End(error) { s.ended.Store(true) }
+
+func (r *recordingTracer) Start(operation tracing.Operation, topic string, parent types.TraceContext) tracing.Span {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	traceID := parent.TraceID()
+	if traceID == "" {
+		traceID = fmt.Sprintf("%032x", len(r.spans)+1)
+	}
+	span := &recordedSpan{
+		operation:    operation,
+		topic:        topic,
+		parent:       parent,
+		traceContext: types.TraceContext{TraceParent: fmt.Sprintf("00-%s-%016x-01", traceID, len(r.spans)+1)},
+	}
+	r.spans = append(r.spans, span)
+	return span
+}
+
+func (r *recordingTracer) started() []*recordedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]*recordedSpan(nil), r.spans...)
+}
+
+func TestClientTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	client := newConnectedClient(t)
+	handled := make(chan types.MessageEnvelope, 1)
+	subscription, err := client.SubscribeFunc("edgex/events/#", func(message types.MessageEnvelope) error {
+		handled <- message
+		return nil
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	parent := types.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	message := types.NewMessageEnvelope([]byte("event"), types.ContextWithTraceContext(context.Background(), parent))
+	require.NoError(t, client.Publish(message, "edgex/events/device"))
+	received := receive(t, handled)
+
+	spans := tracer.started()
+	require.Len(t, spans, 3)
+	publish, deliver, process := spans[0], spans[1], spans[2]
+	assert.Equal(t, tracing.Publish, publish.operation)
+	assert.Equal(t, "edgex/events/device", publish.topic)
+	assert.Equal(t, parent, publish.parent)
+	assert.Equal(t, tracing.Deliver, deliver.operation)
+	assert.Equal(t, "edgex/events/#", deliver.topic)
+	assert.Equal(t, publish.traceContext, deliver.parent)
+	assert.Equal(t, tracing.Process, process.operation)
+	assert.Equal(t, deliver.traceContext, process.parent)
+	assert.Equal(t, process.traceContext, received.TraceContext(), "the handler must receive the trace context of its span")
+	assert.Equal(t, parent.TraceID(), received.TraceContext().TraceID())
+	assert.Eventually(t, process.ended.Load, time.Second, 10*time.Millisecond)
+	assert.True(t, publish.ended.Load())
+	assert.True(t, deliver.ended.Load())
+}
+
+func TestClientCompression(t *testing.T) {
+	publisher := newConfiguredClient(t, map[string]string{pkg.Compression: pkg.ZstdCompression, pkg.CompressionThreshold: "64"})
+	// The payloads are decompressed whatever the compression of the receiver
+	subscriber := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	binaryMessages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, messageErrors))
+	require.NoError(t, subscriber.SubscribeBinaryData([]types.TopicChannel{{Topic: "edgex/binary", Messages: binaryMessages}}, messageErrors))
+
+	payload := bytes.Repeat([]byte(`{"reading":1}`), 100)
+	for _, expected := range [][]byte{payload, payload[:10]} {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123", Payload: expected}, "edgex/events"))
+		actual := receive(t, messages)
+		assert.Equal(t, expected, actual.Payload)
+		assert.Empty(t, actual.ContentEncoding)
+
+		require.NoError(t, publisher.PublishBinaryData(expected, "edgex/binary"))
+		assert.Equal(t, expected, receive(t, binaryMessages).Payload)
+	}
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{Payload: []byte("data"), ContentEncoding: "lz4"}, "edgex/events"))
+	assert.Error(t, expectError(t, messageErrors))
+	assertNotReceived(t, messages)
+
+	_, err := NewClient(types.MessageBusConfig{Optional: map[string]string{pkg.Compression: "lz4"}})
+	require.Error(t, err)
+}
+
+func TestClientChunking(t *testing.T) {
+	publisher := newConfiguredClient(t, map[string]string{pkg.MaxMessageSize: "1024", pkg.Format: codec.CBOR})
+	// The chunks are reassembled by the receivers configuring the reassembly only
+	subscriber := newConfiguredClient(t, map[string]string{pkg.ChunkReassemblyTimeout: "30s"})
+	unconfigured := newConnectedClient(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	unconfiguredMessages := make(chan types.MessageEnvelope, 1)
+	unconfiguredErrors := make(chan error, 100)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, messageErrors))
+	require.NoError(t, unconfigured.Subscribe([]types.TopicChannel{{Topic: "edgex/firmware", Messages: unconfiguredMessages}}, unconfiguredErrors))
+
+	firmware := bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04, 0x05}, 20000)
+	expected := types.MessageEnvelope{CorrelationID: "123", ContentType: "application/octet-stream", Payload: firmware, Headers: map[string][]string{"version": {"1.2.0"}}}
+	require.NoError(t, publisher.Publish(expected, "edgex/firmware"))
+
+	actual := receive(t, messages)
+	assert.Equal(t, expected.Payload, actual.Payload)
+	assert.Equal(t, expected.Headers, actual.Headers)
+	assert.Equal(t, "edgex/firmware", actual.ReceivedTopic)
+	assertNotReceived(t, messages)
+
+	assert.ErrorContains(t, expectError(t, unconfiguredErrors), "chunking isn't configured")
+	assertNotReceived(t, unconfiguredMessages)
+
+	// The messages below the maximum size aren't split
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "456", Payload: []byte("small")}, "edgex/events"))
+	assert.Equal(t, "456", receive(t, messages).CorrelationID)
+
+	select {
+	case err := <-messageErrors:
+		require.NoError(t, err)
+	default:
+	}
+
+	_, err := NewClient(types.MessageBusConfig{Optional: map[string]string{pkg.MaxMessageSize: "1MB"}})
+	require.Error(t, err)
+}
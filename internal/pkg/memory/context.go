@@ -0,0 +1,41 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package memory
+
+import (
+	"context"
+	"iter"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// PublishWithContext sends the message to all the subscriptions matching the topic. Publishing never waits for the
+// subscribers, so ctx is only checked before publishing.
+func (c *Client) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Publish(message, topic)
+}
+
+// SubscribeWithContext creates a subscription for the specified topics which is removed once the context is done.
+func (c *Client) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return pkg.SubscribeWithContext(ctx, c.SubscribeWithHandle, topics, messageErrors)
+}
+
+// RequestWithContext publishes a request and waits for a response until the context is done
+func (c *Client) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	publish := func(message types.MessageEnvelope, topic string) error {
+		return c.PublishWithContext(ctx, message, topic)
+	}
+
+	return c.requester.Request(ctx, publish, message, requestTopic, responseTopicPrefix)
+}
+
+// Messages returns an iterator over the messages received on the topic. The topic is unsubscribed once the loop
+// exits or ctx is done.
+func (c *Client) Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error] {
+	return pkg.Messages(ctx, c.SubscribeWithHandle, topic)
+}
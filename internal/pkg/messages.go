@@ -0,0 +1,51 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"iter"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Messages returns an iterator over the messages received on the topic and the errors receiving them. The subscription
+// is created with subscribeWithHandle when the iteration starts and unsubscribed when the loop exits, either because
+// the consumer breaks out of it, ctx is done or the subscription is removed by the message client. An error creating
+// the subscription is yielded once before the iteration ends.
+func Messages(
+	ctx context.Context,
+	subscribeWithHandle func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error),
+	topic string) iter.Seq2[types.MessageEnvelope, error] {
+	return func(yield func(types.MessageEnvelope, error) bool) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		messages := make(chan types.MessageEnvelope)
+		messageErrors := make(chan error)
+		subscription, err := subscribeWithHandle(types.TopicChannel{Topic: topic, Messages: messages}, messageErrors)
+		if err != nil {
+			yield(types.MessageEnvelope{}, err)
+			return
+		}
+		defer func() { _ = subscription.Unsubscribe() }()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-subscription.Done():
+				return
+			case message := <-messages:
+				if !yield(message, nil) {
+					return
+				}
+			case err := <-messageErrors:
+				if !yield(types.MessageEnvelope{}, err) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// waitForSubscription waits until the iterator has subscribed to the topic on the fake broker.
+func waitForSubscription(t *testing.T, broker *fakeBroker, topic string) {
+	require.Eventually(t, func() bool {
+		broker.mutex.Lock()
+		defer broker.mutex.Unlock()
+		_, exists := broker.topics[topic]
+		return exists
+	}, time.Second, time.Millisecond)
+}
+
+func TestMessages(t *testing.T) {
+	broker := newFakeBroker()
+
+	go func() {
+		waitForSubscription(t, broker, "test")
+		broker.publish("test", types.MessageEnvelope{CorrelationID: "1"})
+		broker.publishError("test", errors.New("receive failed"))
+		broker.publish("test", types.MessageEnvelope{CorrelationID: "2"})
+	}()
+
+	var received []string
+	var receivedErrors []error
+	for message, err := range Messages(context.Background(), broker.subscribeWithHandle, "test") {
+		if err != nil {
+			receivedErrors = append(receivedErrors, err)
+			continue
+		}
+
+		received = append(received, message.CorrelationID)
+		if len(received) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"1", "2"}, received)
+	require.Len(t, receivedErrors, 1)
+	assert.EqualError(t, receivedErrors[0], "receive failed")
+	assert.Equal(t, 1, broker.unsubscribed, "breaking out of the loop must unsubscribe")
+}
+
+func TestMessagesContextDone(t *testing.T) {
+	broker := newFakeBroker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitForSubscription(t, broker, "test")
+		cancel()
+	}()
+
+	for range Messages(ctx, broker.subscribeWithHandle, "test") {
+		require.Fail(t, "no message expected")
+	}
+	assert.Equal(t, 1, broker.unsubscribed, "cancelling the context must unsubscribe")
+
+	// The topic isn't subscribed if the context is already done
+	for range Messages(ctx, broker.subscribeWithHandle, "test") {
+		require.Fail(t, "no message expected")
+	}
+	assert.Equal(t, 1, broker.subscribed)
+}
+
+func TestMessagesRemoved(t *testing.T) {
+	broker := newFakeBroker()
+
+	go func() {
+		waitForSubscription(t, broker, "test")
+		broker.manager.Remove("test")
+	}()
+
+	for range Messages(context.Background(), broker.subscribeWithHandle, "test") {
+		require.Fail(t, "no message expected")
+	}
+}
+
+func TestMessagesSubscribeError(t *testing.T) {
+	broker := newFakeBroker()
+	broker.subscribeErr = errors.New("subscribe failed")
+
+	count := 0
+	for _, err := range Messages(context.Background(), broker.subscribeWithHandle, "test") {
+		count++
+		assert.EqualError(t, err, "subscribe failed")
+	}
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+)
+
+// RecordPublish records the metrics of a message with a payload of size bytes published to the topic, which took
+// since started and failed with err if not nil.
+func RecordPublish(topic string, size int, started time.Time, err error) {
+	recorder := metrics.Default()
+	labels := metrics.Labels{metrics.TopicLabel: topic}
+
+	recorder.ObserveHistogram(metrics.PublishDuration, labels, time.Since(started).Seconds())
+	if err != nil {
+		recorder.IncCounter(metrics.PublishErrors, labels, 1)
+		return
+	}
+
+	recorder.IncCounter(metrics.MessagesPublished, labels, 1)
+	recorder.IncCounter(metrics.BytesPublished, labels, float64(size))
+}
+
+// RecordReceive records the metrics of a message with a payload of size bytes received for the subscribed topic.
+func RecordReceive(topic string, size int) {
+	recorder := metrics.Default()
+	labels := metrics.Labels{metrics.TopicLabel: topic}
+
+	recorder.IncCounter(metrics.MessagesReceived, labels, 1)
+	recorder.IncCounter(metrics.BytesReceived, labels, float64(size))
+}
+
+// RecordDecodeError records a message received for the subscribed topic which couldn't be decoded.
+func RecordDecodeError(topic string) {
+	metrics.Default().IncCounter(metrics.DecodeErrors, metrics.Labels{metrics.TopicLabel: topic}, 1)
+}
+
+// RecordDrop records a message received for the subscribed topic which was dropped by the overflow policy.
+func RecordDrop(topic string) {
+	metrics.Default().IncCounter(metrics.MessagesDropped, metrics.Labels{metrics.TopicLabel: topic}, 1)
+}
+
+// RecordErrorDropped records an error of a message received for the subscribed topic which was dropped rather than
+// reported to the subscriber.
+func RecordErrorDropped(topic string) {
+	metrics.Default().IncCounter(metrics.ErrorsDropped, metrics.Labels{metrics.TopicLabel: topic}, 1)
+}
+
+// recordCompression records the payload of size bytes published to the topic compressed to compressedSize bytes.
+func recordCompression(topic string, size int, compressedSize int) {
+	recorder := metrics.Default()
+	labels := metrics.Labels{metrics.TopicLabel: topic}
+
+	recorder.IncCounter(metrics.CompressionInputBytes, labels, float64(size))
+	recorder.IncCounter(metrics.CompressionOutputBytes, labels, float64(compressedSize))
+	recorder.ObserveHistogram(metrics.CompressionRatio, labels, float64(compressedSize)/float64(size))
+}
+
+// recordChunks records the message published to the topic split into count chunks.
+func recordChunks(topic string, count int) {
+	metrics.Default().IncCounter(metrics.ChunksPublished, metrics.Labels{metrics.TopicLabel: topic}, float64(count))
+}
+
+// recordChunkTransferDropped records a chunked message received for the subscribed topic which was dropped before
+// being reassembled.
+func recordChunkTransferDropped(topic string) {
+	metrics.Default().IncCounter(metrics.ChunkTransfersDropped, metrics.Labels{metrics.TopicLabel: topic}, 1)
+}
+
+// recordConnection records a message client connecting, delta being 1, or disconnecting, delta being -1.
+func recordConnection(delta float64) {
+	metrics.Default().AddGauge(metrics.Connections, nil, delta)
+}
+
+// startRequest records a request to the topic waiting for its response. The returned function must be called once it
+// completes with the error of the request, which records its round-trip time if it succeeded or the timeout.
+func startRequest(requestTopic string) func(err error) {
+	// The same Recorder is used for the whole request, so the gauge stays balanced if the default is replaced meanwhile
+	recorder := metrics.Default()
+	labels := metrics.Labels{metrics.TopicLabel: requestTopic}
+	started := time.Now()
+	done := trackInFlight(recorder, labels)
+
+	return func(err error) {
+		done()
+
+		var timeoutErr RequestTimeoutErr
+		switch {
+		case err == nil:
+			recorder.ObserveHistogram(metrics.RequestDuration, labels, time.Since(started).Seconds())
+		case errors.As(err, &timeoutErr):
+			recorder.IncCounter(metrics.RequestTimeouts, labels, 1)
+		}
+	}
+}
+
+// trackInFlight records a request waiting for its responses until the returned function is called.
+func trackInFlight(recorder metrics.Recorder, labels metrics.Labels) func() {
+	recorder.AddGauge(metrics.RequestsInFlight, labels, 1)
+
+	return func() {
+		recorder.AddGauge(metrics.RequestsInFlight, labels, -1)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// useRegistry records the metrics to a new Registry for the duration of the test.
+func useRegistry(t *testing.T) *metrics.Registry {
+	registry := metrics.NewRegistry()
+	metrics.SetDefault(registry)
+	t.Cleanup(func() { metrics.SetDefault(metrics.DefaultRegistry) })
+	return registry
+}
+
+// metricValues returns the value of the counters and gauges, or the count of the histograms, by name.
+func metricValues(registry *metrics.Registry) map[string]float64 {
+	values := make(map[string]float64)
+	for _, metric := range registry.Snapshot() {
+		if metric.Kind == metrics.Histogram {
+			values[metric.Name] += float64(metric.Count)
+			continue
+		}
+		values[metric.Name] += metric.Value
+	}
+	return values
+}
+
+func TestRequesterMetrics(t *testing.T) {
+	registry := useRegistry(t)
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	_, err := requester.Request(context.Background(), respondingPublish(broker, "edgex/responses", nil), types.MessageEnvelope{}, "edgex/requests", "edgex/responses")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = requester.Request(ctx, func(types.MessageEnvelope, string) error { return nil }, types.MessageEnvelope{}, "edgex/requests", "edgex/responses")
+	require.Error(t, err)
+
+	_, err = requester.Request(context.Background(), func(types.MessageEnvelope, string) error {
+		return errors.New("publish failed")
+	}, types.MessageEnvelope{}, "edgex/requests", "edgex/responses")
+	require.Error(t, err)
+
+	values := metricValues(registry)
+	assert.Equal(t, float64(1), values[metrics.RequestDuration], "only the requests which received a response are timed")
+	assert.Equal(t, float64(1), values[metrics.RequestTimeouts])
+	assert.Equal(t, float64(0), values[metrics.RequestsInFlight])
+}
+
+func TestConnectionTrackerMetrics(t *testing.T) {
+	registry := useRegistry(t)
+	tracker := NewConnectionTracker()
+
+	tracker.SetConnected()
+	assert.Equal(t, float64(1), metricValues(registry)[metrics.Connections])
+
+	tracker.SetConnectionLost(errors.New("connection lost"))
+	tracker.SetReconnecting()
+	assert.Equal(t, float64(0), metricValues(registry)[metrics.Connections])
+
+	tracker.SetConnected()
+	tracker.SetConnected()
+	assert.Equal(t, float64(1), metricValues(registry)[metrics.Connections])
+
+	tracker.SetDisconnected()
+	tracker.SetDisconnected()
+	assert.Equal(t, float64(0), metricValues(registry)[metrics.Connections])
+}
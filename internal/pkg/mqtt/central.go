@@ -4,8 +4,11 @@ package mqtt
 
 import (
 	"errors"
+	"time"
 
 	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
 
@@ -18,9 +21,15 @@ func (mc *Client) PublishBinaryData(data []byte, topic string) error {
 		return errors.New("mqtt client not exists")
 	}
 
+	data, err := mc.processor.OutgoingBinary(data, topic)
+	if err != nil {
+		return NewOperationErr(PublishOperation, err.Error())
+	}
+
 	optionsReader := mc.mqttClient.OptionsReader()
 
-	return getTokenError(
+	started := time.Now()
+	err = getTokenError(
 		mc.mqttClient.Publish(
 			topic,
 			optionsReader.WillQos(),
@@ -29,6 +38,9 @@ func (mc *Client) PublishBinaryData(data []byte, topic string) error {
 		optionsReader.ConnectTimeout(),
 		PublishOperation,
 		"Unable to publish message")
+	pkg.RecordPublish(topic, len(data), started, err)
+
+	return err
 }
 
 func (mc *Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors chan error) error {
@@ -39,7 +51,7 @@ func (mc *Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors
 	optionsReader := mc.mqttClient.OptionsReader()
 
 	for _, topic := range topics {
-		handler := newBinaryDataMessageHandler(topic.Messages)
+		handler := newBinaryDataMessageHandler(mc.processor, topic, messageErrors)
 		qos := optionsReader.WillQos()
 
 		// Since the MQTT client might try to subscribe to the same topic and get the error 'not currently connected and ResumeSubs not set',
@@ -67,12 +79,24 @@ func (mc *Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors
 	return nil
 }
 
-// newBinaryDataMessageHandler creates a function which propagates the received messages to the proper channel.
-func newBinaryDataMessageHandler(messageChannel chan<- types.MessageEnvelope) pahoMqtt.MessageHandler {
+// newBinaryDataMessageHandler creates a function which propagates the received messages to the proper channel, or the
+// error decompressing them to the errors channel.
+func newBinaryDataMessageHandler(
+	processor *pkg.EnvelopeProcessor,
+	topic types.TopicChannel,
+	errorChannel chan<- error) pahoMqtt.MessageHandler {
+	errorForwarder := newErrorForwarder(topic.Topic, errorChannel)
+
 	return func(client pahoMqtt.Client, message pahoMqtt.Message) {
+		data, err := processor.IncomingBinary(message.Payload())
+		if err != nil {
+			errorForwarder.send(err)
+			return
+		}
+
 		// Use MessageEnvelope.Payload to store the binary data instead of unmarshalling binary to MessageEnvelope
-		messageEnvelope := types.NewMessageEnvelopeForRequest(message.Payload(), nil)
+		messageEnvelope := types.NewMessageEnvelopeForRequest(data, nil)
 		messageEnvelope.ReceivedTopic = message.Topic()
-		messageChannel <- messageEnvelope
+		topic.Messages <- messageEnvelope
 	}
 }
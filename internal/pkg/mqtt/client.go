@@ -15,24 +15,42 @@
 package mqtt
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 
 	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
-// ClientCreator defines the function signature for creating an MQTT client.
-type ClientCreator func(config types.MessageBusConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error)
+// ClientCreator defines the function signature for creating an MQTT client. The handlers must be set in the options
+// of the created client.
+type ClientCreator func(config types.MessageBusConfig, handlers ConnectionHandlers) (pahoMqtt.Client, error)
+
+// ConnectionHandlers are the handlers of the Client for the connection events of the underlying MQTT client.
+type ConnectionHandlers struct {
+	OnConnect        pahoMqtt.OnConnectHandler
+	OnConnectionLost pahoMqtt.ConnectionLostHandler
+	OnReconnecting   pahoMqtt.ReconnectHandler
+}
+
+// setHandlers sets the handlers in the options of the MQTT client.
+func (h ConnectionHandlers) setHandlers(clientOptions *pahoMqtt.ClientOptions) {
+	clientOptions.SetOnConnectHandler(h.OnConnect)
+	clientOptions.SetConnectionLostHandler(h.OnConnectionLost)
+	clientOptions.SetReconnectingHandler(h.OnReconnecting)
+}
 
 // MessageMarshaller defines the function signature for marshaling structs into []byte.
 type MessageMarshaller func(v interface{}) ([]byte, error)
@@ -50,6 +68,11 @@ type Client struct {
 	unmarshaller          MessageUnmarshaller
 	existingSubscriptions map[string]existingSubscription
 	subscriptionMutex     *sync.Mutex
+	subscriptionManager   *pkg.SubscriptionManager
+	requester             *pkg.Requester
+	connection            *pkg.ConnectionTracker
+	deadLetterer          *pkg.DeadLetterer
+	processor             *pkg.EnvelopeProcessor
 }
 
 type existingSubscription struct {
@@ -59,20 +82,58 @@ type existingSubscription struct {
 	errors  chan error
 }
 
-// NewMQTTClient constructs a new MQTT client based on the options provided.
+// NewMQTTClient constructs a new MQTT client based on the options provided. The messages are encoded with the codec
+// named by the Format of the Optional properties, JSON by default.
 func NewMQTTClient(config types.MessageBusConfig) (*Client, error) {
+	envelopeCodec, err := codec.Get(config.Optional[pkg.Format])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", pkg.Format, err)
+	}
+
+	processor, err := pkg.NewEnvelopeProcessor(config.Optional)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &Client{
 		creator:               DefaultClientCreator(),
 		configuration:         config,
-		marshaller:            json.Marshal,
-		unmarshaller:          json.Unmarshal,
+		marshaller:            NewCodecMarshaller(envelopeCodec),
+		unmarshaller:          CodecUnmarshaller,
 		existingSubscriptions: map[string]existingSubscription{},
 		subscriptionMutex:     new(sync.Mutex),
+		subscriptionManager:   pkg.NewSubscriptionManager(),
+		connection:            pkg.NewConnectionTracker(),
+		processor:             processor,
 	}
+	client.requester = pkg.NewRequester(client.SubscribeWithHandle)
+	client.deadLetterer = pkg.NewDeadLetterer(config.Optional, client.Publish)
 
 	return client, nil
 }
 
+// NewCodecMarshaller creates a MessageMarshaller which encodes the MessageEnvelope with the codec.
+func NewCodecMarshaller(envelopeCodec codec.Codec) MessageMarshaller {
+	return func(v interface{}) ([]byte, error) {
+		envelope, ok := v.(types.MessageEnvelope)
+		if !ok {
+			return nil, fmt.Errorf("unable to encode %T: only MessageEnvelope is supported", v)
+		}
+
+		return envelopeCodec.Encode(envelope)
+	}
+}
+
+// CodecUnmarshaller is a MessageUnmarshaller which decodes the MessageEnvelope with the codec it was encoded with.
+func CodecUnmarshaller(data []byte, v interface{}) error {
+	envelope, ok := v.(*types.MessageEnvelope)
+	if !ok {
+		return fmt.Errorf("unable to decode into %T: only *MessageEnvelope is supported", v)
+	}
+
+	return codec.Decode(data, envelope)
+}
+
 // NewMQTTClientWithCreator constructs a new MQTT client based on the options and ClientCreator provided.
 func NewMQTTClientWithCreator(
 	config types.MessageBusConfig,
@@ -80,6 +141,11 @@ func NewMQTTClientWithCreator(
 	unmarshaller MessageUnmarshaller,
 	creator ClientCreator) (*Client, error) {
 
+	processor, err := pkg.NewEnvelopeProcessor(config.Optional)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &Client{
 		creator:               creator,
 		configuration:         config,
@@ -87,7 +153,12 @@ func NewMQTTClientWithCreator(
 		unmarshaller:          unmarshaller,
 		existingSubscriptions: make(map[string]existingSubscription),
 		subscriptionMutex:     new(sync.Mutex),
+		subscriptionManager:   pkg.NewSubscriptionManager(),
+		connection:            pkg.NewConnectionTracker(),
+		processor:             processor,
 	}
+	client.requester = pkg.NewRequester(client.SubscribeWithHandle)
+	client.deadLetterer = pkg.NewDeadLetterer(config.Optional, client.Publish)
 
 	return client, nil
 }
@@ -98,7 +169,11 @@ func (mc *Client) Connect() error {
 	if mc.mqttClient == nil {
 		// Move created MQTT Client here since we need to set the onConnectHandler which needs to have access to
 		// the Client's activeSubscriptions. This was not possible from the factory method.
-		mqttClient, err := mc.creator(mc.configuration, mc.onConnectHandler)
+		mqttClient, err := mc.creator(mc.configuration, ConnectionHandlers{
+			OnConnect:        mc.onConnectHandler,
+			OnConnectionLost: mc.onConnectionLostHandler,
+			OnReconnecting:   mc.onReconnectingHandler,
+		})
 		if err != nil {
 			return err
 		}
@@ -112,16 +187,25 @@ func (mc *Client) Connect() error {
 
 	optionsReader := mc.mqttClient.OptionsReader()
 
-	return getTokenError(
+	err := getTokenError(
 		mc.mqttClient.Connect(),
 		optionsReader.ConnectTimeout(),
 		ConnectOperation,
 		"Unable to connect")
+	if err != nil {
+		return err
+	}
+
+	mc.connection.SetConnected()
+
+	return nil
 }
 
 func (mc *Client) onConnectHandler(_ pahoMqtt.Client) {
 	optionsReader := mc.mqttClient.OptionsReader()
 
+	mc.connection.SetConnected()
+
 	mc.subscriptionMutex.Lock()
 	defer mc.subscriptionMutex.Unlock()
 
@@ -131,31 +215,77 @@ func (mc *Client) onConnectHandler(_ pahoMqtt.Client) {
 		token := mc.mqttClient.Subscribe(subscription.topic, subscription.qos, subscription.handler)
 		message := fmt.Sprintf("Failed to re-create subscription for topic=%s", subscription.topic)
 		err := getTokenError(token, optionsReader.ConnectTimeout(), SubscribeOperation, message)
+		mc.connection.SubscriptionRestored(subscription.topic, err)
 		if err != nil {
 			subscription.errors <- err
 		}
 	}
 }
 
+func (mc *Client) onConnectionLostHandler(_ pahoMqtt.Client, err error) {
+	mc.connection.SetConnectionLost(err)
+}
+
+func (mc *Client) onReconnectingHandler(_ pahoMqtt.Client, _ *pahoMqtt.ClientOptions) {
+	mc.connection.SetReconnecting()
+}
+
+// IsConnected reports whether the client is connected to the MQTT server.
+func (mc *Client) IsConnected() bool {
+	return mc.connection.IsConnected()
+}
+
+// State returns the state of the connection to the MQTT server.
+func (mc *Client) State() types.ConnectionState {
+	return mc.connection.State()
+}
+
+// Events returns the channel the changes of the connection to the MQTT server are emitted on. The events of a lost
+// connection are only followed by the Reconnecting and Reconnected events when AutoReconnect is enabled.
+func (mc *Client) Events() <-chan types.ConnectionEvent {
+	return mc.connection.Events()
+}
+
 // Publish sends a message to the connected MQTT server.
 func (mc *Client) Publish(message types.MessageEnvelope, topic string) error {
-	marshaledMessage, err := mc.marshaller(message)
+	return mc.publish(message, topic, func(token pahoMqtt.Token, timeout time.Duration) error {
+		return getTokenError(token, timeout, PublishOperation, "Unable to publish message")
+	})
+}
+
+// publish processes, encodes and publishes the message, in chunks if needed, waiting for the token of every publish
+// with waitToken until it fails.
+func (mc *Client) publish(message types.MessageEnvelope, topic string, waitToken func(token pahoMqtt.Token, timeout time.Duration) error) (err error) {
+	if mc.mqttClient == nil {
+		return errors.New("mqtt client not exists")
+	}
+
+	span := pkg.StartSpan(tracing.Publish, topic, &message)
+	defer func() { span.End(err) }()
+
+	if err = mc.processor.Outgoing(&message, topic); err != nil {
+		return NewOperationErr(PublishOperation, err.Error())
+	}
+
+	marshaledMessages, err := pkg.EncodeChunks(mc.processor, message, topic, func(message types.MessageEnvelope) ([]byte, error) {
+		return mc.marshaller(message)
+	})
 	if err != nil {
 		return NewOperationErr(PublishOperation, err.Error())
 	}
 
 	optionsReader := mc.mqttClient.OptionsReader()
 
-	return getTokenError(
-		mc.mqttClient.Publish(
-			topic,
-			optionsReader.WillQos(),
-			optionsReader.WillRetained(),
-			marshaledMessage),
-		optionsReader.ConnectTimeout(),
-		PublishOperation,
-		"Unable to publish message")
+	started := time.Now()
+	for _, marshaledMessage := range marshaledMessages {
+		token := mc.mqttClient.Publish(topic, optionsReader.WillQos(), optionsReader.WillRetained(), marshaledMessage)
+		if err = waitToken(token, optionsReader.ConnectTimeout()); err != nil {
+			break
+		}
+	}
+	pkg.RecordPublish(topic, len(message.Payload), started, err)
 
+	return err
 }
 
 // Subscribe creates a subscription for the specified topics.
@@ -166,7 +296,7 @@ func (mc *Client) Subscribe(topics []types.TopicChannel, messageErrors chan erro
 	defer mc.subscriptionMutex.Unlock()
 
 	for _, topic := range topics {
-		handler := newMessageHandler(mc.unmarshaller, topic.Messages, messageErrors)
+		handler := newMessageHandler(mc.unmarshaller, mc.processor, topic, messageErrors, mc.deadLetterer)
 		qos := optionsReader.WillQos()
 
 		token := mc.mqttClient.Subscribe(topic.Topic, qos, handler)
@@ -186,13 +316,47 @@ func (mc *Client) Subscribe(topics []types.TopicChannel, messageErrors chan erro
 	return nil
 }
 
-// Request publishes a request and waits for a response
+// SubscribeWithHandle creates a subscription for the specified topic and returns its handle. The subscription on the
+// MQTT server is shared by all the handles for the topic.
+func (mc *Client) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	return mc.subscriptionManager.Subscribe(topic, messageErrors, mc.Subscribe, mc.unsubscribe)
+}
+
+// SubscribeFunc creates a subscription for the specified topic which invokes the handler for every received message.
+func (mc *Client) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	return pkg.SubscribeFunc(mc.SubscribeWithHandle, topic, handler, options, mc.deadLetterer)
+}
+
+// Request publishes a request and waits for a response. The response topic prefix is subscribed by the first request
+// using it and stays subscribed for the following requests.
 func (mc *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
-	return pkg.DoRequest(mc.Subscribe, mc.Unsubscribe, mc.Publish, message, requestTopic, responseTopicPrefix, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return mc.requester.Request(ctx, mc.Publish, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestMany publishes a request and collects the responses received within the window, stopping early once
+// maxResponses responses are received. A maxResponses of 0 collects all the responses received within the window.
+func (mc *Client) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	return mc.requester.RequestMany(ctx, mc.Publish, message, requestTopic, responseTopicPrefix, maxResponses)
 }
 
 // Unsubscribe to unsubscribe from the specified topics.
 func (mc *Client) Unsubscribe(topics ...string) error {
+	if err := mc.unsubscribe(topics...); err != nil {
+		return err
+	}
+
+	mc.subscriptionManager.Remove(topics...)
+
+	return nil
+}
+
+func (mc *Client) unsubscribe(topics ...string) error {
 	mc.subscriptionMutex.Lock()
 	defer mc.subscriptionMutex.Unlock()
 
@@ -219,13 +383,15 @@ func (mc *Client) Disconnect() error {
 	// disconnecting.
 	optionsReader := mc.mqttClient.OptionsReader()
 	mc.mqttClient.Disconnect(uint(optionsReader.ConnectTimeout() * time.Millisecond))
+	mc.connection.SetDisconnected()
+	mc.subscriptionManager.RemoveAll()
 
 	return nil
 }
 
 // DefaultClientCreator returns a default function for creating MQTT clients.
 func DefaultClientCreator() ClientCreator {
-	return func(config types.MessageBusConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error) {
+	return func(config types.MessageBusConfig, handlers ConnectionHandlers) (pahoMqtt.Client, error) {
 		clientConfiguration, err := CreateMQTTClientConfiguration(config)
 		if err != nil {
 			return nil, err
@@ -237,7 +403,7 @@ func DefaultClientCreator() ClientCreator {
 			return nil, err
 		}
 
-		clientOptions.OnConnect = handler
+		handlers.setHandlers(clientOptions)
 		return pahoMqtt.NewClient(clientOptions), nil
 	}
 }
@@ -246,7 +412,7 @@ func DefaultClientCreator() ClientCreator {
 // creating an MQTT client.
 func ClientCreatorWithCertLoader(certCreator pkg.X509KeyPairCreator, certLoader pkg.X509KeyLoader,
 	caCertCreator pkg.X509CaCertCreator, caCertLoader pkg.X509CaCertLoader, pemDecoder pkg.PEMDecoder) ClientCreator {
-	return func(options types.MessageBusConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error) {
+	return func(options types.MessageBusConfig, handlers ConnectionHandlers) (pahoMqtt.Client, error) {
 		clientConfiguration, err := CreateMQTTClientConfiguration(options)
 		if err != nil {
 			return nil, err
@@ -258,30 +424,114 @@ func ClientCreatorWithCertLoader(certCreator pkg.X509KeyPairCreator, certLoader
 			return nil, err
 		}
 
-		clientOptions.OnConnect = handler
+		handlers.setHandlers(clientOptions)
 		return pahoMqtt.NewClient(clientOptions), nil
 	}
 }
 
 // newMessageHandler creates a function which meets the criteria for a MessageHandler and propagates the received
-// messages to the proper channel.
+// messages to the proper channel, according to the overflow policy of the TopicChannel. The chunked messages are
+// reassembled before being delivered. The messages which can't be unmarshalled, reassembled or processed by the
+// processor are dead-lettered with the deadLetterer, which may be nil.
 func newMessageHandler(
 	unmarshaler MessageUnmarshaller,
-	messageChannel chan<- types.MessageEnvelope,
-	errorChannel chan<- error) pahoMqtt.MessageHandler {
+	processor *pkg.EnvelopeProcessor,
+	topic types.TopicChannel,
+	errorChannel chan<- error,
+	deadLetterer *pkg.DeadLetterer) pahoMqtt.MessageHandler {
+	deliverer := pkg.NewDeliverer(topic, errorChannel)
+	reassembler := processor.NewReassembler(topic.Topic)
+	errorForwarder := newErrorForwarder(topic.Topic, errorChannel)
 
 	return func(client pahoMqtt.Client, message pahoMqtt.Message) {
 		var messageEnvelope types.MessageEnvelope
 		payload := message.Payload()
 		err := unmarshaler(payload, &messageEnvelope)
+		if err == nil {
+			messageEnvelope.ReceivedTopic = message.Topic()
+			var complete bool
+			if complete, err = reassembler.Add(&messageEnvelope); err == nil {
+				if !complete {
+					// The message is a chunk of a transfer which isn't complete yet
+					return
+				}
+				err = processor.Incoming(&messageEnvelope)
+			}
+		}
 		if err != nil {
-			errorChannel <- err
+			pkg.RecordDecodeError(topic.Topic)
+			if deadLetterer != nil {
+				// Publishing from the handler would block the router of the MQTT client until the publish completes
+				go func(topic string) {
+					if err := deadLetterer.DeadLetter(topic, payload, "", err); err != nil {
+						errorForwarder.send(err)
+					}
+				}(message.Topic())
+			}
+			errorForwarder.send(err)
 			return
 		}
 
-		messageEnvelope.ReceivedTopic = message.Topic()
+		deliverer.Receive(messageEnvelope, nil)
+	}
+}
+
+// maxQueuedErrors is the number of errors an errorForwarder queues while the subscriber doesn't receive them, the
+// following errors are dropped until the subscriber catches up.
+const maxQueuedErrors = 100
+
+// errorForwarder reports the errors of the messages received for a subscription to its errors channel from a go func
+// of its own, so the message handler doesn't block the router of the MQTT client while the subscriber doesn't receive
+// them. The errors are queued in order, an error which doesn't fit in the queue is dropped, counted and logged.
+type errorForwarder struct {
+	topic      string
+	errors     chan<- error
+	queue      []error
+	forwarding bool
+	mutex      sync.Mutex
+}
+
+func newErrorForwarder(topic string, errorChannel chan<- error) *errorForwarder {
+	return &errorForwarder{topic: topic, errors: errorChannel}
+}
+
+// send queues the error to be reported to the errors channel, which is discarded if nil.
+func (f *errorForwarder) send(err error) {
+	if f.errors == nil {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.queue) >= maxQueuedErrors {
+		pkg.RecordErrorDropped(f.topic)
+		slog.Warn("error of a message received by the MQTT client dropped, the subscriber isn't receiving its errors",
+			"topic", f.topic, "error", err)
+		return
+	}
+
+	f.queue = append(f.queue, err)
+	if !f.forwarding {
+		f.forwarding = true
+		go f.forward()
+	}
+}
+
+// forward sends the queued errors to the errors channel until the queue is empty.
+func (f *errorForwarder) forward() {
+	for {
+		f.mutex.Lock()
+		if len(f.queue) == 0 {
+			f.forwarding = false
+			f.mutex.Unlock()
+			return
+		}
+		err := f.queue[0]
+		f.queue = f.queue[1:]
+		f.mutex.Unlock()
 
-		messageChannel <- messageEnvelope
+		f.errors <- err
 	}
 }
 
@@ -305,7 +555,9 @@ func getTokenError(token pahoMqtt.Token, timeout time.Duration, operation string
 	}
 
 	if token.Error() != nil {
-		return NewOperationErr(operation, token.Error().Error())
+		operationErr := NewOperationErr(operation, token.Error().Error())
+		operationErr.notConnected = errors.Is(token.Error(), pahoMqtt.ErrNotConnected)
+		return operationErr
 	}
 
 	return nil
@@ -16,6 +16,7 @@
 package mqtt
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -30,6 +31,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 
 	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
@@ -179,13 +182,14 @@ func (mc MockMQTTClient) Connect() pahoMqtt.Token {
 	return &mc.connect
 }
 
+// Publish delivers the message to the handlers of all the subscriptions matching the topic, like a broker would.
 func (mc MockMQTTClient) Publish(topic string, _ byte, _ bool, message interface{}) pahoMqtt.Token {
-	handler, ok := mc.subscriptions[topic]
-	if !ok {
-		return &mc.publish
+	for filter, handler := range mc.subscriptions {
+		if handler != nil && pkg.TopicMatches(filter, topic) {
+			go handler(mc, MockMessage{payload: message.([]byte), topic: topic})
+		}
 	}
 
-	go handler(mc, MockMessage{payload: message.([]byte), topic: topic})
 	return &mc.publish
 }
 
@@ -202,8 +206,13 @@ func (mt MockToken) Wait() bool {
 	panic("function not expected to be invoked")
 }
 
+// Done returns a closed channel when the token completes, otherwise a channel which is never closed.
 func (mt MockToken) Done() <-chan struct{} {
-	panic("function not expected to be invoked")
+	done := make(chan struct{})
+	if mt.waitTimeOut {
+		close(done)
+	}
+	return done
 }
 
 func (MockMQTTClient) IsConnected() bool {
@@ -272,7 +281,7 @@ func (MockMessage) Ack() {
 
 // mockClientCreator higher-order function which creates a function that constructs a MockMQTTClient
 func mockClientCreator(connect MockToken, publish MockToken, subscribe MockToken) ClientCreator {
-	return func(config types.MessageBusConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error) {
+	return func(config types.MessageBusConfig, handlers ConnectionHandlers) (pahoMqtt.Client, error) {
 		return MockMQTTClient{
 			connect:       connect,
 			publish:       publish,
@@ -294,6 +303,38 @@ func TestInvalidClientOptions(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestInvalidFormat(t *testing.T) {
+	_, err := NewMQTTClient(types.MessageBusConfig{
+		Broker:   TcpHostInfo,
+		Optional: map[string]string{pkg.Format: "xml"},
+	})
+	require.Error(t, err)
+}
+
+func TestCodecMarshaller(t *testing.T) {
+	expected := types.MessageEnvelope{
+		CorrelationID: "123",
+		Payload:       []byte{0x00, 0xff},
+		Headers:       map[string][]string{"route": {"core-data"}},
+	}
+
+	for _, name := range []string{codec.JSON, codec.CBOR, codec.Raw} {
+		envelopeCodec, err := codec.Get(name)
+		require.NoError(t, err)
+
+		data, err := NewCodecMarshaller(envelopeCodec)(expected)
+		require.NoError(t, err, name)
+
+		var actual types.MessageEnvelope
+		require.NoError(t, CodecUnmarshaller(data, &actual), name)
+		assert.Equal(t, expected, actual, name)
+	}
+
+	_, err := NewCodecMarshaller(codec.Detect(nil))("not an envelope")
+	require.Error(t, err)
+	require.Error(t, CodecUnmarshaller([]byte("{}"), &map[string]string{}))
+}
+
 func TestInvalidTlsOptions(t *testing.T) {
 	options := types.MessageBusConfig{
 		Broker: TlsHostInfo,
@@ -902,6 +943,163 @@ func TestClient_Publish(t *testing.T) {
 	}
 }
 
+func TestGetTokenErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     MockToken
+		retryable bool
+	}{
+		{"Timeout", TimeoutNoErrorMockToken(), true},
+		{"Not connected", MockToken{waitTimeOut: true, err: pahoMqtt.ErrNotConnected}, true},
+		{"Operation error", ErrorMockToken(), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := getTokenError(test.token, time.Second, PublishOperation, "timed out")
+			retryableErr, ok := err.(interface{ Retryable() bool })
+			require.True(t, ok)
+			assert.Equal(t, test.retryable, retryableErr.Retryable())
+		})
+	}
+}
+
+func TestNewMessageHandlerOverflow(t *testing.T) {
+	messages := make(chan types.MessageEnvelope, 1)
+	topic := types.TopicChannel{
+		Topic:        "test/#",
+		Messages:     messages,
+		Backpressure: types.Backpressure{Policy: types.OverflowDropOldest},
+	}
+	handler := newMessageHandler(json.Unmarshal, new(pkg.EnvelopeProcessor), topic, make(chan error), nil)
+
+	for _, id := range []string{"1", "2", "3"} {
+		payload, err := json.Marshal(types.MessageEnvelope{CorrelationID: id})
+		require.NoError(t, err)
+		// A blocked handler would stall the router of the MQTT client, so it must return while the channel is full
+		handler(nil, MockMessage{payload: payload, topic: "test/overflow"})
+	}
+
+	require.Len(t, messages, 1)
+	message := <-messages
+	assert.Equal(t, "3", message.CorrelationID)
+	assert.Equal(t, "test/overflow", message.ReceivedTopic)
+}
+
+func TestNewMessageHandlerUndrainedErrors(t *testing.T) {
+	for _, errs := range []chan error{make(chan error), nil} {
+		handler := newMessageHandler(json.Unmarshal, new(pkg.EnvelopeProcessor), types.TopicChannel{Topic: "test/#"}, errs, nil)
+
+		returned := make(chan struct{})
+		go func() {
+			// A blocked handler would stall the router of the MQTT client, so it must return while nobody receives the
+			// errors
+			handler(nil, MockMessage{payload: []byte("not an envelope"), topic: "test/invalid"})
+			close(returned)
+		}()
+
+		select {
+		case <-returned:
+		case <-time.After(time.Second):
+			require.Fail(t, "the handler is blocked by the errors channel")
+		}
+
+		if errs != nil {
+			// The error isn't dropped, it's reported once the subscriber receives its errors
+			select {
+			case err := <-errs:
+				require.Error(t, err)
+			case <-time.After(time.Second):
+				require.Fail(t, "error not received")
+			}
+		}
+	}
+}
+
+func TestNewMessageHandlerDroppedErrors(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.SetDefault(registry)
+	defer metrics.SetDefault(metrics.DefaultRegistry)
+
+	errs := make(chan error)
+	handler := newMessageHandler(json.Unmarshal, new(pkg.EnvelopeProcessor), types.TopicChannel{Topic: "test/#"}, errs, nil)
+
+	// The errors are queued while nobody receives them, those which don't fit in the queue are dropped and counted
+	sent := maxQueuedErrors + 5
+	for range sent {
+		handler(nil, MockMessage{payload: []byte("not an envelope"), topic: "test/invalid"})
+	}
+
+	received := 0
+	for done := false; !done; {
+		select {
+		case err := <-errs:
+			require.Error(t, err)
+			received++
+		case <-time.After(100 * time.Millisecond):
+			done = true
+		}
+	}
+
+	var dropped float64
+	for _, metric := range registry.Snapshot() {
+		if metric.Name == metrics.ErrorsDropped && metric.Labels[metrics.TopicLabel] == "test/#" {
+			dropped = metric.Value
+		}
+	}
+	assert.GreaterOrEqual(t, received, maxQueuedErrors)
+	assert.Equal(t, sent, received+int(dropped))
+}
+
+func TestNewMessageHandlerChunks(t *testing.T) {
+	processor, err := pkg.NewEnvelopeProcessor(map[string]string{pkg.MaxMessageSize: "512"})
+	require.NoError(t, err)
+	messages := make(chan types.MessageEnvelope, 1)
+	handler := newMessageHandler(json.Unmarshal, processor, types.TopicChannel{Topic: "test/#", Messages: messages}, make(chan error), nil)
+
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: bytes.Repeat([]byte("firmware"), 500)}
+	chunks, err := pkg.EncodeChunks(processor, expected, "test/firmware", func(message types.MessageEnvelope) ([]byte, error) {
+		return json.Marshal(message)
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+
+	for _, chunk := range chunks {
+		require.Empty(t, messages, "the message must only be delivered once reassembled")
+		handler(nil, MockMessage{payload: chunk, topic: "test/firmware"})
+	}
+
+	require.Len(t, messages, 1)
+	message := <-messages
+	assert.Equal(t, expected.Payload, message.Payload)
+	assert.Equal(t, "123", message.CorrelationID)
+	assert.Nil(t, message.Headers)
+}
+
+func TestNewBinaryDataMessageHandler(t *testing.T) {
+	processor, err := pkg.NewEnvelopeProcessor(map[string]string{pkg.Compression: pkg.GzipCompression})
+	require.NoError(t, err)
+	messages := make(chan types.MessageEnvelope, 1)
+	errs := make(chan error, 1)
+	handler := newBinaryDataMessageHandler(processor, types.TopicChannel{Topic: "test/binary", Messages: messages}, errs)
+
+	data := bytes.Repeat([]byte{0x01, 0x02}, 1024)
+	compressed, err := processor.OutgoingBinary(data, "test/binary")
+	require.NoError(t, err)
+	require.NotEqual(t, data, compressed)
+
+	for _, payload := range [][]byte{compressed, data} {
+		handler(nil, MockMessage{payload: payload, topic: "test/binary"})
+		message := <-messages
+		assert.Equal(t, data, message.Payload)
+		assert.Equal(t, "test/binary", message.ReceivedTopic)
+	}
+
+	invalid := append([]byte("\x00EXZ1\x04zstd"), data...)
+	handler(nil, MockMessage{payload: invalid, topic: "test/binary"})
+	require.Error(t, <-errs)
+	require.Empty(t, messages)
+}
+
 func TestClient_Subscribe(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -1066,6 +1264,53 @@ func TestClient_Disconnect(t *testing.T) {
 	require.NoError(t, err, "Disconnect is not expected to return an error if not connected")
 }
 
+func TestClient_ConnectionEvents(t *testing.T) {
+	var handlers ConnectionHandlers
+	creator := mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken())
+	client, err := NewMQTTClientWithCreator(TestMessageBusConfig, json.Marshal, json.Unmarshal,
+		func(config types.MessageBusConfig, connectionHandlers ConnectionHandlers) (pahoMqtt.Client, error) {
+			handlers = connectionHandlers
+			return creator(config, connectionHandlers)
+		})
+	require.NoError(t, err)
+	assert.Equal(t, types.Disconnected, client.State())
+
+	require.NoError(t, client.Connect())
+	assert.True(t, client.IsConnected())
+
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events/#", Messages: make(chan types.MessageEnvelope)}}, errs))
+
+	lostErr := errors.New("connection reset by peer")
+	handlers.OnConnectionLost(client.mqttClient, lostErr)
+	assert.False(t, client.IsConnected())
+	handlers.OnReconnecting(client.mqttClient, nil)
+	assert.Equal(t, types.Reconnecting, client.State())
+	handlers.OnConnect(client.mqttClient)
+	assert.Equal(t, types.Connected, client.State())
+
+	require.NoError(t, client.Disconnect())
+	assert.Equal(t, types.Disconnected, client.State())
+
+	expected := []types.ConnectionEvent{
+		{Type: types.ConnectedEvent},
+		{Type: types.DisconnectedEvent, Err: lostErr},
+		{Type: types.ReconnectingEvent},
+		{Type: types.ReconnectedEvent},
+		{Type: types.SubscriptionRestoredEvent, Topic: "edgex/events/#"},
+		{Type: types.DisconnectedEvent},
+	}
+	for _, expectedEvent := range expected {
+		select {
+		case event := <-client.Events():
+			event.Time = time.Time{}
+			assert.Equal(t, expectedEvent, event)
+		default:
+			require.Fail(t, "event not emitted", "expected %s event", expectedEvent.Type)
+		}
+	}
+}
+
 func TestSubscriptionMessageHandler(t *testing.T) {
 	client, _ := NewMQTTClientWithCreator(
 		TestMessageBusConfig,
@@ -1243,3 +1488,69 @@ func TestCreateClientOptions(t *testing.T) {
 	assert.Equal(t, config.AutoReconnect, options.AutoReconnect)
 	assert.Equal(t, time.Duration(config.ConnectTimeout)*time.Second, options.ConnectTimeout)
 }
+
+func TestClient_SubscribeFunc(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(
+		TestMessageBusConfig,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken()))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	received := make(chan types.MessageEnvelope, 1)
+	handler := func(message types.MessageEnvelope) error {
+		received <- message
+		return nil
+	}
+
+	subscription, err := client.SubscribeFunc("test1", handler, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	err = client.Publish(types.MessageEnvelope{CorrelationID: "123"}, "test1")
+	require.NoError(t, err)
+
+	select {
+	case message := <-received:
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, "test1", message.ReceivedTopic)
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received by the handler")
+	}
+
+	err = subscription.Unsubscribe()
+	require.NoError(t, err)
+	_, exists := client.existingSubscriptions["test1"]
+	assert.False(t, exists)
+}
+
+func TestClient_SubscribeWithHandle(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(
+		TestMessageBusConfig,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken()))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	messages1 := make(chan types.MessageEnvelope, 1)
+	messages2 := make(chan types.MessageEnvelope, 1)
+	subscription1, err := client.SubscribeWithHandle(types.TopicChannel{Topic: "test1", Messages: messages1}, nil)
+	require.NoError(t, err)
+	subscription2, err := client.SubscribeWithHandle(types.TopicChannel{Topic: "test1", Messages: messages2}, nil)
+	require.NoError(t, err)
+
+	err = client.Publish(types.MessageEnvelope{CorrelationID: "123"}, "test1")
+	require.NoError(t, err)
+	assert.Equal(t, "123", (<-messages1).CorrelationID)
+	assert.Equal(t, "123", (<-messages2).CorrelationID)
+
+	require.NoError(t, subscription1.Unsubscribe())
+	_, exists := client.existingSubscriptions["test1"]
+	assert.True(t, exists, "subscription must be kept until the last handle is unsubscribed")
+
+	require.NoError(t, client.Unsubscribe("test1"))
+	<-subscription2.Done()
+	_, exists = client.existingSubscriptions["test1"]
+	assert.False(t, exists)
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// PublishWithContext sends a message to the connected MQTT server and waits for the publish to complete until the
+// context is done.
+func (mc *Client) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	return mc.publish(message, topic, func(token pahoMqtt.Token, timeout time.Duration) error {
+		return getTokenErrorWithContext(ctx, token, timeout, PublishOperation, "Unable to publish message")
+	})
+}
+
+// SubscribeWithContext creates a subscription for the specified topics which is removed once the context is done.
+// The subscriptions on the MQTT server are created until the context is done and shared with the other handles for
+// the topics, which are kept once the context is done.
+func (mc *Client) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if mc.mqttClient == nil {
+		return errors.New("mqtt client not exists")
+	}
+
+	subscribeWithHandle := func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+		subscribe := func(topics []types.TopicChannel, messageErrors chan error) error {
+			return mc.subscribeWithContext(ctx, topics, messageErrors)
+		}
+		return mc.subscriptionManager.Subscribe(topic, messageErrors, subscribe, mc.unsubscribe)
+	}
+
+	return pkg.SubscribeWithContext(ctx, subscribeWithHandle, topics, messageErrors)
+}
+
+// subscribeWithContext creates the subscriptions on the MQTT server like Subscribe, waiting for them until the
+// context is done.
+func (mc *Client) subscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	optionsReader := mc.mqttClient.OptionsReader()
+
+	mc.subscriptionMutex.Lock()
+	defer mc.subscriptionMutex.Unlock()
+
+	for _, topic := range topics {
+		handler := newMessageHandler(mc.unmarshaller, mc.processor, topic, messageErrors, mc.deadLetterer)
+		qos := optionsReader.WillQos()
+
+		token := mc.mqttClient.Subscribe(topic.Topic, qos, handler)
+		err := getTokenErrorWithContext(ctx, token, optionsReader.ConnectTimeout(), SubscribeOperation, "Failed to create subscription")
+		if err != nil {
+			return err
+		}
+
+		mc.existingSubscriptions[topic.Topic] = existingSubscription{
+			topic:   topic.Topic,
+			qos:     qos,
+			handler: handler,
+			errors:  messageErrors,
+		}
+	}
+
+	return nil
+}
+
+// RequestWithContext publishes a request and waits for a response until the context is done
+func (mc *Client) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	publish := func(message types.MessageEnvelope, topic string) error {
+		return mc.PublishWithContext(ctx, message, topic)
+	}
+
+	return mc.requester.Request(ctx, publish, message, requestTopic, responseTopicPrefix)
+}
+
+// Messages returns an iterator over the messages received on the topic. The topic is unsubscribed once the loop
+// exits or ctx is done.
+func (mc *Client) Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error] {
+	return pkg.Messages(ctx, mc.SubscribeWithHandle, topic)
+}
+
+// getTokenErrorWithContext is the same as getTokenError, but also stops waiting for the token once the context is
+// done. The timeout only applies when the context doesn't have a deadline of its own.
+func getTokenErrorWithContext(
+	ctx context.Context,
+	token pahoMqtt.Token,
+	timeout time.Duration,
+	operation string,
+	defaultTimeoutMessage string) error {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	select {
+	case <-token.Done():
+		if token.Error() != nil {
+			operationErr := NewOperationErr(operation, token.Error().Error())
+			operationErr.notConnected = errors.Is(token.Error(), pahoMqtt.ErrNotConnected)
+			return operationErr
+		}
+		return nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return NewTimeoutError(operation, defaultTimeoutMessage)
+		}
+		return fmt.Errorf("'%s' operation cancelled: %w", operation, ctx.Err())
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func TestClient_PublishWithContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelExpired()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		publishToken MockToken
+		expectError  bool
+		errorType    error
+	}{
+		{"Successful publish", context.Background(), SuccessfulMockToken(), false, nil},
+		{"Publish error", context.Background(), ErrorMockToken(), true, OperationErr{}},
+		{"Publish cancelled", cancelled, TimeoutNoErrorMockToken(), true, context.Canceled},
+		{"Publish deadline exceeded", expired, TimeoutNoErrorMockToken(), true, TimeoutErr{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, _ := NewMQTTClientWithCreator(
+				TestMessageBusConfig,
+				json.Marshal,
+				json.Unmarshal,
+				mockClientCreator(SuccessfulMockToken(), test.publishToken, MockToken{}))
+
+			err := client.Connect()
+			require.NoError(t, err)
+
+			err = client.PublishWithContext(test.ctx, types.MessageEnvelope{}, "test-topic")
+			if !test.expectError {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			switch expected := test.errorType.(type) {
+			case TimeoutErr, OperationErr:
+				assert.IsType(t, expected, err)
+			default:
+				assert.ErrorIs(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestClient_PublishNotConnectedRetryable(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(
+		TestMessageBusConfig,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), MockToken{waitTimeOut: true, err: pahoMqtt.ErrNotConnected}, MockToken{}))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	// Both publish methods classify the failure the same way, so the retries and the outbox don't depend on the method
+	publishes := map[string]func() error{
+		"Publish": func() error {
+			return client.Publish(types.MessageEnvelope{}, "test-topic")
+		},
+		"PublishWithContext": func() error {
+			return client.PublishWithContext(context.Background(), types.MessageEnvelope{}, "test-topic")
+		},
+	}
+	for name, publish := range publishes {
+		t.Run(name, func(t *testing.T) {
+			err := publish()
+			require.Error(t, err)
+			retryableErr, ok := err.(interface{ Retryable() bool })
+			require.True(t, ok)
+			assert.True(t, retryableErr.Retryable())
+		})
+	}
+}
+
+func TestClient_PublishWithContextChunks(t *testing.T) {
+	config := types.MessageBusConfig{Broker: TcpsHostInfo, Optional: maps.Clone(OptionalPropertiesNoTls)}
+	config.Optional[pkg.MaxMessageSize] = "512"
+	client, err := NewMQTTClientWithCreator(
+		config,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken()))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/#", Messages: messages}}, make(chan error, 1)))
+	published := make(chan []byte, 100)
+	client.mqttClient.Subscribe("test/+", 0, func(_ pahoMqtt.Client, message pahoMqtt.Message) {
+		published <- message.Payload()
+	})
+
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: bytes.Repeat([]byte("firmware"), 500)}
+	require.NoError(t, client.PublishWithContext(context.Background(), expected, "test/firmware"))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, expected.Payload, message.Payload)
+	case <-time.After(time.Second):
+		require.Fail(t, "chunked message not received")
+	}
+
+	require.Greater(t, len(published), 1)
+	for range len(published) {
+		assert.LessOrEqual(t, len(<-published), 512)
+	}
+}
+
+func TestClient_SubscribeWithContext(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(
+		TestMessageBusConfig,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken()))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	topics := []types.TopicChannel{{Topic: "test1", Messages: make(chan types.MessageEnvelope)}}
+
+	err = client.SubscribeWithContext(ctx, topics, make(chan error, 1))
+	require.NoError(t, err)
+
+	client.subscriptionMutex.Lock()
+	_, exists := client.existingSubscriptions["test1"]
+	client.subscriptionMutex.Unlock()
+	require.True(t, exists)
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		client.subscriptionMutex.Lock()
+		defer client.subscriptionMutex.Unlock()
+		_, exists := client.existingSubscriptions["test1"]
+		return !exists
+	}, time.Second, 10*time.Millisecond)
+
+	err = client.SubscribeWithContext(ctx, topics, make(chan error, 1))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_RequestWithContext(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(
+		TestMessageBusConfig,
+		json.Marshal,
+		json.Unmarshal,
+		mockClientCreator(SuccessfulMockToken(), SuccessfulMockToken(), SuccessfulMockToken()))
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	requests := make(chan types.MessageEnvelope, 1)
+	err = client.Subscribe([]types.TopicChannel{{Topic: "request", Messages: requests}}, make(chan error, 1))
+	require.NoError(t, err)
+
+	go func() {
+		request := <-requests
+		response := types.MessageEnvelope{RequestID: request.RequestID, Payload: []byte("response")}
+		_ = client.Publish(response, strings.Join([]string{"response", request.RequestID}, "/"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := client.RequestWithContext(ctx, types.MessageEnvelope{RequestID: "123"}, "request", "response")
+	require.NoError(t, err)
+	assert.Equal(t, "123", response.RequestID)
+	assert.Equal(t, "response", string(response.Payload))
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelExpired()
+	_, err = client.RequestWithContext(expired, types.MessageEnvelope{RequestID: "456"}, "no-responder", "response")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
@@ -35,6 +35,11 @@ func (te TimeoutErr) Error() string {
 	return fmt.Sprintf("Timeout occured while performing a '%s' operation: %s", te.operation, te.message)
 }
 
+// Retryable reports that the operation may complete once retried.
+func (te TimeoutErr) Retryable() bool {
+	return true
+}
+
 // NewTimeoutError creates a new TimeoutErr.
 func NewTimeoutError(operation string, message string) TimeoutErr {
 	return TimeoutErr{
@@ -47,12 +52,20 @@ func NewTimeoutError(operation string, message string) TimeoutErr {
 type OperationErr struct {
 	operation string
 	message   string
+	// notConnected is set when the operation failed because the client isn't connected to the MQTT server
+	notConnected bool
 }
 
 func (oe OperationErr) Error() string {
 	return fmt.Sprintf("An error occured while performing a '%s' operation: %s", oe.operation, oe.message)
 }
 
+// Retryable reports whether the operation may succeed once retried, which is the case when it failed because the
+// client isn't connected to the MQTT server, i.e. while reconnecting.
+func (oe OperationErr) Retryable() bool {
+	return oe.notConnected
+}
+
 // NewOperationErr creates a new OperationErr
 func NewOperationErr(operation string, message string) OperationErr {
 	return OperationErr{
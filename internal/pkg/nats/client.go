@@ -20,13 +20,19 @@
 package nats
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats/interfaces"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 	"github.com/hashicorp/go-multierror"
 	"github.com/nats-io/nats.go"
@@ -70,17 +76,52 @@ func NewClientWithConnectionFactory(cfg types.MessageBusConfig, connectionFactor
 	switch strings.ToLower(cc.Format) {
 	case "json":
 		m = &jsonMarshaller{opts: cc}
-	default:
+	case "nats", "":
 		m = &natsMarshaller{opts: cc}
+	default:
+		envelopeCodec, err := codec.Get(cc.Format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", pkg.Format, err)
+		}
+		m = &codecMarshaller{opts: cc, codec: envelopeCodec}
+	}
+
+	processor, err := pkg.NewEnvelopeProcessor(withDefaultMaxMessageSize(cfg.Optional))
+	if err != nil {
+		return nil, err
 	}
 
-	return &Client{
+	client := &Client{
 		config:                cc,
 		connect:               connectionFactory,
 		m:                     m,
 		existingSubscriptions: make(map[string]*nats.Subscription),
 		subscriptionMutex:     new(sync.Mutex),
-	}, nil
+		subscriptionManager:   pkg.NewSubscriptionManager(),
+		connectionTracker:     pkg.NewConnectionTracker(),
+		processor:             processor,
+	}
+	client.requester = pkg.NewRequester(client.SubscribeWithHandle)
+	client.deadLetterer = pkg.NewDeadLetterer(cfg.Optional, client.Publish)
+
+	return client, nil
+}
+
+// defaultMaxMessageSize is the default max_payload of the NATS server, from which the messages are split into chunks
+// unless the MaxMessageSize is configured
+const defaultMaxMessageSize = 1024 * 1024
+
+// withDefaultMaxMessageSize returns the Optional properties with the defaultMaxMessageSize unless configured.
+func withDefaultMaxMessageSize(optional map[string]string) map[string]string {
+	if _, exists := optional[pkg.MaxMessageSize]; exists {
+		return optional
+	}
+
+	withDefault := make(map[string]string, len(optional)+1)
+	maps.Copy(withDefault, optional)
+	withDefault[pkg.MaxMessageSize] = strconv.Itoa(defaultMaxMessageSize)
+
+	return withDefault
 }
 
 // Client provides NATS MessageBus implementations per the underlying connection
@@ -91,6 +132,11 @@ type Client struct {
 	config                ClientConfig
 	existingSubscriptions map[string]*nats.Subscription
 	subscriptionMutex     *sync.Mutex
+	subscriptionManager   *pkg.SubscriptionManager
+	requester             *pkg.Requester
+	connectionTracker     *pkg.ConnectionTracker
+	deadLetterer          *pkg.DeadLetterer
+	processor             *pkg.EnvelopeProcessor
 }
 
 // Connect establishes the connections to publish and subscribe hosts
@@ -103,19 +149,68 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("connection function not specified")
 	}
 
-	conn, err := c.connect(c.config)
+	config := c.config
+	config.connectionHandlers = []nats.Option{
+		nats.DisconnectErrHandler(c.onDisconnect),
+		nats.ReconnectHandler(c.onReconnect),
+		nats.ClosedHandler(c.onClosed),
+	}
+
+	conn, err := c.connect(config)
 
 	if err != nil {
 		return err
 	}
 
 	c.connection = conn
+	c.connectionTracker.SetConnected()
 
 	return nil
 }
 
+// onDisconnect records the lost connection, which NATS attempts to re-establish unless reconnecting is disabled
+func (c *Client) onDisconnect(nc *nats.Conn, err error) {
+	c.connectionTracker.SetConnectionLost(err)
+
+	if nc.Opts.AllowReconnect {
+		c.connectionTracker.SetReconnecting()
+	}
+}
+
+// onReconnect records the re-established connection, NATS restores the subscriptions when reconnecting
+func (c *Client) onReconnect(_ *nats.Conn) {
+	c.connectionTracker.SetConnected()
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for topic := range c.existingSubscriptions {
+		c.connectionTracker.SubscriptionRestored(topic, nil)
+	}
+}
+
+// onClosed records the connection closed for good, i.e. once the attempts to reconnect are exhausted
+func (c *Client) onClosed(nc *nats.Conn) {
+	c.connectionTracker.SetConnectionLost(nc.LastError())
+}
+
+// IsConnected reports whether the client is connected to the NATS server
+func (c *Client) IsConnected() bool {
+	return c.connectionTracker.IsConnected()
+}
+
+// State returns the state of the connection to the NATS server
+func (c *Client) State() types.ConnectionState {
+	return c.connectionTracker.State()
+}
+
+// Events returns the channel the changes of the connection to the NATS server are emitted on
+func (c *Client) Events() <-chan types.ConnectionEvent {
+	return c.connectionTracker.Events()
+}
+
 // Publish publishes EdgeX messages to NATS
-func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+func (c *Client) Publish(message types.MessageEnvelope, topic string) (err error) {
 	if c.connection == nil {
 		return fmt.Errorf("cannot publish with disconnected client")
 	}
@@ -124,13 +219,44 @@ func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
 		return fmt.Errorf("cannot publish to empty topic")
 	}
 
-	msg, err := c.m.Marshal(message, topic)
+	span := pkg.StartSpan(tracing.Publish, topic, &message)
+	defer func() { span.End(err) }()
+
+	if err = c.processor.Outgoing(&message, topic); err != nil {
+		return err
+	}
+
+	msgs, err := pkg.EncodeChunksWithSize(c.processor, message, topic, func(message types.MessageEnvelope) (*nats.Msg, error) {
+		return c.m.Marshal(message, topic)
+	}, (*nats.Msg).Size)
 
 	if err != nil {
 		return err
 	}
 
-	return c.connection.PublishMsg(msg)
+	started := time.Now()
+	for _, msg := range msgs {
+		if err = c.connection.PublishMsg(msg); err != nil {
+			break
+		}
+	}
+	pkg.RecordPublish(topic, len(message.Payload), started, err)
+	if isConnectionError(err) {
+		return pkg.NewConnectionErr(err)
+	}
+
+	return err
+}
+
+// isConnectionError reports whether the error is caused by the connection to the NATS server being unavailable, in
+// which case the operation may succeed once retried.
+func isConnectionError(err error) bool {
+	return errors.Is(err, nats.ErrConnectionClosed) ||
+		errors.Is(err, nats.ErrConnectionDraining) ||
+		errors.Is(err, nats.ErrConnectionReconnecting) ||
+		errors.Is(err, nats.ErrReconnectBufExceeded) ||
+		errors.Is(err, nats.ErrNoServers) ||
+		errors.Is(err, nats.ErrTimeout)
 }
 
 // Subscribe establishes NATS subscriptions for the given topics
@@ -144,14 +270,27 @@ func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error
 
 	for _, tc := range topics {
 		s := TopicToSubject(tc.Topic)
+		deliverer := pkg.NewDeliverer(tc, messageErrors)
+		reassembler := c.processor.NewReassembler(tc.Topic)
 
 		subscription, err := c.connection.QueueSubscribe(s, c.config.QueueGroup, func(msg *nats.Msg) {
 			env := types.MessageEnvelope{}
 			err := c.m.Unmarshal(msg, &env)
+			complete := false
+			if err == nil {
+				complete, err = reassembler.Add(&env)
+			}
+			if err == nil && complete {
+				err = c.processor.Incoming(&env)
+			}
 			if err != nil {
+				pkg.RecordDecodeError(tc.Topic)
+				if dlErr := c.deadLetterer.DeadLetter(subjectToTopic(msg.Subject), msg.Data, "", err); dlErr != nil {
+					messageErrors <- dlErr
+				}
 				messageErrors <- err
-			} else {
-				tc.Messages <- env
+			} else if complete {
+				deliverer.Receive(env, nil)
 			}
 
 			// core nats messages without reply do not need to be ack'd
@@ -180,13 +319,43 @@ func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error
 	return nil
 }
 
-// Request publishes a request and waits for a response
+// SubscribeWithHandle establishes a NATS subscription for the given topic and returns its handle. The NATS
+// subscription is shared by all the handles for the topic.
+func (c *Client) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	return c.subscriptionManager.Subscribe(topic, messageErrors, c.Subscribe, c.unsubscribe)
+}
+
+// SubscribeFunc establishes a NATS subscription for the topic which invokes the handler for every received message
+func (c *Client) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	return pkg.SubscribeFunc(c.SubscribeWithHandle, topic, handler, options, c.deadLetterer)
+}
+
+// Request publishes a request and waits for a response. The response topic prefix is subscribed by the first request
+// using it and stays subscribed for the following requests.
 func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
-	return pkg.DoRequest(c.Subscribe, c.Unsubscribe, c.Publish, message, requestTopic, responseTopicPrefix, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.requester.Request(ctx, c.Publish, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestMany publishes a request and collects the responses received within the window, stopping early once
+// maxResponses responses are received. A maxResponses of 0 collects all the responses received within the window.
+func (c *Client) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	return c.requester.RequestMany(ctx, c.Publish, message, requestTopic, responseTopicPrefix, maxResponses)
 }
 
 // Unsubscribe to unsubscribe from the specified topics.
 func (c *Client) Unsubscribe(topics ...string) error {
+	err := c.unsubscribe(topics...)
+	c.subscriptionManager.Remove(topics...)
+	return err
+}
+
+func (c *Client) unsubscribe(topics ...string) error {
 	if c.connection == nil {
 		return fmt.Errorf("cannot unsubscribe with disconnected client")
 	}
@@ -218,6 +387,9 @@ func (c *Client) Unsubscribe(topics ...string) error {
 
 // Disconnect drains open subscriptions before closing
 func (c *Client) Disconnect() error {
+	c.connectionTracker.SetDisconnected()
+	c.subscriptionManager.RemoveAll()
+
 	if c.connection == nil {
 		return nil
 	}
@@ -159,6 +159,26 @@ func TestClient_Publish(t *testing.T) {
 
 		assert.NoError(t, err)
 	})
+
+	t.Run("chunked", func(t *testing.T) {
+		connection := &mocks2.Connection{}
+		sut.connection = connection
+		sut.m = &natsMarshaller{}
+
+		var published []*nats.Msg
+		connection.On("PublishMsg", mock.Anything).Run(func(args mock.Arguments) {
+			published = append(published, args.Get(0).(*nats.Msg))
+		}).Return(nil)
+
+		// The messages exceeding the default max_payload of the NATS server are split
+		err := sut.Publish(types.MessageEnvelope{CorrelationID: "id", Payload: make([]byte, 3*defaultMaxMessageSize)}, "topic")
+
+		require.NoError(t, err)
+		require.Len(t, published, 4)
+		for _, msg := range published {
+			assert.LessOrEqual(t, msg.Size(), defaultMaxMessageSize)
+		}
+	})
 }
 
 func TestClient_Subscribe(t *testing.T) {
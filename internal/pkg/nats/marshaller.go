@@ -20,16 +20,31 @@
 package nats
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/nats-io/nats.go"
 
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
 
+// messageID returns the id the broker deduplicates the message with in ExactlyOnce mode, which is unique per
+// publishing service / correlation ID, and per chunk for the chunked messages.
+func messageID(opts ClientConfig, v types.MessageEnvelope) string {
+	id := fmt.Sprintf("%s-%s", opts.ClientId, v.CorrelationID)
+	if index := v.Headers[pkg.ChunkIndexHeader]; len(index) > 0 {
+		id = fmt.Sprintf("%s-chunk-%s", id, index[0])
+	}
+
+	return id
+}
+
 type jsonMarshaller struct {
 	opts ClientConfig
 }
@@ -41,10 +56,11 @@ func (jm *jsonMarshaller) Marshal(v types.MessageEnvelope, publishTopic string)
 
 	out := nats.NewMsg(subject)
 	out.Data, err = json.Marshal(v)
+	out.Header.Set(formatHeader, codec.JSON)
 
 	if jm.opts.ExactlyOnce {
 		// the broker should only accept a message once per publishing service / correlation ID
-		out.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s-%s", jm.opts.ClientId, v.CorrelationID))
+		out.Header.Set(nats.MsgIdHdr, messageID(jm.opts, v))
 	}
 
 	if err != nil {
@@ -55,22 +71,76 @@ func (jm *jsonMarshaller) Marshal(v types.MessageEnvelope, publishTopic string)
 }
 
 func (jm *jsonMarshaller) Unmarshal(msg *nats.Msg, target *types.MessageEnvelope) error {
-	topic := subjectToTopic(msg.Subject)
+	return decodeMsg(msg, target)
+}
+
+// codecMarshaller encodes the envelopes with a codec of the registry as the data of the NATS messages.
+type codecMarshaller struct {
+	opts  ClientConfig
+	codec codec.Codec
+}
+
+func (cm *codecMarshaller) Marshal(v types.MessageEnvelope, publishTopic string) (*nats.Msg, error) {
+	data, err := cm.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := nats.NewMsg(TopicToSubject(publishTopic))
+	out.Data = data
+	out.Header.Set(formatHeader, cm.codec.Name())
+
+	if cm.opts.ExactlyOnce {
+		// the broker should only accept a message once per publishing service / correlation ID
+		out.Header.Set(nats.MsgIdHdr, messageID(cm.opts, v))
+	}
+
+	return out, nil
+}
+
+func (cm *codecMarshaller) Unmarshal(msg *nats.Msg, target *types.MessageEnvelope) error {
+	return decodeMsg(msg, target)
+}
+
+// decodeMsg decodes the data of the NATS message with the codec named by its formatHeader, or the codec it is detected
+// to be encoded with, so the messages of the clients configured with another format are received too.
+func decodeMsg(msg *nats.Msg, target *types.MessageEnvelope) error {
+	envelopeCodec := codec.Detect(msg.Data)
+	if format := msg.Header.Get(formatHeader); format != "" {
+		if formatCodec, err := codec.Get(format); err == nil {
+			envelopeCodec = formatCodec
+		}
+	}
 
-	if err := json.Unmarshal(msg.Data, target); err != nil {
+	if err := envelopeCodec.Decode(msg.Data, target); err != nil {
 		return err
 	}
-	target.ReceivedTopic = topic
+
+	target.ReceivedTopic = subjectToTopic(msg.Subject)
 	return nil
 }
 
 const (
-	contentTypeHeader   = "Content-Type"
-	correlationIDHeader = "X-Correlation-ID"
-	requestIDHeader     = "RequestId"
-	apiVersionHeader    = "ApiVersion"
-	errorCodeHeader     = "ErrorCode"
-	queryParamsHeader   = "QueryParams"
+	contentTypeHeader = "Content-Type"
+	// contentEncodingHeader is the name of the compression of the data of the NATS message
+	contentEncodingHeader = "Content-Encoding"
+	correlationIDHeader   = "X-Correlation-ID"
+	requestIDHeader       = "RequestId"
+	apiVersionHeader      = "ApiVersion"
+	errorCodeHeader       = "ErrorCode"
+	queryParamsHeader     = "QueryParams"
+	// formatHeader is the name of the codec the envelope is encoded with in the data of the NATS message, which is
+	// set unless the format is nats
+	formatHeader = "X-Edgex-Format"
+	// encryptionKeyIDHeader is the id of the key the data of the NATS message is encrypted with
+	encryptionKeyIDHeader = "X-Edgex-Encryption-Key-Id"
+	// signerHeader is the identity of the publisher which signed the message
+	signerHeader = "X-Edgex-Signer"
+	// signatureHeader is the base64 encoded signature of the message
+	signatureHeader = "X-Edgex-Signature"
+	// headersPrefix prefixes the NATS headers carrying the Headers of the envelope, so they can't collide with the
+	// headers of the other envelope fields
+	headersPrefix = "X-Edgex-Header-"
 )
 
 type natsMarshaller struct {
@@ -87,21 +157,49 @@ func (nm *natsMarshaller) Marshal(v types.MessageEnvelope, publishTopic string)
 	out.Header.Set(requestIDHeader, v.RequestID)
 	out.Header.Set(apiVersionHeader, v.ApiVersion)
 	out.Header.Set(errorCodeHeader, strconv.Itoa(v.ErrorCode))
+	if v.TraceParent != "" {
+		out.Header.Set(types.TraceParentHeader, v.TraceParent)
+		if v.TraceState != "" {
+			out.Header.Set(types.TraceStateHeader, v.TraceState)
+		}
+	}
+	if v.ContentEncoding != "" {
+		out.Header.Set(contentEncodingHeader, v.ContentEncoding)
+	}
+	if v.EncryptionKeyID != "" {
+		out.Header.Set(encryptionKeyIDHeader, v.EncryptionKeyID)
+	}
+	if len(v.Signature) > 0 {
+		out.Header.Set(signerHeader, v.Signer)
+		out.Header.Set(signatureHeader, base64.StdEncoding.EncodeToString(v.Signature))
+	}
 	if len(v.QueryParams) > 0 {
 		for key, value := range v.QueryParams {
 			query := key + ":" + value
 			out.Header.Add(queryParamsHeader, query)
 		}
 	}
+	for key, values := range v.Headers {
+		// The keys can't contain ':' or spaces and the values are trimmed by NATS, so both are escaped
+		headerKey := headersPrefix + url.QueryEscape(key)
+		for _, value := range values {
+			out.Header.Add(headerKey, url.QueryEscape(value))
+		}
+	}
 	if nm.opts.ExactlyOnce {
 		// the broker should only accept a message once per publishing service / correlation ID
-		out.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s-%s", nm.opts.ClientId, v.CorrelationID))
+		out.Header.Set(nats.MsgIdHdr, messageID(nm.opts, v))
 	}
 
 	return out, nil
 }
 
 func (nm *natsMarshaller) Unmarshal(msg *nats.Msg, target *types.MessageEnvelope) error {
+	if msg.Header.Get(formatHeader) != "" {
+		// The message was published by a client configured with another format, which encodes the whole envelope
+		return decodeMsg(msg, target)
+	}
+
 	topic := subjectToTopic(msg.Subject)
 
 	target.ReceivedTopic = topic
@@ -111,6 +209,18 @@ func (nm *natsMarshaller) Unmarshal(msg *nats.Msg, target *types.MessageEnvelope
 	target.ContentType = msg.Header.Get(contentTypeHeader)
 	target.RequestID = msg.Header.Get(requestIDHeader)
 	target.ApiVersion = msg.Header.Get(apiVersionHeader)
+	target.TraceParent = msg.Header.Get(types.TraceParentHeader)
+	target.TraceState = msg.Header.Get(types.TraceStateHeader)
+	target.ContentEncoding = msg.Header.Get(contentEncodingHeader)
+	target.EncryptionKeyID = msg.Header.Get(encryptionKeyIDHeader)
+	target.Signer = msg.Header.Get(signerHeader)
+	target.Signature = nil
+	if signature := msg.Header.Get(signatureHeader); signature != "" {
+		var err error
+		if target.Signature, err = base64.StdEncoding.DecodeString(signature); err != nil {
+			return fmt.Errorf("unable to decode signature: %w", err)
+		}
+	}
 
 	errorCode := msg.Header.Get(errorCodeHeader)
 	if errorCode != "" {
@@ -125,8 +235,33 @@ func (nm *natsMarshaller) Unmarshal(msg *nats.Msg, target *types.MessageEnvelope
 	query := msg.Header.Values(queryParamsHeader)
 	if len(query) > 0 {
 		for _, q := range query {
-			kv := strings.Split(q, ":")
-			target.QueryParams[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			// Only the first ':' separates the key from the value, which may contain ':' too
+			key, value, _ := strings.Cut(q, ":")
+			target.QueryParams[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	target.Headers = nil
+	for headerKey, values := range msg.Header {
+		escapedKey, found := strings.CutPrefix(headerKey, headersPrefix)
+		if !found {
+			continue
+		}
+
+		key, err := url.QueryUnescape(escapedKey)
+		if err != nil {
+			return fmt.Errorf("unable to decode header key '%s': %w", escapedKey, err)
+		}
+
+		if target.Headers == nil {
+			target.Headers = make(map[string][]string)
+		}
+		for _, escapedValue := range values {
+			value, err := url.QueryUnescape(escapedValue)
+			if err != nil {
+				return fmt.Errorf("unable to decode value of header '%s': %w", key, err)
+			}
+			target.Headers[key] = append(target.Headers[key], value)
 		}
 	}
 
@@ -31,7 +31,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats/interfaces"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
 
@@ -97,6 +99,23 @@ func TestNATSMarshaller(t *testing.T) {
 			validWithNoQueryParams.ReceivedTopic = pubTopic
 			validWithQueryParams := validWithNoQueryParams
 			validWithQueryParams.QueryParams = map[string]string{"foo": "bar"}
+			validWithHeaders := validWithNoQueryParams
+			validWithHeaders.QueryParams = map[string]string{"url": "http://localhost:59880"}
+			validWithHeaders.Headers = map[string][]string{
+				"route":        {"core-data:events", " padded "},
+				"Key:With %":   {"multi\nline"},
+				"Content-Type": {"not the envelope content type"},
+			}
+			validWithTraceContext := validWithNoQueryParams
+			validWithTraceContext.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+			validWithTraceContext.TraceState = "vendor=value"
+			validEncrypted := validWithNoQueryParams
+			validEncrypted.EncryptionKeyID = "k1"
+			validCompressed := validWithNoQueryParams
+			validCompressed.ContentEncoding = "zstd"
+			validSigned := validWithQueryParams
+			validSigned.Signer = "core-command"
+			validSigned.Signature = []byte{0x00, 0xff, 0x10}
 
 			tests := []struct {
 				name             string
@@ -105,6 +124,11 @@ func TestNATSMarshaller(t *testing.T) {
 			}{
 				{"valid", validWithQueryParams, false},
 				{"valid - no query parameters", validWithNoQueryParams, true},
+				{"valid - trace context", validWithTraceContext, true},
+				{"valid - headers", validWithHeaders, false},
+				{"valid - encrypted", validEncrypted, true},
+				{"valid - compressed", validCompressed, true},
+				{"valid - signed", validSigned, false},
 			}
 			for _, tt := range tests {
 				t.Run(tt.name, func(t *testing.T) {
@@ -114,6 +138,11 @@ func TestNATSMarshaller(t *testing.T) {
 					assert.NotEmpty(t, marshaled.Header.Get(requestIDHeader))
 					assert.Equal(t, common.ApiVersion, marshaled.Header.Get(apiVersionHeader))
 					assert.Equal(t, "0", marshaled.Header.Get(errorCodeHeader))
+					assert.Equal(t, tt.envelope.TraceParent, marshaled.Header.Get(types.TraceParentHeader))
+					assert.Equal(t, tt.envelope.TraceState, marshaled.Header.Get(types.TraceStateHeader))
+					assert.Equal(t, tt.envelope.ContentEncoding, marshaled.Header.Get(contentEncodingHeader))
+					assert.Equal(t, tt.envelope.EncryptionKeyID, marshaled.Header.Get(encryptionKeyIDHeader))
+					assert.Equal(t, tt.envelope.Signer, marshaled.Header.Get(signerHeader))
 					if tt.emptyQueryParams {
 						assert.Empty(t, marshaled.Header.Get(queryParamsHeader))
 					} else {
@@ -132,6 +161,37 @@ func TestNATSMarshaller(t *testing.T) {
 	}
 }
 
+func TestCodecMarshaller(t *testing.T) {
+	for _, name := range []string{codec.CBOR, codec.Raw} {
+		t.Run(name, func(t *testing.T) {
+			envelopeCodec, err := codec.Get(name)
+			require.NoError(t, err)
+			sut := &codecMarshaller{
+				opts:  ClientConfig{ClientOptions: ClientOptions{ClientId: uuid.NewString(), ExactlyOnce: true}},
+				codec: envelopeCodec,
+			}
+
+			pubTopic := uuid.NewString()
+			expected := sampleMessage(100)
+			expected.Headers = map[string][]string{"route": {"core-data:events"}}
+			expected.QueryParams = map[string]string{"foo": "bar"}
+			expected.ReceivedTopic = pubTopic
+
+			marshaled, err := sut.Marshal(expected, pubTopic)
+			require.NoError(t, err)
+			assert.Equal(t, name, marshaled.Header.Get(formatHeader))
+			assert.Equal(t, fmt.Sprintf("%s-%s", sut.opts.ClientId, expected.CorrelationID), marshaled.Header.Get(nats.MsgIdHdr))
+
+			// The messages must be decoded whatever the format of the receiving client
+			for unmarshallerName, unmarshaller := range marshallerCases {
+				unmarshaled := types.MessageEnvelope{}
+				require.NoError(t, unmarshaller.Unmarshal(marshaled, &unmarshaled), unmarshallerName)
+				assert.Equal(t, expected, unmarshaled, unmarshallerName)
+			}
+		})
+	}
+}
+
 func BenchmarkMarshallers(b *testing.B) {
 	for _, plSize := range []int{100, 1000, 10000, 20000} {
 		for name, sut := range marshallerCases {
@@ -164,3 +224,20 @@ func sampleMessage(plSize int) types.MessageEnvelope {
 		QueryParams:   make(map[string]string),
 	}
 }
+
+func TestMessageID(t *testing.T) {
+	opts := ClientConfig{ClientOptions: ClientOptions{ClientId: "core-data", ExactlyOnce: true}}
+	message := types.MessageEnvelope{CorrelationID: "123"}
+	assert.Equal(t, "core-data-123", messageID(opts, message))
+
+	// The chunks of a message share its correlation id, so they must not be deduplicated by the broker
+	message.Headers = map[string][]string{pkg.ChunkIndexHeader: {"2"}}
+	assert.Equal(t, "core-data-123-chunk-2", messageID(opts, message))
+}
+
+func TestWithDefaultMaxMessageSize(t *testing.T) {
+	assert.Equal(t, map[string]string{pkg.MaxMessageSize: "1048576"}, withDefaultMaxMessageSize(nil))
+
+	optional := map[string]string{pkg.MaxMessageSize: "0"}
+	assert.Equal(t, optional, withDefaultMaxMessageSize(optional))
+}
@@ -38,6 +38,8 @@ import (
 type ClientConfig struct {
 	BrokerURL string
 	ClientOptions
+	// connectionHandlers are the options setting the handlers of the Client for the connection events
+	connectionHandlers []nats.Option
 }
 
 // ConnectionOptions contains the connection configurations for the NATS client.
@@ -145,6 +147,8 @@ func (cc ClientConfig) ConnectOpt() ([]nats.Option, error) {
 	if cc.CredentialsFile != "" {
 		opts = append(opts, nats.UserCredentials(cc.CredentialsFile))
 	}
+
+	opts = append(opts, cc.connectionHandlers...)
 	return opts, nil
 }
 
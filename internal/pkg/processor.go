@@ -0,0 +1,87 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// EnvelopeProcessor applies the payload compression, the payload encryption, the message signing and the chunked
+// transfer configured by the Optional properties to the messages published and received by the clients. The zero
+// EnvelopeProcessor neither compresses, encrypts, signs nor splits, refuses the chunks and removes the signature of the
+// received messages.
+type EnvelopeProcessor struct {
+	compressor *Compressor
+	encryptor  *Encryptor
+	signer     *Signer
+	chunker    *Chunker
+}
+
+// NewEnvelopeProcessor creates an EnvelopeProcessor with the Compressor, the Encryptor, the Signer and the Chunker of
+// the Optional configuration.
+func NewEnvelopeProcessor(optional map[string]string) (*EnvelopeProcessor, error) {
+	compressor, err := NewCompressor(optional)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptor, err := NewEncryptor(optional)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := NewSigner(optional)
+	if err != nil {
+		return nil, err
+	}
+
+	chunker, err := NewChunker(optional)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnvelopeProcessor{compressor: compressor, encryptor: encryptor, signer: signer, chunker: chunker}, nil
+}
+
+// Outgoing compresses, encrypts then signs the message published on the topic, so the payload is compressed before
+// encryption makes it incompressible, and the signature is verified before decrypting.
+func (p *EnvelopeProcessor) Outgoing(message *types.MessageEnvelope, topic string) error {
+	if err := p.compressor.Compress(message, topic); err != nil {
+		return err
+	}
+
+	if err := p.encryptor.Encrypt(message); err != nil {
+		return err
+	}
+
+	return p.signer.Sign(message, topic)
+}
+
+// Incoming verifies, decrypts then decompresses the received message, which must have its ReceivedTopic set.
+func (p *EnvelopeProcessor) Incoming(message *types.MessageEnvelope) error {
+	if err := p.signer.Verify(message); err != nil {
+		return err
+	}
+
+	if err := p.encryptor.Decrypt(message); err != nil {
+		return err
+	}
+
+	return Decompress(message)
+}
+
+// NewReassembler creates the Reassembler of the chunked messages received by the subscription to the topic, which
+// must be added to it before being processed by Incoming.
+func (p *EnvelopeProcessor) NewReassembler(topic string) *Reassembler {
+	return p.chunker.NewReassembler(topic)
+}
+
+// OutgoingBinary compresses the binary data published on the topic, which is neither encrypted nor signed.
+func (p *EnvelopeProcessor) OutgoingBinary(data []byte, topic string) ([]byte, error) {
+	return p.compressor.CompressBinary(data, topic)
+}
+
+// IncomingBinary decompresses the received binary data.
+func (p *EnvelopeProcessor) IncomingBinary(data []byte) ([]byte, error) {
+	return DecompressBinary(data)
+}
@@ -3,6 +3,9 @@
 package redis
 
 import (
+	"strings"
+	"time"
+
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
@@ -17,8 +20,26 @@ func (c Client) PublishBinaryData(data []byte, topic string) error {
 		return pkg.NewInvalidTopicErr("", "Unable to publish to the invalid topic")
 	}
 
-	topic = convertToRedisTopicScheme(topic)
-	return c.redisClient.SendBinaryData(topic, data)
+	data, err := c.processor.OutgoingBinary(data, topic)
+	if err != nil {
+		return err
+	}
+
+	redisTopic := convertToRedisTopicScheme(topic)
+	started := time.Now()
+	if err = c.redisClient.SendBinaryData(redisTopic, data); err != nil && strings.Contains(err.Error(), "EOF") {
+		// Redis may have been restarted and the first attempt will fail with EOF, so need to try again
+		err = c.redisClient.SendBinaryData(redisTopic, data)
+	}
+	pkg.RecordPublish(topic, len(data), started, err)
+
+	c.trackConnection(err)
+
+	if err != nil && isConnectionError(err) {
+		return pkg.NewConnectionErr(err)
+	}
+
+	return err
 }
 
 func (c Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors chan error) error {
@@ -43,6 +64,10 @@ func (c Client) SubscribeBinaryData(topics []types.TopicChannel, messageErrors c
 				}
 
 				message.ReceivedTopic = convertFromRedisTopicScheme(message.ReceivedTopic)
+				if message.Payload, err = c.processor.IncomingBinary(message.Payload); err != nil {
+					messageErrors <- err
+					continue
+				}
 
 				messageChannel <- *message
 			}
@@ -17,10 +17,14 @@
 package redis
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"reflect"
 	"strings"
@@ -28,6 +32,8 @@ import (
 	"time"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
 
@@ -47,12 +53,25 @@ type Client struct {
 	// Used to avoid multiple subscriptions to the same topic
 	existingTopics map[string]bool
 	mapMutex       *sync.Mutex
+
+	subscriptionManager *pkg.SubscriptionManager
+	requester           *pkg.Requester
+	connection          *pkg.ConnectionTracker
+	deadLetterer        *pkg.DeadLetterer
+	processor           *pkg.EnvelopeProcessor
+	codec               codec.Codec
 }
 
-// NewClient creates a new Client based on the provided configuration.
+// NewClient creates a new Client based on the provided configuration. The messages are encoded with the codec named
+// by the Format of the Optional properties, JSON by default.
 func NewClient(messageBusConfig types.MessageBusConfig) (Client, error) {
-	return NewClientWithCreator(messageBusConfig, NewGoRedisClientWrapper, tls.X509KeyPair, tls.LoadX509KeyPair,
-		x509.ParseCertificate, os.ReadFile, pem.Decode)
+	envelopeCodec, err := codec.Get(messageBusConfig.Optional[pkg.Format])
+	if err != nil {
+		return Client{}, fmt.Errorf("invalid %s: %w", pkg.Format, err)
+	}
+
+	return NewClientWithCreator(messageBusConfig, NewGoRedisClientWrapperWithCodec(envelopeCodec), tls.X509KeyPair,
+		tls.LoadX509KeyPair, x509.ParseCertificate, os.ReadFile, pem.Decode)
 }
 
 // NewClientWithCreator creates a new Client based on the provided configuration while allowing more control on the
@@ -79,6 +98,17 @@ func NewClientWithCreator(
 		return Client{}, err
 	}
 
+	processor, err := pkg.NewEnvelopeProcessor(messageBusConfig.Optional)
+	if err != nil {
+		return Client{}, err
+	}
+
+	// The messages are encoded by the Redis client, the codec only sizes them to split them into chunks
+	envelopeCodec, err := codec.Get(messageBusConfig.Optional[pkg.Format])
+	if err != nil {
+		return Client{}, fmt.Errorf("invalid %s: %w", pkg.Format, err)
+	}
+
 	var client RedisClient
 
 	// Create underlying client to use when publishing
@@ -99,21 +129,44 @@ func NewClientWithCreator(
 		}
 	}
 
-	return Client{
-		redisClient:    client,
-		existingTopics: make(map[string]bool),
-		mapMutex:       new(sync.Mutex),
-	}, nil
+	redisClient := Client{
+		redisClient:         client,
+		existingTopics:      make(map[string]bool),
+		mapMutex:            new(sync.Mutex),
+		subscriptionManager: pkg.NewSubscriptionManager(),
+		connection:          pkg.NewConnectionTracker(),
+		processor:           processor,
+		codec:               envelopeCodec,
+	}
+	redisClient.deadLetterer = pkg.NewDeadLetterer(messageBusConfig.Optional, redisClient.Publish)
+	// The method value binds a copy of the client, so the requester is created last for its subscriptions to use the
+	// fully built client, i.e. with the dead letterer
+	redisClient.requester = pkg.NewRequester(redisClient.SubscribeWithHandle)
+
+	return redisClient, nil
 }
 
-// Connect noop as preemptive connections are not needed.
+// Connect checks the connection to the Redis server. The connections are pooled by the underlying client, which
+// establishes them as needed, so an unreachable Redis server doesn't fail Connect: a DisconnectedEvent with the cause
+// is emitted instead, and a ReconnectedEvent once an operation succeeds.
 func (c Client) Connect() error {
-	// No need to connect, connection pooling is handled by the underlying client.
+	if c.redisClient == nil {
+		// Nothing to connect to, the operations return a MissingConfigurationErr
+		return nil
+	}
+
+	if err := c.redisClient.Ping(); err != nil {
+		c.connection.SetConnectFailed(fmt.Errorf("unable to connect to Redis server: %w", err))
+		return nil
+	}
+
+	c.connection.SetConnected()
+
 	return nil
 }
 
 // Publish sends the provided message to appropriate Redis Pub/Sub.
-func (c Client) Publish(message types.MessageEnvelope, topic string) error {
+func (c Client) Publish(message types.MessageEnvelope, topic string) (err error) {
 	if c.redisClient == nil {
 		return pkg.NewMissingConfigurationErr("Broker", "Unable to create a connection for publishing")
 	}
@@ -123,16 +176,46 @@ func (c Client) Publish(message types.MessageEnvelope, topic string) error {
 		return pkg.NewInvalidTopicErr("", "Unable to publish to the invalid topic")
 	}
 
-	topic = convertToRedisTopicScheme(topic)
-	var err error
-	if err = c.redisClient.Send(topic, message); err != nil && strings.Contains(err.Error(), "EOF") {
-		// Redis may have been restarted and the first attempt will fail with EOF, so need to try again
-		err = c.redisClient.Send(topic, message)
+	span := pkg.StartSpan(tracing.Publish, topic, &message)
+	defer func() { span.End(err) }()
+
+	if err = c.processor.Outgoing(&message, topic); err != nil {
+		return err
+	}
+
+	messages, err := pkg.SplitChunks(c.processor, message, topic, c.encodedSize)
+	if err != nil {
+		return err
+	}
+
+	redisTopic := convertToRedisTopicScheme(topic)
+	started := time.Now()
+	for _, message := range messages {
+		if err = c.redisClient.Send(redisTopic, message); err != nil && strings.Contains(err.Error(), "EOF") {
+			// Redis may have been restarted and the first attempt will fail with EOF, so need to try again
+			err = c.redisClient.Send(redisTopic, message)
+		}
+		if err != nil {
+			break
+		}
+	}
+	pkg.RecordPublish(topic, len(message.Payload), started, err)
+
+	c.trackConnection(err)
+
+	if err != nil && isConnectionError(err) {
+		return pkg.NewConnectionErr(err)
 	}
 
 	return err
 }
 
+// encodedSize returns the size of the message encoded by the Redis client.
+func (c Client) encodedSize(message types.MessageEnvelope) (int, error) {
+	encoded, err := c.codec.Encode(message)
+	return len(encoded), err
+}
+
 // Subscribe creates background processes which reads messages from the appropriate Redis Pub/Sub and sends to the
 // provided channels
 func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
@@ -151,8 +234,11 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 		wg.Add(1)
 		go func(topic types.TopicChannel) {
 			topicName := convertToRedisTopicScheme(topic.Topic)
-			messageChannel := topic.Messages
+			deliverer := pkg.NewDeliverer(topic, messageErrors)
+			reassembler := c.processor.NewReassembler(topic.Topic)
 			var previousErr error
+			// restoring is set once the connection was lost, until the next message is received
+			restoring := false
 
 			err := c.redisClient.Subscribe(topicName)
 			wg.Done()
@@ -179,22 +265,61 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 				c.mapMutex.Unlock()
 
 				if err != nil {
+					var undecodable undecodableMessageErr
+					if errors.As(err, &undecodable) {
+						pkg.RecordDecodeError(topic.Topic)
+						// Dead-letter every undecodable message, even though the same error is only reported once
+						dlErr := c.deadLetterer.DeadLetter(convertFromRedisTopicScheme(undecodable.topic), undecodable.payload, "", undecodable.err)
+						if dlErr != nil {
+							messageErrors <- dlErr
+						}
+					}
+
 					// This handles case when getting same repeated error due to Redis connectivity issue
 					// Avoids starving of other threads/processes and recipient spamming the log file.
 					if previousErr != nil && reflect.DeepEqual(err, previousErr) {
 						time.Sleep(1 * time.Millisecond) // Sleep allows other threads to get time
 						continue
 					}
+					if isConnectionError(err) {
+						c.connection.SetConnectionLost(err)
+						restoring = true
+					}
 					messageErrors <- err
 
 					previousErr = err
 					continue
 				}
 
+				// The underlying client subscribes again when it re-establishes the connection, which is only noticed
+				// once the next message is received, so the subscription is reported as restored by the first message
+				// received after the connection was lost.
+				if restoring {
+					c.connection.SetConnected()
+					c.connection.SubscriptionRestored(topic.Topic, nil)
+					restoring = false
+				}
+
 				previousErr = nil
 				message.ReceivedTopic = convertFromRedisTopicScheme(message.ReceivedTopic)
+				complete, err := reassembler.Add(message)
+				if err == nil && !complete {
+					// The message is a chunk of a transfer which isn't complete yet
+					continue
+				}
+				if err == nil {
+					err = c.processor.Incoming(message)
+				}
+				if err != nil {
+					pkg.RecordDecodeError(topic.Topic)
+					if dlErr := c.deadLetterer.DeadLetter(message.ReceivedTopic, message.Payload, message.ContentType, err); dlErr != nil {
+						messageErrors <- dlErr
+					}
+					messageErrors <- err
+					continue
+				}
 
-				messageChannel <- *message
+				deliverer.Receive(*message, nil)
 			}
 		}(topics[i])
 	}
@@ -207,11 +332,42 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 
 }
 
+// SubscribeWithHandle creates a subscription for the specified topic and returns its handle. The subscription on
+// Redis Pub/Sub is shared by all the handles for the topic.
+func (c Client) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	return c.subscriptionManager.Subscribe(topic, messageErrors, c.Subscribe, c.unsubscribe)
+}
+
+// SubscribeFunc creates a subscription for the specified topic which invokes the handler for every received message.
+func (c Client) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	return pkg.SubscribeFunc(c.SubscribeWithHandle, topic, handler, options, c.deadLetterer)
+}
+
+// Request publishes a request and waits for a response. The response topic prefix is subscribed by the first request
+// using it and stays subscribed for the following requests.
 func (c Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
-	return pkg.DoRequest(c.Subscribe, c.Unsubscribe, c.Publish, message, requestTopic, responseTopicPrefix, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.requester.Request(ctx, c.Publish, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestMany publishes a request and collects the responses received within the window, stopping early once
+// maxResponses responses are received. A maxResponses of 0 collects all the responses received within the window.
+func (c Client) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	return c.requester.RequestMany(ctx, c.Publish, message, requestTopic, responseTopicPrefix, maxResponses)
 }
 
 func (c Client) Unsubscribe(topics ...string) error {
+	err := c.unsubscribe(topics...)
+	c.subscriptionManager.Remove(topics...)
+	return err
+}
+
+func (c Client) unsubscribe(topics ...string) error {
 	c.mapMutex.Lock()
 
 	for _, topic := range topics {
@@ -230,6 +386,10 @@ func (c Client) Unsubscribe(topics ...string) error {
 
 // Disconnect closes connections to the Redis server.
 func (c Client) Disconnect() error {
+	c.subscriptionManager.RemoveAll()
+
+	c.connection.SetDisconnected()
+
 	var disconnectErrors []string
 	if c.redisClient != nil {
 		err := c.redisClient.Close()
@@ -245,6 +405,43 @@ func (c Client) Disconnect() error {
 	return nil
 }
 
+// IsConnected reports whether the Redis server was reachable by the latest operation of the client.
+func (c Client) IsConnected() bool {
+	return c.connection.IsConnected()
+}
+
+// State returns the state of the connection to the Redis server. The connection is Connected once Connect succeeds
+// and Disconnected once an operation fails because the server is unreachable, until an operation succeeds again. The
+// underlying client re-establishes the connections on demand, so the state is never Reconnecting.
+func (c Client) State() types.ConnectionState {
+	return c.connection.State()
+}
+
+// Events returns the channel the changes of the connection to the Redis server are emitted on.
+func (c Client) Events() <-chan types.ConnectionEvent {
+	return c.connection.Events()
+}
+
+// trackConnection records the outcome of an operation in the state of the connection. The connection is lost when
+// the operation failed because the server is unreachable and re-established once an operation succeeds.
+func (c Client) trackConnection(err error) {
+	if err == nil {
+		c.connection.SetConnected()
+		return
+	}
+
+	if isConnectionError(err) {
+		c.connection.SetConnectionLost(err)
+	}
+}
+
+// isConnectionError reports whether the error is caused by the connection to the Redis server rather than the
+// operation itself.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.As(err, &netErr)
+}
+
 func (c Client) validateTopics(topics []types.TopicChannel) error {
 	c.mapMutex.Lock()
 	defer c.mapMutex.Unlock()
@@ -27,9 +27,11 @@ package redis
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"sync"
 	"testing"
@@ -73,6 +75,24 @@ func TestNewClient(t *testing.T) {
 			wantErr: false,
 		},
 
+		{
+			name: "Successfully create client with CBOR format",
+			messageBusConfig: types.MessageBusConfig{
+				Broker:   HostInfo,
+				Optional: map[string]string{pkg.Format: "cbor"},
+			},
+			wantErr: false,
+		},
+
+		{
+			name: "Invalid format",
+			messageBusConfig: types.MessageBusConfig{
+				Broker:   HostInfo,
+				Optional: map[string]string{pkg.Format: "xml"},
+			},
+			wantErr: true,
+		},
+
 		{
 			name: "Invalid Redis Server",
 			messageBusConfig: types.MessageBusConfig{
@@ -260,6 +280,135 @@ func TestClient_Connect(t *testing.T) {
 	require.NoError(t, err, "Connect is not expected to return an error")
 }
 
+func TestClient_ConnectionState(t *testing.T) {
+	redisMock := &redisMocks.RedisClient{}
+	redisMock.On("Ping").Return(errors.New("connection refused")).Once()
+	redisMock.On("Ping").Return(nil)
+	redisMock.On("Send", "failing", mock.Anything).Return(&net.OpError{Op: "write", Err: errors.New("broken pipe")})
+	redisMock.On("Send", "invalid", mock.Anything).Return(errors.New("WRONGTYPE"))
+	redisMock.On("Send", "working", mock.Anything).Return(nil)
+	redisMock.On("SendBinaryData", "failing", mock.Anything).Return(&net.OpError{Op: "write", Err: errors.New("broken pipe")})
+	redisMock.On("SendBinaryData", "working", mock.Anything).Return(nil)
+	redisMock.On("Close").Return(nil)
+
+	client, err := NewClientWithCreator(types.MessageBusConfig{Broker: HostInfo},
+		func(string, string, *tls.Config) (RedisClient, error) { return redisMock, nil },
+		mockCertCreator(nil), mockCertLoader(nil), mockCaCertCreator(nil), mockCaCertLoader(nil), mockPemDecoder(&pem.Block{}))
+	require.NoError(t, err)
+
+	require.NoError(t, client.Connect(), "an unreachable Redis server must not fail Connect")
+	assert.Equal(t, types.Disconnected, client.State())
+	connectFailed := <-client.Events()
+	assert.Equal(t, types.DisconnectedEvent, connectFailed.Type)
+	assert.ErrorContains(t, connectFailed.Err, "connection refused")
+
+	require.NoError(t, client.Connect())
+	assert.True(t, client.IsConnected())
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "invalid"))
+	assert.True(t, client.IsConnected(), "an error of the operation doesn't affect the connection")
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "failing"))
+	assert.False(t, client.IsConnected())
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "working"))
+	assert.True(t, client.IsConnected())
+
+	err = client.PublishBinaryData([]byte("data"), "failing")
+	require.ErrorAs(t, err, &pkg.ConnectionErr{}, "the connection errors of the binary data must be retryable")
+	assert.False(t, client.IsConnected())
+
+	require.NoError(t, client.PublishBinaryData([]byte("data"), "working"))
+	assert.True(t, client.IsConnected())
+
+	require.NoError(t, client.Disconnect())
+	assert.Equal(t, types.Disconnected, client.State())
+
+	var eventTypes []types.ConnectionEventType
+	for len(client.Events()) > 0 {
+		eventTypes = append(eventTypes, (<-client.Events()).Type)
+	}
+	assert.Equal(t, []types.ConnectionEventType{
+		types.ReconnectedEvent,
+		types.DisconnectedEvent,
+		types.ReconnectedEvent,
+		types.DisconnectedEvent,
+		types.ReconnectedEvent,
+		types.DisconnectedEvent,
+	}, eventTypes)
+}
+
+func TestClient_DeadLetterTopic(t *testing.T) {
+	undecodable := undecodableMessageErr{topic: "edgex.events", payload: []byte("{"), err: errors.New("unexpected end of JSON input")}
+	deadLetters := make(chan types.MessageEnvelope, 2)
+
+	redisMock := &redisMocks.RedisClient{}
+	redisMock.On("Subscribe", "edgex.events").Return(nil)
+	redisMock.On("Receive", "edgex.events").Return(nil, undecodable).Twice()
+	redisMock.On("Receive", "edgex.events").WaitUntil(time.After(time.Hour)).Return(nil, nil)
+	redisMock.On("Send", "edgex.dead-letters", mock.Anything).Run(func(args mock.Arguments) {
+		deadLetters <- args.Get(1).(types.MessageEnvelope)
+	}).Return(nil)
+
+	client, err := NewClientWithCreator(
+		types.MessageBusConfig{Broker: HostInfo, Optional: map[string]string{pkg.DeadLetterTopic: "edgex/dead-letters"}},
+		func(string, string, *tls.Config) (RedisClient, error) { return redisMock, nil },
+		mockCertCreator(nil), mockCertLoader(nil), mockCaCertCreator(nil), mockCaCertLoader(nil), mockPemDecoder(&pem.Block{}))
+	require.NoError(t, err)
+
+	messageErrors := make(chan error, 2)
+	err = client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, messageErrors)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case message := <-deadLetters:
+			var deadLetter types.DeadLetter
+			require.NoError(t, json.Unmarshal(message.Payload, &deadLetter))
+			assert.Equal(t, "edgex/events", deadLetter.Topic)
+			assert.Equal(t, []byte("{"), deadLetter.Payload)
+			assert.Equal(t, "unexpected end of JSON input", deadLetter.Reason)
+		case <-time.After(time.Second):
+			require.Fail(t, "message not dead-lettered", "every undecodable message must be dead-lettered")
+		}
+	}
+
+	require.ErrorContains(t, <-messageErrors, "unable to unmarshal payload")
+	assert.Empty(t, messageErrors, "the repeated error must only be reported once")
+}
+
+func TestClient_RequestDeadLetterTopic(t *testing.T) {
+	undecodable := undecodableMessageErr{topic: "edgex.response.1", payload: []byte("{"), err: errors.New("unexpected end of JSON input")}
+	deadLetters := make(chan types.MessageEnvelope, 1)
+
+	redisMock := &redisMocks.RedisClient{}
+	redisMock.On("Subscribe", "edgex.response.*").Return(nil)
+	redisMock.On("Receive", "edgex.response.*").Return(nil, undecodable).Once()
+	redisMock.On("Receive", "edgex.response.*").WaitUntil(time.After(time.Hour)).Return(nil, nil)
+	redisMock.On("Send", "edgex.request", mock.Anything).Return(nil)
+	redisMock.On("Send", "edgex.dead-letters", mock.Anything).Run(func(args mock.Arguments) {
+		deadLetters <- args.Get(1).(types.MessageEnvelope)
+	}).Return(nil)
+
+	client, err := NewClientWithCreator(
+		types.MessageBusConfig{Broker: HostInfo, Optional: map[string]string{pkg.DeadLetterTopic: "edgex/dead-letters"}},
+		func(string, string, *tls.Config) (RedisClient, error) { return redisMock, nil },
+		mockCertCreator(nil), mockCertLoader(nil), mockCaCertCreator(nil), mockCaCertLoader(nil), mockPemDecoder(&pem.Block{}))
+	require.NoError(t, err)
+
+	_, err = client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request", "edgex/response", 100*time.Millisecond)
+	require.Error(t, err)
+
+	select {
+	case message := <-deadLetters:
+		var deadLetter types.DeadLetter
+		require.NoError(t, json.Unmarshal(message.Payload, &deadLetter))
+		assert.Equal(t, "edgex/response/1", deadLetter.Topic)
+	case <-time.After(time.Second):
+		require.Fail(t, "undecodable response not dead-lettered")
+	}
+}
+
 func TestClient_Publish(t *testing.T) {
 	ValidMessage := types.MessageEnvelope{
 		CorrelationID: "abc",
@@ -649,6 +798,10 @@ func (r *SubscriptionRedisClientMock) Receive(topic string) (*types.MessageEnvel
 	}
 }
 
+func (r *SubscriptionRedisClientMock) Ping() error {
+	return nil
+}
+
 func (r *SubscriptionRedisClientMock) Close() error {
 	panic("implement me")
 }
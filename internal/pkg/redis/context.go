@@ -0,0 +1,40 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package redis
+
+import (
+	"context"
+	"iter"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// PublishWithContext sends the provided message to appropriate Redis Pub/Sub and waits for the publish to complete
+// until the context is done.
+func (c Client) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	return pkg.RunWithContext(ctx, func() error {
+		return c.Publish(message, topic)
+	})
+}
+
+// SubscribeWithContext creates background processes which reads messages from the appropriate Redis Pub/Sub and
+// sends to the provided channels until the context is done.
+func (c Client) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return pkg.SubscribeWithContext(ctx, c.SubscribeWithHandle, topics, messageErrors)
+}
+
+// RequestWithContext publishes a request and waits for a response until the context is done
+func (c Client) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	publish := func(message types.MessageEnvelope, topic string) error {
+		return c.PublishWithContext(ctx, message, topic)
+	}
+
+	return c.requester.Request(ctx, publish, message, requestTopic, responseTopicPrefix)
+}
+
+// Messages returns an iterator over the messages received on the topic. The topic is unsubscribed once the loop
+// exits or ctx is done.
+func (c Client) Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error] {
+	return pkg.Messages(ctx, c.SubscribeWithHandle, topic)
+}
@@ -19,12 +19,12 @@ package redis
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
 
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/codec"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 
 	goRedis "github.com/go-redis/redis/v7"
@@ -38,29 +38,45 @@ type goRedisWrapper struct {
 	wrappedClient      *goRedis.Client
 	subscriptions      map[string]*goRedis.PubSub
 	subscriptionsMutex *sync.Mutex
+	codec              codec.Codec
 }
 
 // NewGoRedisClientWrapper creates a RedisClient implementation which uses a 'go-redis' Client to achieve the necessary
-// functionality.
+// functionality. The messages are encoded as JSON.
 func NewGoRedisClientWrapper(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
-	options, err := goRedis.ParseURL(redisServerURL)
+	jsonCodec, err := codec.Get(codec.JSON)
 	if err != nil {
 		return nil, err
 	}
 
-	options.Password = password
-	options.TLSConfig = tlsConfig
+	return NewGoRedisClientWrapperWithCodec(jsonCodec)(redisServerURL, password, tlsConfig)
+}
+
+// NewGoRedisClientWrapperWithCodec returns a RedisClientCreator creating the RedisClient implementation which uses a
+// 'go-redis' Client, and encodes the messages with the codec. The received messages are decoded with the codec they
+// were encoded with.
+func NewGoRedisClientWrapperWithCodec(envelopeCodec codec.Codec) RedisClientCreator {
+	return func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
+		options, err := goRedis.ParseURL(redisServerURL)
+		if err != nil {
+			return nil, err
+		}
+
+		options.Password = password
+		options.TLSConfig = tlsConfig
 
-	return &goRedisWrapper{
-		wrappedClient:      goRedis.NewClient(options),
-		subscriptions:      make(map[string]*goRedis.PubSub),
-		subscriptionsMutex: &sync.Mutex{},
-	}, nil
+		return &goRedisWrapper{
+			wrappedClient:      goRedis.NewClient(options),
+			subscriptions:      make(map[string]*goRedis.PubSub),
+			subscriptionsMutex: &sync.Mutex{},
+			codec:              envelopeCodec,
+		}, nil
+	}
 }
 
 // Send sends the provided message to a topic.
 func (g *goRedisWrapper) Send(topic string, message types.MessageEnvelope) error {
-	encoded, err := json.Marshal(message)
+	encoded, err := g.codec.Encode(message)
 	if err != nil {
 		return err
 	}
@@ -108,9 +124,9 @@ func (g *goRedisWrapper) Receive(topic string) (*types.MessageEnvelope, error) {
 
 	message := &types.MessageEnvelope{}
 	payload := []byte(data.Payload)
-	err = json.Unmarshal(payload, message)
+	err = codec.Decode(payload, message)
 	if err != nil {
-		return nil, fmt.Errorf("unable to unmarshal payload: %w", err)
+		return nil, undecodableMessageErr{topic: data.Channel, payload: payload, err: err}
 	}
 
 	message.ReceivedTopic = data.Channel
@@ -118,6 +134,11 @@ func (g *goRedisWrapper) Receive(topic string) (*types.MessageEnvelope, error) {
 	return message, nil
 }
 
+// Ping checks the connection to the Redis server.
+func (g *goRedisWrapper) Ping() error {
+	return g.wrappedClient.Ping().Err()
+}
+
 // Close closes the subscriptions and the underlying 'go-redis' client.
 func (g *goRedisWrapper) Close() error {
 	g.subscriptionsMutex.Lock()
@@ -163,3 +184,19 @@ func (g *goRedisWrapper) getSubscription(topic string) (*goRedis.PubSub, error)
 
 	return subscription, nil
 }
+
+// undecodableMessageErr is returned by Receive for a message whose payload can't be unmarshalled. It keeps the raw
+// payload so the message can be dead-lettered.
+type undecodableMessageErr struct {
+	topic   string
+	payload []byte
+	err     error
+}
+
+func (e undecodableMessageErr) Error() string {
+	return fmt.Sprintf("unable to unmarshal payload: %v", e.err)
+}
+
+func (e undecodableMessageErr) Unwrap() error {
+	return e.err
+}
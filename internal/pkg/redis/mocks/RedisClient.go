@@ -27,6 +27,20 @@ func (_m *RedisClient) Close() error {
 	return r0
 }
 
+// Ping provides a mock function with given fields:
+func (_m *RedisClient) Ping() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Receive provides a mock function with given fields: topic
 func (_m *RedisClient) Receive(topic string) (*types.MessageEnvelope, error) {
 	ret := _m.Called(topic)
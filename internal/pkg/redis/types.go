@@ -52,6 +52,8 @@ type RedisClient interface {
 	Receive(topic string) (*types.MessageEnvelope, error)
 	// Close cleans up any entities which need to be deconstructed.
 	Close() error
+	// Ping checks the connection to the Redis server.
+	Ping() error
 
 	// SendBinaryData sends a binary data to the specified topic.
 	SendBinaryData(topic string, data []byte) error
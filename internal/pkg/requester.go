@@ -0,0 +1,359 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/metrics"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Requester sends requests and waits for their responses over one long-lived wildcard subscription per response topic
+// prefix, rather than subscribing and unsubscribing the response topic of every request. The responses
+// are routed to the waiting requests by the RequestID of their response topic.
+type Requester struct {
+	subscribeWithHandle func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error)
+	subscriptions       map[string]*responseSubscription
+	pending             map[string]*pendingRequest
+	mutex               sync.Mutex
+}
+
+// pendingRequest is a request waiting for its responses. The responses are guarded by the mutex of the Requester.
+type pendingRequest struct {
+	responseTopicPrefix string
+	maxResponses        int
+	responses           []types.MessageEnvelope
+	received            chan struct{}
+	failed              chan error
+}
+
+// responseSubscription is the wildcard subscription of a response topic prefix. It's added to the Requester before
+// subscribing, so the requests using the prefix meanwhile wait for it rather than subscribing again, and ready is closed
+// once subscribed. The subscription is guarded by the mutex of the Requester.
+type responseSubscription struct {
+	subscription types.Subscription
+	ready        chan struct{}
+}
+
+// NewRequester creates a Requester which uses subscribeWithHandle to subscribe to the response topics.
+func NewRequester(subscribeWithHandle func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error)) *Requester {
+	return &Requester{
+		subscribeWithHandle: subscribeWithHandle,
+		subscriptions:       make(map[string]*responseSubscription),
+		pending:             make(map[string]*pendingRequest),
+	}
+}
+
+// Request publishes a request containing a RequestID to the specified topic with the publish function and waits
+// until ctx is done for the response published to <responseTopicPrefix>/<RequestID>. The subscription for the
+// response topic prefix is created by the first request using it and kept for the following ones.
+func (r *Requester) Request(
+	ctx context.Context,
+	publish func(message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	span := StartSpan(tracing.Request, requestTopic, &requestMessage)
+	finish := startRequest(requestTopic)
+	response, err := r.request(ctx, publish, requestMessage, requestTopic, responseTopicPrefix)
+	finish(err)
+	span.End(err)
+
+	return response, err
+}
+
+func (r *Requester) request(
+	ctx context.Context,
+	publish func(message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	if len(strings.TrimSpace(requestMessage.RequestID)) == 0 {
+		requestMessage.RequestID = uuid.NewString()
+	}
+
+	// Format of response topic is <prefix>/<request-id>
+	responseTopic := strings.Join([]string{responseTopicPrefix, requestMessage.RequestID}, "/")
+
+	if err := ctx.Err(); err != nil {
+		return nil, requestContextError(err, requestTopic, responseTopic)
+	}
+
+	request, err := r.send(publish, requestMessage, requestTopic, responseTopicPrefix, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.unregister(requestMessage.RequestID, request)
+
+	select {
+	case <-ctx.Done():
+		return nil, requestContextError(ctx.Err(), requestTopic, responseTopic)
+
+	case err = <-request.failed:
+		return nil, fmt.Errorf("encountered error waiting for response to %s: %v", requestTopic, err)
+
+	case <-request.received:
+		responses := r.responses(request)
+		return &responses[0], nil
+	}
+}
+
+// RequestMany publishes a request like Request, but collects all the responses received until ctx is done or
+// maxResponses responses are received. A maxResponses of 0 or less collects the responses until ctx is done. The
+// responses received are returned without error when the deadline of ctx is exceeded, as the deadline is the window
+// for collecting the responses.
+func (r *Requester) RequestMany(
+	ctx context.Context,
+	publish func(message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string,
+	maxResponses int) (responses []types.MessageEnvelope, err error) {
+	span := StartSpan(tracing.Request, requestTopic, &requestMessage)
+	defer func() { span.End(err) }()
+
+	if len(strings.TrimSpace(requestMessage.RequestID)) == 0 {
+		requestMessage.RequestID = uuid.NewString()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("request to %s cancelled: %w", requestTopic, err)
+	}
+
+	if maxResponses < 0 {
+		maxResponses = 0
+	}
+
+	// The responses are collected until ctx is done, so only the requests in flight are recorded
+	defer trackInFlight(metrics.Default(), metrics.Labels{metrics.TopicLabel: requestTopic})()
+
+	request, err := r.send(publish, requestMessage, requestTopic, responseTopicPrefix, maxResponses)
+	if err != nil {
+		return nil, err
+	}
+	defer r.unregister(requestMessage.RequestID, request)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return r.responses(request), nil
+			}
+			return r.responses(request), fmt.Errorf("request to %s cancelled: %w", requestTopic, ctx.Err())
+
+		case err = <-request.failed:
+			return r.responses(request), fmt.Errorf("encountered error waiting for responses to %s: %v", requestTopic, err)
+
+		case <-request.received:
+			if responses := r.responses(request); maxResponses > 0 && len(responses) >= maxResponses {
+				return responses, nil
+			}
+		}
+	}
+}
+
+// send registers the request and publishes it.
+func (r *Requester) send(
+	publish func(message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string,
+	maxResponses int) (*pendingRequest, error) {
+	// Must register the request first so that it is in place when the request is handled and response published back
+	request, err := r.register(requestMessage.RequestID, responseTopicPrefix, maxResponses)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create response subscription: %w", err)
+	}
+
+	err = publish(requestMessage, requestTopic)
+	if err != nil {
+		r.unregister(requestMessage.RequestID, request)
+		return nil, fmt.Errorf("unable to create publish request to %s: %w", requestTopic, err)
+	}
+
+	return request, nil
+}
+
+// responses returns a copy of the responses received so far for the request.
+func (r *Requester) responses(request *pendingRequest) []types.MessageEnvelope {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]types.MessageEnvelope(nil), request.responses...)
+}
+
+// register adds the request to the correlation table, subscribing to the response topic prefix if needed. The
+// subscription is created without holding the mutex, as it waits for the broker, so the responses of the other requests
+// are still routed meanwhile.
+func (r *Requester) register(requestID string, responseTopicPrefix string, maxResponses int) (*pendingRequest, error) {
+	for {
+		r.mutex.Lock()
+
+		if _, exists := r.pending[requestID]; exists {
+			r.mutex.Unlock()
+			return nil, fmt.Errorf("a request with RequestID %s is already waiting for its response", requestID)
+		}
+
+		subscription, exists := r.subscriptions[responseTopicPrefix]
+		if !exists {
+			subscription = &responseSubscription{ready: make(chan struct{})}
+			r.subscriptions[responseTopicPrefix] = subscription
+			r.mutex.Unlock()
+
+			if err := r.subscribe(responseTopicPrefix, subscription); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		select {
+		case <-subscription.ready:
+		default:
+			// Another request is subscribing to the prefix, the subscription is checked again once it's done
+			r.mutex.Unlock()
+			<-subscription.ready
+			continue
+		}
+
+		// The subscription may have been removed without its receive go func having noticed yet
+		select {
+		case <-subscription.subscription.Done():
+			delete(r.subscriptions, responseTopicPrefix)
+			r.mutex.Unlock()
+			continue
+		default:
+		}
+
+		request := &pendingRequest{
+			responseTopicPrefix: responseTopicPrefix,
+			maxResponses:        maxResponses,
+			received:            make(chan struct{}, 1),
+			failed:              make(chan error, 1),
+		}
+		r.pending[requestID] = request
+		r.mutex.Unlock()
+
+		return request, nil
+	}
+}
+
+// subscribe subscribes to the response topic prefix with a wildcard and starts receiving the responses. The
+// subscription is removed from the Requester if it failed, so the next request subscribes again.
+func (r *Requester) subscribe(responseTopicPrefix string, subscription *responseSubscription) error {
+	defer close(subscription.ready)
+
+	messages := make(chan types.MessageEnvelope)
+	messageErrors := make(chan error)
+	topic := strings.Join([]string{responseTopicPrefix, MultiLevelWildcard}, TopicSeparator)
+	handle, err := r.subscribeWithHandle(types.TopicChannel{Topic: topic, Messages: messages}, messageErrors)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err != nil {
+		delete(r.subscriptions, responseTopicPrefix)
+		return err
+	}
+
+	subscription.subscription = handle
+	go r.receive(responseTopicPrefix, handle, messages, messageErrors)
+
+	return nil
+}
+
+func (r *Requester) unregister(requestID string, request *pendingRequest) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.pending[requestID] == request {
+		delete(r.pending, requestID)
+	}
+}
+
+// receive routes the responses received for the response topic prefix until the subscription is removed.
+func (r *Requester) receive(
+	responseTopicPrefix string,
+	subscription types.Subscription,
+	messages chan types.MessageEnvelope,
+	messageErrors chan error) {
+	for {
+		select {
+		case <-subscription.Done():
+			r.removeSubscription(responseTopicPrefix, subscription)
+			return
+
+		case message := <-messages:
+			r.route(responseTopicPrefix, message)
+
+		case <-messageErrors:
+			// The error can't be correlated with a request, i.e. the response couldn't be decoded, so the
+			// request waits until its context is done.
+		}
+	}
+}
+
+// route delivers the response to the request with the RequestID from the response topic. Responses with a
+// RequestID other than the one of their topic, i.e. published to the response topic of another request, and
+// responses for unknown requests, i.e. which timed out already or received all the responses they wait for, are
+// dropped.
+func (r *Requester) route(responseTopicPrefix string, message types.MessageEnvelope) {
+	requestID := strings.TrimPrefix(message.ReceivedTopic, responseTopicPrefix+TopicSeparator)
+	if message.RequestID != "" && message.RequestID != requestID {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	request, exists := r.pending[requestID]
+	if !exists || request.responseTopicPrefix != responseTopicPrefix {
+		return
+	}
+
+	request.responses = append(request.responses, message)
+	if request.maxResponses > 0 && len(request.responses) >= request.maxResponses {
+		delete(r.pending, requestID)
+	}
+
+	select {
+	case request.received <- struct{}{}:
+	default:
+	}
+}
+
+// removeSubscription forgets the subscription of the response topic prefix once it was removed, i.e. by the
+// Unsubscribe or Disconnect of the message client, and fails the requests waiting for a response on it.
+func (r *Requester) removeSubscription(responseTopicPrefix string, subscription types.Subscription) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// A new subscription may already have been created for the prefix, which the pending requests now rely on
+	if current, exists := r.subscriptions[responseTopicPrefix]; !exists || current.subscription != subscription {
+		return
+	}
+	delete(r.subscriptions, responseTopicPrefix)
+
+	for requestID, request := range r.pending {
+		if request.responseTopicPrefix == responseTopicPrefix {
+			delete(r.pending, requestID)
+			request.failed <- fmt.Errorf("subscription for response topic prefix %s was removed", responseTopicPrefix)
+		}
+	}
+}
+
+// requestContextError converts the error of a done context into the error returned by a request.
+func requestContextError(err error, requestTopic string, responseTopic string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewRequestTimeoutErr(responseTopic)
+	}
+
+	return fmt.Errorf("request to %s cancelled: %w", requestTopic, err)
+}
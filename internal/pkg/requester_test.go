@@ -0,0 +1,302 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// respondingPublish returns a publish function which simulates a service responding to every request on
+// <responseTopicPrefix>/<RequestID> with the request payload, after the optional delay.
+func respondingPublish(broker *fakeBroker, responseTopicPrefix string, delay func(request types.MessageEnvelope) time.Duration) func(types.MessageEnvelope, string) error {
+	return func(request types.MessageEnvelope, topic string) error {
+		go func() {
+			if delay != nil {
+				time.Sleep(delay(request))
+			}
+			response := types.MessageEnvelope{RequestID: request.RequestID, Payload: request.Payload}
+			broker.publish(strings.Join([]string{responseTopicPrefix, request.RequestID}, "/"), response)
+		}()
+		return nil
+	}
+}
+
+func TestRequester(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+	publish := respondingPublish(broker, "response", nil)
+
+	for i := 0; i < 3; i++ {
+		response, err := requester.Request(context.Background(), publish, types.MessageEnvelope{Payload: []byte("ping")}, "request", "response")
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.RequestID)
+		assert.Equal(t, "ping", string(response.Payload))
+	}
+
+	assert.Equal(t, 1, broker.subscribed, "the response topic prefix must be subscribed once")
+	assert.Equal(t, 0, broker.unsubscribed)
+	assert.Contains(t, broker.topics, "response/#")
+}
+
+func TestRequesterConcurrent(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	// Respond in the reverse order of the requests
+	publish := respondingPublish(broker, "response", func(request types.MessageEnvelope) time.Duration {
+		return time.Duration(10-int(request.Payload[0])) * 5 * time.Millisecond
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := requester.Request(context.Background(), publish, types.MessageEnvelope{Payload: []byte{byte(i)}}, "request", "response")
+			if assert.NoError(t, err) {
+				assert.Equal(t, []byte{byte(i)}, response.Payload)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, broker.subscribed)
+	assert.Empty(t, requester.pending)
+}
+
+func TestRequesterRoutesByTopic(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	publish := func(request types.MessageEnvelope, topic string) error {
+		// Response without a RequestID, which is routed by the response topic
+		go broker.publish("response/"+request.RequestID, types.MessageEnvelope{Payload: []byte("pong")})
+		return nil
+	}
+
+	response, err := requester.Request(context.Background(), publish, types.MessageEnvelope{RequestID: uuid.NewString()}, "request", "response")
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(response.Payload))
+}
+
+func TestRequesterRefusesMismatchedRequestID(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	publish := func(request types.MessageEnvelope, topic string) error {
+		go func() {
+			// Response claiming the RequestID of the request but published to the response topic of another one
+			broker.publish("response/other", types.MessageEnvelope{RequestID: request.RequestID, Payload: []byte("forged")})
+			broker.publish("response/"+request.RequestID, types.MessageEnvelope{RequestID: request.RequestID, Payload: []byte("pong")})
+		}()
+		return nil
+	}
+
+	response, err := requester.Request(context.Background(), publish, types.MessageEnvelope{RequestID: uuid.NewString()}, "request", "response")
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(response.Payload))
+}
+
+func TestRequesterErrors(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	noResponse := func(types.MessageEnvelope, string) error { return nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := requester.Request(ctx, noResponse, types.MessageEnvelope{RequestID: "123"}, "request", "response")
+	require.EqualError(t, err, "timed out waiting for response on response/123 topic")
+	assert.Empty(t, requester.pending)
+
+	// A late response for a request which timed out is dropped
+	broker.publish("response/123", types.MessageEnvelope{RequestID: "123"})
+
+	_, err = requester.Request(context.Background(), func(types.MessageEnvelope, string) error {
+		return errors.New("failed")
+	}, types.MessageEnvelope{}, "request", "response")
+	require.ErrorContains(t, err, "unable to create publish request to request")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = requester.Request(cancelled, noResponse, types.MessageEnvelope{}, "request", "response")
+	require.ErrorIs(t, err, context.Canceled)
+
+	broker.subscribeErr = errors.New("subscribe failed")
+	_, err = requester.Request(context.Background(), noResponse, types.MessageEnvelope{}, "request", "other")
+	require.ErrorContains(t, err, "unable to create response subscription")
+}
+
+func TestRequesterSubscriptionRemoved(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	noResponse := func(types.MessageEnvelope, string) error {
+		// Remove the subscription like the Unsubscribe or Disconnect of the message client
+		go broker.manager.Remove("response/#")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := requester.Request(ctx, noResponse, types.MessageEnvelope{}, "request", "response")
+	require.ErrorContains(t, err, "subscription for response topic prefix response was removed")
+
+	// The next request subscribes again
+	response, err := requester.Request(ctx, respondingPublish(broker, "response", nil), types.MessageEnvelope{}, "request", "response")
+	require.NoError(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, 2, broker.subscribed)
+}
+
+func TestRequesterSlowSubscribe(t *testing.T) {
+	broker := newFakeBroker()
+	acknowledge := make(chan struct{})
+	subscribing := make(chan struct{}, 2)
+	requester := NewRequester(func(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+		if topic.Topic == "slow/#" {
+			// Waits for the broker to acknowledge the subscription, i.e. the SUBACK
+			subscribing <- struct{}{}
+			<-acknowledge
+		}
+		return broker.subscribeWithHandle(topic, messageErrors)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	slowResponses := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := requester.Request(ctx, respondingPublish(broker, "slow", nil), types.MessageEnvelope{}, "request", "slow")
+			slowResponses <- err
+		}()
+	}
+	<-subscribing
+
+	// The requests on the other prefixes don't wait for the subscription of the slow prefix
+	fastResponse := make(chan error, 1)
+	go func() {
+		_, err := requester.Request(ctx, respondingPublish(broker, "fast", nil), types.MessageEnvelope{}, "request", "fast")
+		fastResponse <- err
+	}()
+	select {
+	case err := <-fastResponse:
+		assert.NoError(t, err)
+	case <-time.After(100 * time.Millisecond):
+		assert.Fail(t, "the request is blocked by the subscription of another prefix")
+	}
+
+	close(acknowledge)
+	for range 2 {
+		require.NoError(t, <-slowResponses)
+	}
+	assert.Empty(t, subscribing, "the slow prefix must be subscribed once")
+	assert.Equal(t, 2, broker.subscribed)
+}
+
+func TestRequesterRequestMany(t *testing.T) {
+	broker := newFakeBroker()
+	requester := NewRequester(broker.subscribeWithHandle)
+
+	// Simulates three services responding to the request
+	publish := func(request types.MessageEnvelope, topic string) error {
+		for i := 0; i < 3; i++ {
+			go broker.publish("response/"+request.RequestID, types.MessageEnvelope{RequestID: request.RequestID, Payload: []byte{byte(i)}})
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	responses, err := requester.RequestMany(ctx, publish, types.MessageEnvelope{}, "request", "response", 0)
+	require.NoError(t, err, "the window closing is not an error")
+	assert.Len(t, responses, 3)
+	assert.Error(t, ctx.Err(), "all the responses are collected until the window closes")
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	responses, err = requester.RequestMany(ctx, publish, types.MessageEnvelope{}, "request", "response", 2)
+	require.NoError(t, err)
+	assert.Len(t, responses, 2)
+	assert.NoError(t, ctx.Err(), "must return as soon as maxResponses responses are received")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	responses, err = requester.RequestMany(ctx, func(types.MessageEnvelope, string) error { return nil }, types.MessageEnvelope{}, "request", "response", 2)
+	require.NoError(t, err)
+	assert.Empty(t, responses)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = requester.RequestMany(cancelled, publish, types.MessageEnvelope{}, "request", "response", 0)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, 1, broker.subscribed)
+	require.Eventually(t, func() bool {
+		requester.mutex.Lock()
+		defer requester.mutex.Unlock()
+		return len(requester.pending) == 0
+	}, time.Second, time.Millisecond)
+}
+
+// The benchmarks compare subscribing the response topic for every request to sharing the subscription of the
+// response topic prefix with a Requester, for a broker taking 100µs to acknowledge a subscribe or unsubscribe, i.e. the
+// SUBACK from an MQTT broker.
+const benchmarkRoundTrip = 100 * time.Microsecond
+
+func BenchmarkSubscribePerRequest(b *testing.B) {
+	broker := newFakeBroker()
+	broker.roundTrip = benchmarkRoundTrip
+	publish := respondingPublish(broker, "response", nil)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			request := types.MessageEnvelope{RequestID: uuid.NewString()}
+			responseTopic := strings.Join([]string{"response", request.RequestID}, "/")
+			messages := make(chan types.MessageEnvelope, 1)
+			if err := broker.subscribe([]types.TopicChannel{{Topic: responseTopic, Messages: messages}}, nil); err != nil {
+				b.Fatal(err)
+			}
+			if err := publish(request, "request"); err != nil {
+				b.Fatal(err)
+			}
+
+			select {
+			case <-messages:
+			case <-time.After(time.Second):
+				b.Fatal("timed out waiting for the response")
+			}
+			_ = broker.unsubscribe(responseTopic)
+		}
+	})
+}
+
+func BenchmarkRequester(b *testing.B) {
+	broker := newFakeBroker()
+	broker.roundTrip = benchmarkRoundTrip
+	requester := NewRequester(broker.subscribeWithHandle)
+	publish := respondingPublish(broker, "response", nil)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			_, err := requester.Request(ctx, publish, types.MessageEnvelope{}, "request", "response")
+			cancel()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
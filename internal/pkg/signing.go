@@ -0,0 +1,321 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// hmacKeyPEMType is the type of the PEM blocks holding the HMAC-SHA256 shared secrets
+	hmacKeyPEMType = "HMAC KEY"
+	// privateKeyPEMType is the type of the PEM blocks holding the PKCS #8 Ed25519 private keys
+	privateKeyPEMType = "PRIVATE KEY"
+	// publicKeyPEMType is the type of the PEM blocks holding the PKIX Ed25519 public keys
+	publicKeyPEMType = "PUBLIC KEY"
+	// signerPEMHeader is the header of the verification PEM blocks holding the identity of the signer of their key. A
+	// block without it verifies the messages of any signer, i.e. a secret shared by all the services, but doesn't
+	// authenticate their identity.
+	signerPEMHeader = "Signer"
+	// minHMACKeySize is the minimum size of the HMAC-SHA256 secrets, the size of the hash
+	minHMACKeySize = sha256.Size
+)
+
+var (
+	errInvalidSignature = errors.New("invalid signature")
+	errUnsignedMessage  = errors.New("unsigned message refused")
+)
+
+// SigningOptions are the Optional properties configuring the signing of the published messages and the verification
+// of the received ones.
+type SigningOptions struct {
+	// SigningIdentity is the identity of the publisher the published messages are signed as
+	SigningIdentity string
+	// SigningKeyFile is the file holding the PEM encoded key the published messages are signed with
+	SigningKeyFile string
+	// SigningKeyPEMBlock holds the PEM encoded key the published messages are signed with, which takes precedence
+	// over SigningKeyFile
+	SigningKeyPEMBlock string
+	// VerificationKeyFile is the file holding the PEM encoded keys the received messages are verified with
+	VerificationKeyFile string
+	// VerificationKeyPEMBlock holds the PEM encoded keys the received messages are verified with, which takes
+	// precedence over VerificationKeyFile
+	VerificationKeyPEMBlock string
+	// SignatureRequired refuses the received messages which aren't signed
+	SignatureRequired bool
+}
+
+// verificationKey verifies the signature of the data.
+type verificationKey interface {
+	verify(data []byte, signature []byte) bool
+}
+
+type hmacKey []byte
+
+func (k hmacKey) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (k hmacKey) verify(data []byte, signature []byte) bool {
+	return hmac.Equal(k.sign(data), signature)
+}
+
+type ed25519PublicKey ed25519.PublicKey
+
+func (k ed25519PublicKey) verify(data []byte, signature []byte) bool {
+	return ed25519.Verify(ed25519.PublicKey(k), data, signature)
+}
+
+// Signer signs the published messages as the identity of the publisher, with HMAC-SHA256 or Ed25519 depending on
+// its key, and verifies the signature of the received messages with the keys of their signer. The Signer of a
+// received message is only kept once its signature is verified, so consumers can rely on it to authenticate the
+// publisher.
+type Signer struct {
+	identity string
+	sign     func(data []byte) []byte
+	// keys are the verification keys by signer identity, the keys verifying any signer are under the empty identity
+	keys     map[string][]verificationKey
+	required bool
+}
+
+// NewSigner creates a Signer from the SigningOptions of the Optional configuration. The signing key is a PEM block of
+// type "HMAC KEY" holding a secret of at least 32 bytes, or "PRIVATE KEY" holding a PKCS #8 Ed25519 key. The
+// verification keys are PEM blocks of type "HMAC KEY", or "PUBLIC KEY" holding a PKIX Ed25519 key, each one verifying
+// the signer named by its Signer header. Returns nil if neither signing nor verification is configured, which removes
+// the signature from the received messages as it isn't verified.
+func NewSigner(optional map[string]string) (*Signer, error) {
+	options := SigningOptions{}
+	if err := Load(optional, &options); err != nil {
+		return nil, fmt.Errorf("invalid signing configuration: %w", err)
+	}
+
+	signingPEM, err := loadPEM(options.SigningKeyPEMBlock, options.SigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load signing key: %w", err)
+	}
+	verificationPEM, err := loadPEM(options.VerificationKeyPEMBlock, options.VerificationKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load verification keys: %w", err)
+	}
+
+	if len(signingPEM) == 0 && len(verificationPEM) == 0 {
+		if options.SigningIdentity != "" {
+			return nil, NewMissingConfigurationErr(SigningKeyFile, "the signing key must be configured to sign the messages")
+		}
+		if options.SignatureRequired {
+			return nil, NewMissingConfigurationErr(VerificationKeyFile, "the verification keys must be configured to require signatures")
+		}
+		return nil, nil
+	}
+
+	signer := &Signer{required: options.SignatureRequired}
+
+	if len(signingPEM) > 0 {
+		if options.SigningIdentity == "" {
+			return nil, NewMissingConfigurationErr(SigningIdentity, "the identity the messages are signed as must be configured")
+		}
+		signer.identity = options.SigningIdentity
+		if signer.sign, err = parseSigningKey(signingPEM); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(verificationPEM) > 0 {
+		if signer.keys, err = parseVerificationKeys(verificationPEM); err != nil {
+			return nil, err
+		}
+	} else if options.SignatureRequired {
+		return nil, NewMissingConfigurationErr(VerificationKeyFile, "the verification keys must be configured to require signatures")
+	}
+
+	return signer, nil
+}
+
+// loadPEM returns the PEM block if not empty, otherwise the content of the file if any.
+func loadPEM(pemBlock string, file string) ([]byte, error) {
+	if pemBlock != "" || file == "" {
+		return []byte(pemBlock), nil
+	}
+
+	return os.ReadFile(file)
+}
+
+func parseSigningKey(pemData []byte) (func(data []byte) []byte, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("invalid signing key: no PEM block found")
+	}
+
+	switch block.Type {
+	case hmacKeyPEMType:
+		if len(block.Bytes) < minHMACKeySize {
+			return nil, fmt.Errorf("invalid signing key: HMAC secrets must be at least %d bytes", minHMACKeySize)
+		}
+		return hmacKey(block.Bytes).sign, nil
+
+	case privateKeyPEMType:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key: %w", err)
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid signing key: %T keys aren't supported, only Ed25519", key)
+		}
+		return func(data []byte) []byte { return ed25519.Sign(privateKey, data) }, nil
+
+	default:
+		return nil, fmt.Errorf("invalid signing key: unsupported PEM block type '%s'", block.Type)
+	}
+}
+
+func parseVerificationKeys(pemData []byte) (map[string][]verificationKey, error) {
+	keys := make(map[string][]verificationKey)
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+
+		signer := block.Headers[signerPEMHeader]
+		switch block.Type {
+		case hmacKeyPEMType:
+			if len(block.Bytes) < minHMACKeySize {
+				return nil, fmt.Errorf("invalid verification key of signer '%s': HMAC secrets must be at least %d bytes", signer, minHMACKeySize)
+			}
+			keys[signer] = append(keys[signer], hmacKey(block.Bytes))
+
+		case publicKeyPEMType:
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid verification key of signer '%s': %w", signer, err)
+			}
+			publicKey, ok := key.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("invalid verification key of signer '%s': %T keys aren't supported, only Ed25519", signer, key)
+			}
+			keys[signer] = append(keys[signer], ed25519PublicKey(publicKey))
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("invalid verification keys: no PEM block of type '%s' or '%s' found", hmacKeyPEMType, publicKeyPEMType)
+	}
+
+	return keys, nil
+}
+
+// Sign sets the identity of the Signer and the signature of the message published on the topic. The message is
+// unchanged if the Signer is nil or doesn't sign the messages.
+func (s *Signer) Sign(message *types.MessageEnvelope, topic string) error {
+	if s == nil || s.sign == nil {
+		return nil
+	}
+
+	message.Signer = s.identity
+	message.Signature = s.sign(signedData(*message, topic))
+
+	return nil
+}
+
+// Verify verifies the signature of the received message with the keys of its signer, and removes the signature once
+// verified. Returns an error if the signature is invalid, or if the message isn't signed and signatures are required.
+// The Signer of the message is removed when its signature is only verified by a key verifying any signer, as any
+// holder of the key could have signed as this identity. The signature of the messages received by a nil Signer, or
+// one which doesn't verify the messages, is removed along with their Signer as it isn't verified.
+func (s *Signer) Verify(message *types.MessageEnvelope) error {
+	signature := message.Signature
+	message.Signature = nil
+
+	if s == nil || s.keys == nil {
+		message.Signer = ""
+		return nil
+	}
+
+	if len(signature) == 0 {
+		message.Signer = ""
+		if s.required {
+			return fmt.Errorf("message received on topic '%s': %w", message.ReceivedTopic, errUnsignedMessage)
+		}
+		return nil
+	}
+
+	data := signedData(*message, message.ReceivedTopic)
+	if message.Signer != "" {
+		for _, key := range s.keys[message.Signer] {
+			if key.verify(data, signature) {
+				return nil
+			}
+		}
+	}
+
+	signer := message.Signer
+	message.Signer = ""
+
+	for _, key := range s.keys[""] {
+		if key.verify(data, signature) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("message received on topic '%s' from signer '%s': %w", message.ReceivedTopic, signer, errInvalidSignature)
+}
+
+// signedData returns the fields of the message published on the topic which are signed, so the message can't be
+// altered or replayed on another topic without invalidating its signature. The trace context isn't signed as it
+// changes along the way.
+func signedData(message types.MessageEnvelope, topic string) []byte {
+	var data []byte
+	appendField := func(field []byte) {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+
+	for _, field := range []string{topic, message.Signer, message.ApiVersion, message.CorrelationID, message.RequestID,
+		strconv.Itoa(message.ErrorCode), message.ContentType, message.ContentEncoding, message.EncryptionKeyID} {
+		appendField([]byte(field))
+	}
+	appendField(message.Payload)
+
+	queryKeys := make([]string, 0, len(message.QueryParams))
+	for key := range message.QueryParams {
+		queryKeys = append(queryKeys, key)
+	}
+	sort.Strings(queryKeys)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(queryKeys)))
+	for _, key := range queryKeys {
+		appendField([]byte(key))
+		appendField([]byte(message.QueryParams[key]))
+	}
+
+	headerKeys := make([]string, 0, len(message.Headers))
+	for key := range message.Headers {
+		headerKeys = append(headerKeys, key)
+	}
+	sort.Strings(headerKeys)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(headerKeys)))
+	for _, key := range headerKeys {
+		appendField([]byte(key))
+		data = binary.BigEndian.AppendUint32(data, uint32(len(message.Headers[key])))
+		for _, value := range message.Headers[key] {
+			appendField([]byte(value))
+		}
+	}
+
+	return data
+}
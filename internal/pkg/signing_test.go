@@ -0,0 +1,252 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// hmacKeyPEM returns the PEM block of an HMAC secret of the size filled with the fill byte, verifying the signer
+// unless empty.
+func hmacKeyPEM(signer string, size int, fill byte) string {
+	block := &pem.Block{Type: hmacKeyPEMType, Bytes: bytes.Repeat([]byte{fill}, size)}
+	if signer != "" {
+		block.Headers = map[string]string{signerPEMHeader: signer}
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+// ed25519KeyPEMs returns the PEM blocks of a new Ed25519 private key, and of its public key verifying the signer.
+func ed25519KeyPEMs(t *testing.T, signer string) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: privateDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Headers: map[string]string{signerPEMHeader: signer}, Bytes: publicDER}))
+}
+
+func newTestSigner(t *testing.T, optional map[string]string) *Signer {
+	signer, err := NewSigner(optional)
+	require.NoError(t, err)
+	require.NotNil(t, signer)
+	return signer
+}
+
+func testSignedMessage() types.MessageEnvelope {
+	return types.MessageEnvelope{
+		CorrelationID: "123",
+		RequestID:     "456",
+		ContentType:   "application/json",
+		Payload:       []byte(`{"command":"set"}`),
+		QueryParams:   map[string]string{"ds-pushevent": "true", "ds-returnevent": "false"},
+		Headers:       map[string][]string{"route": {"a", "b"}},
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaDER, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	require.NoError(t, err)
+	ecdsaPEM := string(pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: ecdsaDER}))
+
+	privatePEM, publicPEM := ed25519KeyPEMs(t, "core-command")
+	keyFile := filepath.Join(t.TempDir(), "verification.pem")
+	require.NoError(t, os.WriteFile(keyFile, []byte(publicPEM), 0600))
+
+	tests := []struct {
+		name     string
+		optional map[string]string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"not configured", map[string]string{}, true, false},
+		{"HMAC signing", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: hmacKeyPEM("", 32, 1)}, false, false},
+		{"Ed25519 signing", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: privatePEM}, false, false},
+		{"verification only", map[string]string{VerificationKeyFile: keyFile, SignatureRequired: "true"}, false, false},
+		{"missing signing key", map[string]string{SigningIdentity: "core-command"}, false, true},
+		{"missing identity", map[string]string{SigningKeyPEMBlock: hmacKeyPEM("", 32, 1)}, false, true},
+		{"missing verification keys", map[string]string{SignatureRequired: "true"}, false, true},
+		{"signing without verification keys required", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: privatePEM, SignatureRequired: "true"}, false, true},
+		{"missing verification key file", map[string]string{VerificationKeyFile: filepath.Join(t.TempDir(), "missing.pem")}, false, true},
+		{"short HMAC secret", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: hmacKeyPEM("", 16, 1)}, false, true},
+		{"short HMAC verification secret", map[string]string{VerificationKeyPEMBlock: hmacKeyPEM("core-command", 16, 1)}, false, true},
+		{"unsupported private key", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: ecdsaPEM}, false, true},
+		{"unsupported PEM type", map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: publicPEM}, false, true},
+		{"no verification key", map[string]string{VerificationKeyPEMBlock: ecdsaPEM}, false, true},
+		{"invalid required", map[string]string{VerificationKeyPEMBlock: publicPEM, SignatureRequired: "maybe"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(tt.optional)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, signer == nil)
+		})
+	}
+}
+
+func TestSignerSignVerify(t *testing.T) {
+	privatePEM, publicPEM := ed25519KeyPEMs(t, "core-command")
+	otherPrivatePEM, _ := ed25519KeyPEMs(t, "core-command")
+
+	tests := []struct {
+		name         string
+		signingKey   string
+		verification string
+		// expectedSigner is the signer exposed once verified, which is only authenticated by the keys of its identity
+		expectedSigner string
+	}{
+		{"HMAC", hmacKeyPEM("", 32, 1), hmacKeyPEM("core-command", 32, 1), "core-command"},
+		{"HMAC shared by any signer", hmacKeyPEM("", 32, 1), hmacKeyPEM("", 32, 1), ""},
+		{"Ed25519", privatePEM, publicPEM, "core-command"},
+		{"rotated keys", privatePEM, hmacKeyPEM("core-command", 32, 1) + publicPEM, "core-command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := newTestSigner(t, map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: tt.signingKey})
+			verifier := newTestSigner(t, map[string]string{VerificationKeyPEMBlock: tt.verification})
+
+			signed := testSignedMessage()
+			require.NoError(t, signer.Sign(&signed, "edgex/core/command/request"))
+			assert.Equal(t, "core-command", signed.Signer)
+			assert.NotEmpty(t, signed.Signature)
+
+			received := signed
+			received.ReceivedTopic = "edgex/core/command/request"
+			received.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+			received.QueryParams = map[string]string{"ds-returnevent": "false", "ds-pushevent": "true"}
+			require.NoError(t, verifier.Verify(&received))
+			assert.Equal(t, tt.expectedSigner, received.Signer, "only the authenticated signer must be exposed")
+			assert.Nil(t, received.Signature)
+
+			tamper := map[string]func(message *types.MessageEnvelope){
+				"topic":        func(message *types.MessageEnvelope) { message.ReceivedTopic = "edgex/core/command/other" },
+				"payload":      func(message *types.MessageEnvelope) { message.Payload = []byte(`{"command":"get"}`) },
+				"signer":       func(message *types.MessageEnvelope) { message.Signer = "device-virtual" },
+				"query params": func(message *types.MessageEnvelope) { message.QueryParams["ds-pushevent"] = "false" },
+				"headers":      func(message *types.MessageEnvelope) { message.Headers = map[string][]string{"route": {"b", "a"}} },
+				"error code":   func(message *types.MessageEnvelope) { message.ErrorCode = 1 },
+			}
+			for field, tamperWith := range tamper {
+				tampered := signed
+				tampered.ReceivedTopic = "edgex/core/command/request"
+				tampered.QueryParams = map[string]string{"ds-pushevent": "true", "ds-returnevent": "false"}
+				tamperWith(&tampered)
+				require.ErrorIs(t, verifier.Verify(&tampered), errInvalidSignature, field)
+				assert.Empty(t, tampered.Signer, "the signer of an invalid signature must not be exposed")
+			}
+
+			forged := testSignedMessage()
+			require.NoError(t, newTestSigner(t, map[string]string{SigningIdentity: "core-command", SigningKeyPEMBlock: otherPrivatePEM}).Sign(&forged, "edgex/core/command/request"))
+			forged.ReceivedTopic = "edgex/core/command/request"
+			require.ErrorIs(t, verifier.Verify(&forged), errInvalidSignature)
+		})
+	}
+}
+
+func TestSignerUnknownSigner(t *testing.T) {
+	_, publicPEM := ed25519KeyPEMs(t, "core-command")
+	verifier := newTestSigner(t, map[string]string{VerificationKeyPEMBlock: publicPEM})
+
+	message := testSignedMessage()
+	require.NoError(t, newTestSigner(t, map[string]string{SigningIdentity: "device-virtual", SigningKeyPEMBlock: hmacKeyPEM("", 32, 1)}).Sign(&message, "test"))
+	message.ReceivedTopic = "test"
+	require.ErrorIs(t, verifier.Verify(&message), errInvalidSignature)
+}
+
+func TestSignerUnsigned(t *testing.T) {
+	_, publicPEM := ed25519KeyPEMs(t, "core-command")
+	verifier := newTestSigner(t, map[string]string{VerificationKeyPEMBlock: publicPEM})
+	requiring := newTestSigner(t, map[string]string{VerificationKeyPEMBlock: publicPEM, SignatureRequired: "true"})
+
+	message := testSignedMessage()
+	message.Signer = "core-command"
+	require.NoError(t, verifier.Verify(&message), "unsigned messages must be accepted unless signatures are required")
+	assert.Empty(t, message.Signer, "an unsigned message must not claim a signer")
+
+	message.Signer = "core-command"
+	require.ErrorIs(t, requiring.Verify(&message), errUnsignedMessage)
+	assert.Empty(t, message.Signer)
+}
+
+func TestSignerNil(t *testing.T) {
+	var signer *Signer
+
+	message := testSignedMessage()
+	require.NoError(t, signer.Sign(&message, "test"))
+	assert.Equal(t, testSignedMessage(), message)
+
+	message.Signer = "core-command"
+	message.Signature = []byte{1, 2, 3}
+	require.NoError(t, signer.Verify(&message))
+	assert.Empty(t, message.Signer, "the signer must not be exposed when not verified")
+	assert.Nil(t, message.Signature)
+
+	verifyOnly := newTestSigner(t, map[string]string{VerificationKeyPEMBlock: hmacKeyPEM("", 32, 1)})
+	require.NoError(t, verifyOnly.Sign(&message, "test"))
+	assert.Nil(t, message.Signature, "a Signer without signing key must not sign")
+}
+
+func TestEnvelopeProcessor(t *testing.T) {
+	optional := map[string]string{
+		EncryptionKeyId:         "k1",
+		EncryptionKeyPEMBlock:   encryptionKeyPEM("", 32, 1),
+		SigningIdentity:         "core-command",
+		SigningKeyPEMBlock:      hmacKeyPEM("", 32, 2),
+		VerificationKeyPEMBlock: hmacKeyPEM("core-command", 32, 2),
+		SignatureRequired:       "true",
+	}
+	processor, err := NewEnvelopeProcessor(optional)
+	require.NoError(t, err)
+
+	message := testSignedMessage()
+	require.NoError(t, processor.Outgoing(&message, "test"))
+	assert.Equal(t, "k1", message.EncryptionKeyID)
+	assert.Equal(t, "core-command", message.Signer)
+
+	// The encrypted payload is signed, so the key id can't be altered either
+	tampered := message
+	tampered.ReceivedTopic = "test"
+	tampered.EncryptionKeyID = "k2"
+	require.ErrorIs(t, processor.Incoming(&tampered), errInvalidSignature)
+
+	message.ReceivedTopic = "test"
+	require.NoError(t, processor.Incoming(&message))
+	assert.Equal(t, testSignedMessage().Payload, message.Payload)
+	assert.Equal(t, "core-command", message.Signer)
+
+	_, err = NewEnvelopeProcessor(map[string]string{SigningIdentity: "core-command"})
+	require.Error(t, err)
+	_, err = NewEnvelopeProcessor(map[string]string{EncryptionKeyId: "k1"})
+	require.Error(t, err)
+
+	var zero EnvelopeProcessor
+	message = testSignedMessage()
+	require.NoError(t, zero.Outgoing(&message, "test"))
+	require.NoError(t, zero.Incoming(&message))
+	assert.Equal(t, testSignedMessage(), message)
+}
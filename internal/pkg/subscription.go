@@ -0,0 +1,418 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// SubscriptionManager shares one subscription on the broker between all the Subscription handles for a topic.
+// The subscription on the broker is created with the first handle for the topic and removed with the last one.
+type SubscriptionManager struct {
+	topics map[string]*sharedSubscription
+	// unsubscribing are the topics being unsubscribed from the broker, which are subscribed again once it's done
+	unsubscribing map[string]*sharedSubscription
+	mutex         sync.Mutex
+}
+
+// sharedSubscription is the subscription on the broker for a topic, which fans out the received messages and errors
+// to the handles of the topic.
+type sharedSubscription struct {
+	topic       string
+	messages    chan types.MessageEnvelope
+	errors      chan error
+	unsubscribe func(topics ...string) error
+	handles     map[*subscriptionHandle]struct{}
+	mutex       sync.Mutex
+	done        chan struct{}
+	// unsubscribed is closed once the subscription on the broker is removed, until then the messages and errors the
+	// message client still sends are discarded rather than leaving it blocked
+	unsubscribed     chan struct{}
+	unsubscribedOnce sync.Once
+}
+
+// subscriptionHandle implements types.Subscription. The messages and errors of the topic are queued for each handle,
+// up to the buffer size of its TopicChannel, so a handle whose subscriber is slow doesn't hold up the other handles of
+// the topic until its queue is full. The overflow policy of the TopicChannel then applies to the queue as well.
+type subscriptionHandle struct {
+	manager   *SubscriptionManager
+	shared    *sharedSubscription
+	deliverer *Deliverer
+	errors    chan<- error
+	queue     []delivery
+	capacity  int
+	mutex     sync.Mutex
+	// notify signals the run goroutine that a delivery was queued, room an enqueue waiting that one was taken
+	notify   chan struct{}
+	room     chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+	received atomic.Uint64
+	errCount atomic.Uint64
+}
+
+// delivery is a message or an error queued for a handle.
+type delivery struct {
+	message types.MessageEnvelope
+	err     error
+}
+
+// NewSubscriptionManager creates an empty SubscriptionManager.
+func NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{
+		topics:        make(map[string]*sharedSubscription),
+		unsubscribing: make(map[string]*sharedSubscription),
+	}
+}
+
+// Subscribe creates a Subscription handle which delivers the messages of the topic to the TopicChannel, according to
+// its overflow policy, and the errors to messageErrors. The subscribe function is used to create the subscription on
+// the broker if none exists for the topic yet, and the unsubscribe function to remove it once the last handle is
+// unsubscribed. A topic being unsubscribed from the broker is only subscribed again once the unsubscribe is done.
+func (m *SubscriptionManager) Subscribe(
+	topic types.TopicChannel,
+	messageErrors chan error,
+	subscribe func(topics []types.TopicChannel, messageErrors chan error) error,
+	unsubscribe func(topics ...string) error) (types.Subscription, error) {
+	m.mutex.Lock()
+	for {
+		pending, exists := m.unsubscribing[topic.Topic]
+		if !exists {
+			break
+		}
+		m.mutex.Unlock()
+		<-pending.unsubscribed
+		m.mutex.Lock()
+	}
+	defer m.mutex.Unlock()
+
+	shared, exists := m.topics[topic.Topic]
+	if !exists {
+		shared = &sharedSubscription{
+			topic:        topic.Topic,
+			messages:     make(chan types.MessageEnvelope),
+			errors:       make(chan error),
+			unsubscribe:  unsubscribe,
+			handles:      make(map[*subscriptionHandle]struct{}),
+			done:         make(chan struct{}),
+			unsubscribed: make(chan struct{}),
+		}
+
+		err := subscribe([]types.TopicChannel{{Topic: topic.Topic, Messages: shared.messages}}, shared.errors)
+		if err != nil {
+			return nil, err
+		}
+
+		m.topics[topic.Topic] = shared
+		go shared.run()
+	}
+
+	handle := &subscriptionHandle{
+		manager:   m,
+		shared:    shared,
+		deliverer: NewDeliverer(topic, messageErrors),
+		errors:    messageErrors,
+		capacity:  max(cap(topic.Messages), 1),
+		notify:    make(chan struct{}, 1),
+		room:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	shared.mutex.Lock()
+	shared.handles[handle] = struct{}{}
+	shared.mutex.Unlock()
+	go handle.run()
+
+	return handle, nil
+}
+
+// Remove closes the handles of the specified topics. It must be called when the topics are unsubscribed from the
+// broker by other means than the handles, i.e. the Unsubscribe of the message client.
+func (m *SubscriptionManager) Remove(topics ...string) {
+	m.mutex.Lock()
+	var removed []*sharedSubscription
+	for _, topic := range topics {
+		if shared, exists := m.topics[topic]; exists {
+			removed = append(removed, shared)
+			delete(m.topics, topic)
+		}
+	}
+	m.mutex.Unlock()
+
+	// The topics are already unsubscribed from the broker
+	for _, shared := range removed {
+		shared.close()
+		shared.confirmUnsubscribed()
+	}
+}
+
+// RemoveAll closes the handles of all the topics.
+func (m *SubscriptionManager) RemoveAll() {
+	m.mutex.Lock()
+	topics := make([]string, 0, len(m.topics))
+	for topic := range m.topics {
+		topics = append(topics, topic)
+	}
+	m.mutex.Unlock()
+
+	m.Remove(topics...)
+}
+
+// unsubscribe removes the handle and the subscription on the broker if it was the last handle for the topic. The
+// manager isn't locked while the broker is unsubscribed, so a slow broker only holds up the subscribe to the topic.
+func (m *SubscriptionManager) unsubscribe(handle *subscriptionHandle) error {
+	m.mutex.Lock()
+	shared := handle.shared
+	shared.mutex.Lock()
+	delete(shared.handles, handle)
+	last := len(shared.handles) == 0
+	shared.mutex.Unlock()
+	handle.close()
+
+	if !last || m.topics[shared.topic] != shared {
+		m.mutex.Unlock()
+		return nil
+	}
+
+	delete(m.topics, shared.topic)
+	m.unsubscribing[shared.topic] = shared
+	m.mutex.Unlock()
+
+	shared.close()
+	err := shared.unsubscribe(shared.topic)
+
+	m.mutex.Lock()
+	delete(m.unsubscribing, shared.topic)
+	m.mutex.Unlock()
+	shared.confirmUnsubscribed()
+
+	return err
+}
+
+// run fans out the messages and errors to the handles until closed, then discards them until the subscription on the
+// broker is removed.
+func (s *sharedSubscription) run() {
+	for {
+		select {
+		case <-s.done:
+			s.drain()
+			return
+		case message := <-s.messages:
+			for _, handle := range s.snapshot() {
+				handle.enqueue(delivery{message: message})
+			}
+		case err := <-s.errors:
+			for _, handle := range s.snapshot() {
+				handle.enqueue(delivery{err: err})
+			}
+		}
+	}
+}
+
+// drain discards the messages and errors received until the subscription on the broker is removed, so the message
+// client isn't left blocked sending a message received right before the unsubscribe.
+func (s *sharedSubscription) drain() {
+	for {
+		select {
+		case <-s.messages:
+		case <-s.errors:
+		case <-s.unsubscribed:
+			// Discard what was sent while the unsubscribe completed
+			for {
+				select {
+				case <-s.messages:
+				case <-s.errors:
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *sharedSubscription) confirmUnsubscribed() {
+	s.unsubscribedOnce.Do(func() { close(s.unsubscribed) })
+}
+
+func (s *sharedSubscription) snapshot() []*subscriptionHandle {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	handles := make([]*subscriptionHandle, 0, len(s.handles))
+	for handle := range s.handles {
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// close closes all the handles and stops the fan out.
+func (s *sharedSubscription) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	for handle := range s.handles {
+		handle.close()
+	}
+	s.handles = make(map[*subscriptionHandle]struct{})
+	close(s.done)
+}
+
+// enqueue queues the message or error for the handle. A message received while the queue is full is dropped or waits
+// for room according to the overflow policy, while an error always waits for room rather than being lost.
+func (h *subscriptionHandle) enqueue(d delivery) {
+	h.mutex.Lock()
+	for len(h.queue) >= h.capacity {
+		if d.err == nil && h.deliverer.topic.Backpressure.Policy != types.OverflowBlock {
+			h.overflow(d.message)
+			return
+		}
+
+		h.mutex.Unlock()
+		select {
+		case <-h.room:
+		case <-h.done:
+			return
+		}
+		h.mutex.Lock()
+	}
+	h.queue = append(h.queue, d)
+	h.mutex.Unlock()
+
+	signal(h.notify)
+}
+
+// overflow drops the oldest message of the full queue to make room for the message with the OverflowDropOldest policy,
+// otherwise the message itself. The handle must be locked, it is unlocked on return.
+func (h *subscriptionHandle) overflow(message types.MessageEnvelope) {
+	if h.deliverer.topic.Backpressure.Policy == types.OverflowDropOldest {
+		for i, queued := range h.queue {
+			if queued.err != nil {
+				continue
+			}
+
+			h.queue = append(h.queue[:i], h.queue[i+1:]...)
+			h.queue = append(h.queue, delivery{message: message})
+			h.mutex.Unlock()
+			h.deliverer.drop(queued.message)
+			signal(h.notify)
+			return
+		}
+	}
+
+	h.mutex.Unlock()
+	h.deliverer.drop(message)
+}
+
+// dequeue takes the oldest delivery from the queue, if any.
+func (h *subscriptionHandle) dequeue() (delivery, bool) {
+	h.mutex.Lock()
+	if len(h.queue) == 0 {
+		h.mutex.Unlock()
+		return delivery{}, false
+	}
+	d := h.queue[0]
+	h.queue[0] = delivery{}
+	h.queue = h.queue[1:]
+	h.mutex.Unlock()
+
+	signal(h.room)
+	return d, true
+}
+
+// run delivers the queued messages and errors in the order they were received, until the handle is closed.
+func (h *subscriptionHandle) run() {
+	for {
+		d, queued := h.dequeue()
+		if !queued {
+			select {
+			case <-h.notify:
+				continue
+			case <-h.done:
+				return
+			}
+		}
+
+		select {
+		case <-h.done:
+			return
+		default:
+		}
+
+		if d.err != nil {
+			h.deliverError(d.err)
+			continue
+		}
+		h.deliver(d.message)
+	}
+}
+
+// signal wakes up the goroutine waiting on the channel, if any, without blocking.
+func signal(channel chan struct{}) {
+	select {
+	case channel <- struct{}{}:
+	default:
+	}
+}
+
+func (h *subscriptionHandle) deliver(message types.MessageEnvelope) {
+	if h.deliverer.Deliver(message, h.done) {
+		h.received.Add(1)
+	}
+}
+
+func (h *subscriptionHandle) deliverError(err error) {
+	if h.errors == nil {
+		return
+	}
+
+	select {
+	case h.errors <- err:
+		h.errCount.Add(1)
+	case <-h.done:
+	}
+}
+
+func (h *subscriptionHandle) close() {
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// Topic returns the topic of the subscription
+func (h *subscriptionHandle) Topic() string {
+	return h.shared.topic
+}
+
+// Unsubscribe removes the subscription
+func (h *subscriptionHandle) Unsubscribe() error {
+	select {
+	case <-h.done:
+		return nil
+	default:
+	}
+
+	return h.manager.unsubscribe(h)
+}
+
+// Stats returns the statistics of the subscription
+func (h *subscriptionHandle) Stats() types.SubscriptionStats {
+	return types.SubscriptionStats{
+		Received: h.received.Load(),
+		Errors:   h.errCount.Load(),
+		Dropped:  h.deliverer.Dropped(),
+	}
+}
+
+// Done returns a channel which is closed once the subscription is removed
+func (h *subscriptionHandle) Done() <-chan struct{} {
+	return h.done
+}
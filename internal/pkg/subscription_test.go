@@ -0,0 +1,278 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func receive(t *testing.T, messages chan types.MessageEnvelope) types.MessageEnvelope {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+	return types.MessageEnvelope{}
+}
+
+func TestSubscriptionManagerSharedTopic(t *testing.T) {
+	broker := newFakeBroker()
+
+	messages1 := make(chan types.MessageEnvelope, 1)
+	messages2 := make(chan types.MessageEnvelope, 1)
+	errors1 := make(chan error, 1)
+
+	subscription1, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: messages1}, errors1)
+	require.NoError(t, err)
+	subscription2, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: messages2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, broker.subscribed, "the broker subscription must be shared")
+
+	broker.publish("test", types.MessageEnvelope{CorrelationID: "1"})
+	assert.Equal(t, "1", receive(t, messages1).CorrelationID)
+	assert.Equal(t, "1", receive(t, messages2).CorrelationID)
+
+	broker.publishError("test", errors.New("receive failed"))
+	assert.EqualError(t, <-errors1, "receive failed")
+
+	assert.Equal(t, types.SubscriptionStats{Received: 1, Errors: 1}, subscription1.Stats())
+	assert.Equal(t, types.SubscriptionStats{Received: 1, Errors: 0}, subscription2.Stats())
+
+	require.NoError(t, subscription1.Unsubscribe())
+	<-subscription1.Done()
+	assert.Equal(t, 0, broker.unsubscribed, "the broker subscription must be kept for the remaining handle")
+
+	broker.publish("test", types.MessageEnvelope{CorrelationID: "2"})
+	assert.Equal(t, "2", receive(t, messages2).CorrelationID)
+
+	require.NoError(t, subscription1.Unsubscribe(), "unsubscribing twice must have no effect")
+	assert.Equal(t, 0, broker.unsubscribed)
+
+	require.NoError(t, subscription2.Unsubscribe())
+	<-subscription2.Done()
+	assert.Equal(t, 1, broker.unsubscribed)
+
+	// A new handle re-creates the broker subscription
+	subscription3, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: messages1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, broker.subscribed)
+	require.NoError(t, subscription3.Unsubscribe())
+}
+
+func TestSubscriptionManagerRemove(t *testing.T) {
+	broker := newFakeBroker()
+
+	subscription1, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test1", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+	subscription2, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test2", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+
+	broker.manager.Remove("test1")
+	<-subscription1.Done()
+	select {
+	case <-subscription2.Done():
+		require.Fail(t, "subscription for another topic must not be removed")
+	default:
+	}
+
+	broker.manager.RemoveAll()
+	<-subscription2.Done()
+
+	// Remove doesn't unsubscribe from the broker, this is done by the message client calling it
+	assert.Equal(t, 0, broker.unsubscribed)
+	require.NoError(t, subscription1.Unsubscribe())
+	assert.Equal(t, 0, broker.unsubscribed)
+}
+
+func TestSubscriptionManagerSubscribeError(t *testing.T) {
+	broker := newFakeBroker()
+	broker.subscribeErr = errors.New("subscribe failed")
+
+	_, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test"}, nil)
+	require.EqualError(t, err, "subscribe failed")
+	assert.Empty(t, broker.manager.topics)
+}
+
+func TestSubscriptionManagerOverflow(t *testing.T) {
+	broker := newFakeBroker()
+
+	slow := make(chan types.MessageEnvelope, 1)
+	fast := make(chan types.MessageEnvelope, 3)
+
+	slowSubscription, err := broker.subscribeWithHandle(types.TopicChannel{
+		Topic:        "test",
+		Messages:     slow,
+		Backpressure: types.Backpressure{Policy: types.OverflowDropNewest},
+	}, nil)
+	require.NoError(t, err)
+	fastSubscription, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: fast}, nil)
+	require.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		broker.publish("test", types.MessageEnvelope{CorrelationID: id})
+	}
+
+	assert.Equal(t, "1", receive(t, fast).CorrelationID)
+	assert.Equal(t, "2", receive(t, fast).CorrelationID)
+	assert.Equal(t, "3", receive(t, fast).CorrelationID, "the slow subscription must not stall the others")
+
+	// The slow subscriber must not free its channel before its handle has delivered or dropped all the messages
+	require.Eventually(t, func() bool {
+		stats := slowSubscription.Stats()
+		return stats.Received+stats.Dropped == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, types.SubscriptionStats{Received: 1, Dropped: 2}, slowSubscription.Stats())
+	assert.Equal(t, types.SubscriptionStats{Received: 3}, fastSubscription.Stats())
+	assert.Equal(t, "1", receive(t, slow).CorrelationID)
+}
+
+func TestSubscriptionManagerBlockedHandle(t *testing.T) {
+	broker := newFakeBroker()
+
+	blocked := make(chan types.MessageEnvelope)
+	fast := make(chan types.MessageEnvelope, 4)
+
+	blockedSubscription, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: blocked}, nil)
+	require.NoError(t, err)
+	_, err = broker.subscribeWithHandle(types.TopicChannel{Topic: "test", Messages: fast}, nil)
+	require.NoError(t, err)
+
+	published := make(chan struct{})
+	go func() {
+		for _, id := range []string{"1", "2", "3", "4"} {
+			broker.publish("test", types.MessageEnvelope{CorrelationID: id})
+		}
+		close(published)
+	}()
+
+	// The handle waiting for its subscriber with the Block policy doesn't hold up the others until its queue is full
+	for _, id := range []string{"1", "2"} {
+		assert.Equal(t, id, receive(t, fast).CorrelationID)
+	}
+
+	// It then holds up the broker rather than queuing the messages without limit: the first message waits for the
+	// subscriber, the second one is queued and the third one waits for room in the queue
+	select {
+	case <-published:
+		require.Fail(t, "the messages for the blocked handle must not be queued without limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		assert.Equal(t, id, receive(t, blocked).CorrelationID)
+	}
+	<-published
+	assert.Equal(t, "3", receive(t, fast).CorrelationID)
+	assert.Equal(t, "4", receive(t, fast).CorrelationID)
+	require.NoError(t, blockedSubscription.Unsubscribe())
+}
+
+func TestSubscriptionManagerQueueOverflow(t *testing.T) {
+	tests := []struct {
+		policy   types.OverflowPolicy
+		expected string
+	}{
+		{types.OverflowDropNewest, "1"},
+		{types.OverflowDropOldest, "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			broker := newFakeBroker()
+
+			messages := make(chan types.MessageEnvelope, 1)
+			messageErrors := make(chan error)
+			overflowed := make(chan types.MessageEnvelope, 3)
+			subscription, err := broker.subscribeWithHandle(types.TopicChannel{
+				Topic:    "test",
+				Messages: messages,
+				Backpressure: types.Backpressure{
+					Policy:     tt.policy,
+					OnOverflow: func(dropped types.MessageEnvelope) { overflowed <- dropped },
+				},
+			}, messageErrors)
+			require.NoError(t, err)
+
+			// The handle is held up by its subscriber not receiving the error, so the messages stay in its queue
+			broker.publishError("test", errors.New("receive failed"))
+			require.Eventually(t, func() bool {
+				queue := subscription.(*subscriptionHandle)
+				queue.mutex.Lock()
+				defer queue.mutex.Unlock()
+				return len(queue.queue) == 0
+			}, time.Second, time.Millisecond)
+			for _, id := range []string{"1", "2", "3"} {
+				broker.publish("test", types.MessageEnvelope{CorrelationID: id})
+			}
+
+			// The fan out may still be queuing the last message once it's published
+			require.Eventually(t, func() bool {
+				return subscription.Stats().Dropped == 2
+			}, time.Second, time.Millisecond, "the queue of the handle must be bounded")
+			assert.Len(t, overflowed, 2)
+
+			assert.EqualError(t, <-messageErrors, "receive failed")
+			assert.Equal(t, tt.expected, receive(t, messages).CorrelationID)
+			require.NoError(t, subscription.Unsubscribe())
+		})
+	}
+}
+
+func TestSubscriptionManagerUnsubscribeInProgress(t *testing.T) {
+	broker := newFakeBroker()
+
+	removed, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "removed", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+	kept1, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "kept", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+	_, err = broker.subscribeWithHandle(types.TopicChannel{Topic: "kept", Messages: make(chan types.MessageEnvelope)}, nil)
+	require.NoError(t, err)
+
+	broker.mutex.Lock()
+	backendTopic := broker.topics["removed"]
+	broker.roundTrip = 300 * time.Millisecond
+	broker.mutex.Unlock()
+
+	unsubscribed := make(chan error, 1)
+	go func() { unsubscribed <- removed.Unsubscribe() }()
+	<-removed.Done()
+
+	// The other topics aren't held up by the broker unsubscribing the topic
+	started := time.Now()
+	require.NoError(t, kept1.Unsubscribe())
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+
+	// The message client isn't left blocked by a message it received right before the unsubscribe
+	sent := make(chan struct{})
+	go func() {
+		backendTopic.Messages <- types.MessageEnvelope{CorrelationID: "late"}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		require.Fail(t, "the message client is blocked by the removed subscription")
+	}
+
+	// The topic is subscribed again once the broker unsubscribed it
+	messages := make(chan types.MessageEnvelope, 1)
+	resubscribed, err := broker.subscribeWithHandle(types.TopicChannel{Topic: "removed", Messages: messages}, nil)
+	require.NoError(t, err)
+	require.NoError(t, <-unsubscribed)
+
+	broker.mutex.Lock()
+	assert.Equal(t, 1, broker.unsubscribed)
+	assert.Equal(t, 3, broker.subscribed)
+	broker.mutex.Unlock()
+
+	broker.publish("removed", types.MessageEnvelope{CorrelationID: "1"})
+	assert.Equal(t, "1", receive(t, messages).CorrelationID)
+	require.NoError(t, resubscribed.Unsubscribe())
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"strings"
+)
+
+const (
+	TopicSeparator      = "/"
+	SingleLevelWildcard = "+"
+	MultiLevelWildcard  = "#"
+)
+
+// TopicMatches reports whether the topic matches the MQTT style topic filter. A "+" level in the filter matches
+// exactly one level of the topic and a trailing "#" level matches any number of levels, including the parent level.
+// Topics starting with "$" are only matched by filters which start with the same level.
+func TopicMatches(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, TopicSeparator)
+	topicLevels := strings.Split(topic, TopicSeparator)
+
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == SingleLevelWildcard || filterLevels[0] == MultiLevelWildcard) {
+		return false
+	}
+
+	for i, level := range filterLevels {
+		if level == MultiLevelWildcard {
+			return i == len(filterLevels)-1
+		}
+
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		if level != SingleLevelWildcard && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+// TopicParams returns the levels of the topic matched by the "+" wildcards of the filter, in order. The levels
+// matched by a trailing "#" wildcard are the last param, joined by "/".
+func TopicParams(filter string, topic string) []string {
+	filterLevels := strings.Split(filter, TopicSeparator)
+	topicLevels := strings.Split(topic, TopicSeparator)
+
+	var params []string
+	for i, level := range filterLevels {
+		if level == MultiLevelWildcard {
+			if i < len(topicLevels) {
+				params = append(params, strings.Join(topicLevels[i:], TopicSeparator))
+			}
+			break
+		}
+
+		if level == SingleLevelWildcard && i < len(topicLevels) {
+			params = append(params, topicLevels[i])
+		}
+	}
+
+	return params
+}
+
+// ValidateTopic returns an InvalidTopicErr if the topic is empty or contains a wildcard, i.e. it can't be published to.
+func ValidateTopic(topic string) error {
+	if topic == "" {
+		return NewInvalidTopicErr(topic, "topic must not be empty")
+	}
+
+	if strings.ContainsAny(topic, SingleLevelWildcard+MultiLevelWildcard) {
+		return NewInvalidTopicErr(topic, "wildcards are only allowed in subscription topics")
+	}
+
+	return nil
+}
+
+// ValidateTopicFilter returns an InvalidTopicErr if the topic filter is empty or uses the wildcards in a way MQTT
+// doesn't allow, i.e. a wildcard which isn't a whole level or a "#" which isn't the last level.
+func ValidateTopicFilter(filter string) error {
+	if filter == "" {
+		return NewInvalidTopicErr(filter, "topic must not be empty")
+	}
+
+	levels := strings.Split(filter, TopicSeparator)
+	for i, level := range levels {
+		if level == SingleLevelWildcard || (level == MultiLevelWildcard && i == len(levels)-1) {
+			continue
+		}
+
+		if strings.ContainsAny(level, SingleLevelWildcard+MultiLevelWildcard) {
+			return NewInvalidTopicErr(filter, "wildcards must occupy a whole level and '#' must be the last level")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter   string
+		topic    string
+		expected bool
+	}{
+		{"edgex/events", "edgex/events", true},
+		{"edgex/events", "edgex/events/core", false},
+		{"edgex/events", "edgex", false},
+		{"edgex/+", "edgex/events", true},
+		{"edgex/+", "edgex/events/core", false},
+		{"edgex/+", "edgex", false},
+		{"edgex/+", "edgex/", true},
+		{"edgex/+/core", "edgex/events/core", true},
+		{"edgex/+/core", "edgex/events/device", false},
+		{"+/+", "edgex/events", true},
+		{"+", "/edgex", false},
+		{"edgex/#", "edgex", true},
+		{"edgex/#", "edgex/events", true},
+		{"edgex/#", "edgex/events/core/device", true},
+		{"edgex/#", "edgexfoundry/events", false},
+		{"edgex/+/#", "edgex/events", true},
+		{"#", "edgex/events", true},
+		{"#", "/", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter+" "+test.topic, func(t *testing.T) {
+			assert.Equal(t, test.expected, TopicMatches(test.filter, test.topic))
+		})
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	assert.NoError(t, ValidateTopic("edgex/events"))
+	assert.Error(t, ValidateTopic(""))
+	assert.Error(t, ValidateTopic("edgex/+"))
+	assert.Error(t, ValidateTopic("edgex/#"))
+}
+
+func TestValidateTopicFilter(t *testing.T) {
+	assert.NoError(t, ValidateTopicFilter("edgex/events"))
+	assert.NoError(t, ValidateTopicFilter("edgex/+/core"))
+	assert.NoError(t, ValidateTopicFilter("edgex/#"))
+	assert.NoError(t, ValidateTopicFilter("#"))
+	assert.Error(t, ValidateTopicFilter(""))
+	assert.Error(t, ValidateTopicFilter("edgex/#/core"))
+	assert.Error(t, ValidateTopicFilter("edgex/events#"))
+	assert.Error(t, ValidateTopicFilter("edgex/ev+ents"))
+}
+
+func TestTopicParams(t *testing.T) {
+	assert.Equal(t, []string{"device", "command"}, TopicParams("edgex/request/+/+/get", "edgex/request/device/command/get"))
+	assert.Equal(t, []string{"device", "command/get"}, TopicParams("edgex/request/+/#", "edgex/request/device/command/get"))
+	assert.Equal(t, []string{"device"}, TopicParams("edgex/request/+/#", "edgex/request/device"))
+	assert.Empty(t, TopicParams("edgex/request", "edgex/request"))
+}
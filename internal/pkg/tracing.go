@@ -0,0 +1,20 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// StartSpan starts the span of the operation on the topic with the default Tracer, as a child of the trace context
+// of the message which is replaced with the one of the span.
+func StartSpan(operation tracing.Operation, topic string, message *types.MessageEnvelope) tracing.Span {
+	span := tracing.Default().Start(operation, topic, message.TraceContext())
+
+	traceContext := span.TraceContext()
+	message.TraceParent = traceContext.TraceParent
+	message.TraceState = traceContext.TraceState
+
+	return span
+}
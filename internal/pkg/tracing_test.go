@@ -0,0 +1,70 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/tracing"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// fakeTracer starts spans with the same trace context, recording the operation and the error of the last span.
+type fakeTracer struct {
+	traceContext types.TraceContext
+	operation    tracing.Operation
+	parent       types.TraceContext
+	err          error
+}
+
+func (f *fakeTracer) Start(operation tracing.Operation, _ string, parent types.TraceContext) tracing.Span {
+	f.operation = operation
+	f.parent = parent
+	return f
+}
+
+func (f *fakeTracer) TraceContext() types.TraceContext { return f.traceContext }
+
+func (f *fakeTracer) End(err error) { f.err = err }
+
+func TestStartSpan(t *testing.T) {
+	message := types.MessageEnvelope{TraceParent: testTraceParent, TraceState: "vendor=value"}
+	span := StartSpan(tracing.Publish, "edgex/events", &message)
+	span.End(nil)
+	assert.Equal(t, testTraceParent, message.TraceParent, "the trace context must be propagated without Tracer")
+	assert.Equal(t, "vendor=value", message.TraceState)
+
+	tracer := &fakeTracer{traceContext: types.TraceContext{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01"}}
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	StartSpan(tracing.Publish, "edgex/events", &message)
+	assert.Equal(t, types.TraceContext{TraceParent: testTraceParent, TraceState: "vendor=value"}, tracer.parent)
+	assert.Equal(t, tracer.traceContext, message.TraceContext())
+}
+
+func TestRequesterTracing(t *testing.T) {
+	tracer := &fakeTracer{traceContext: types.TraceContext{TraceParent: testTraceParent}}
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	var published types.MessageEnvelope
+	publish := func(message types.MessageEnvelope, topic string) error {
+		published = message
+		return errors.New("publish failed")
+	}
+	requester := NewRequester(newFakeBroker().subscribeWithHandle)
+
+	_, err := requester.Request(context.Background(), publish, types.MessageEnvelope{}, "edgex/requests", "edgex/responses")
+	require.Error(t, err)
+	assert.Equal(t, tracing.Request, tracer.operation)
+	assert.Equal(t, testTraceParent, published.TraceParent, "the request must carry the trace context of its span")
+	assert.Equal(t, err, tracer.err)
+}
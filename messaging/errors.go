@@ -0,0 +1,39 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"fmt"
+)
+
+// DecodeError is returned when the payload of a received message can't be decoded into the expected type.
+type DecodeError struct {
+	// Topic is the topic the message was received on
+	Topic string
+	// ContentType is the content type of the payload
+	ContentType string
+	// Err is the error returned by the codec
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode '%s' payload received on topic '%s': %v", e.ContentType, e.Topic, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseError is returned when the response to a request indicates an error, i.e. its ErrorCode is not 0.
+type ResponseError struct {
+	// RequestID is the id of the request the response is for
+	RequestID string
+	// ErrorCode is the ErrorCode of the response
+	ErrorCode int
+	// Message is the error message from the payload of the response
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("request %s failed with error code %d: %s", e.RequestID, e.ErrorCode, e.Message)
+}
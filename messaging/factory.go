@@ -24,44 +24,52 @@ import (
 	"strings"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/mqtt"
-	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats"
-	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats/jetstream"
 	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/redis"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 )
 
-const (
-	// MQTT messaging implementation
-	MQTT = "mqtt"
-
-	// Redis Pub/Sub messaging implementation
-	Redis = "redis"
-
-	// NatsCore implementation
-	NatsCore = "nats-core"
-
-	// NatsJetStream implementation
-	NatsJetStream = "nats-jetstream"
-)
+// The MQTT and Redis message clients are registered by default, the no_messagebus flag excludes them
+func init() {
+	Register(MQTT, WithBrokerInfo(func(config types.MessageBusConfig) (MessageClient, error) {
+		return mqtt.NewMQTTClient(config)
+	}))
+	Register(Redis, WithBrokerInfo(func(config types.MessageBusConfig) (MessageClient, error) {
+		return redis.NewClient(config)
+	}))
+}
 
 // NewMessageClient is a factory function to instantiate different message client depending on
-// the "Type" from the configuration
+// the "Type" from the configuration. The Type must have been registered with Register, which is done by default for
+// the MQTT, Redis and NATS Types, and by importing messaging/memory for the Memory Type. The client is wrapped with
+// WithRetry when a retry policy is set in the Optional properties of the configuration, see NewRetryPolicy.
 func NewMessageClient(msgConfig types.MessageBusConfig) (MessageClient, error) {
+	factory, exists := lookupFactory(msgConfig.Type)
+	if !exists {
+		return nil, fmt.Errorf("unknown message type '%s' requested, registered types are: %s",
+			msgConfig.Type, strings.Join(RegisteredTypes(), ", "))
+	}
 
-	if msgConfig.Broker.IsHostInfoEmpty() {
-		return nil, fmt.Errorf("unable to create messageClient: Broker info not set")
+	policy, err := NewRetryPolicy(msgConfig.Optional)
+	if err != nil {
+		return nil, err
 	}
 
-	switch lowerMsgType := strings.ToLower(msgConfig.Type); lowerMsgType {
-	case MQTT:
-		return mqtt.NewMQTTClient(msgConfig)
-	case Redis:
-		return redis.NewClient(msgConfig)
-	case NatsCore:
-		return nats.NewClient(msgConfig)
-	case NatsJetStream:
-		return jetstream.NewClient(msgConfig)
-	default:
-		return nil, fmt.Errorf("unknown message type '%s' requested", msgConfig.Type)
+	client, err := factory(msgConfig)
+	if err != nil || policy.MaxAttempts == 1 {
+		return client, err
+	}
+
+	return WithRetry(client, policy), nil
+}
+
+// WithBrokerInfo wraps the factory of a message client which connects to a broker, so that an error is returned
+// when the Broker information is not set in the configuration.
+func WithBrokerInfo(factory ClientFactory) ClientFactory {
+	return func(config types.MessageBusConfig) (MessageClient, error) {
+		if config.Broker.IsHostInfoEmpty() {
+			return nil, fmt.Errorf("unable to create messageClient: Broker info not set")
+		}
+
+		return factory(config)
 	}
 }
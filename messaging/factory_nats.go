@@ -0,0 +1,21 @@
+// Copyright (C) 2024 IOTech Ltd
+
+//go:build include_nats_messaging && !no_messagebus
+
+package messaging
+
+import (
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats"
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/nats/jetstream"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// The NATS message clients are only registered when building with the include_nats_messaging flag
+func init() {
+	Register(NatsCore, WithBrokerInfo(func(config types.MessageBusConfig) (MessageClient, error) {
+		return nats.NewClient(config)
+	}))
+	Register(NatsJetStream, WithBrokerInfo(func(config types.MessageBusConfig) (MessageClient, error) {
+		return jetstream.NewClient(config)
+	}))
+}
@@ -14,7 +14,7 @@
 // limitations under the License.
 //
 
-//go:build include_nats_messaging
+//go:build include_nats_messaging && !no_messagebus
 
 package messaging
 
@@ -57,3 +57,11 @@ func TestNewMessageClientNatsJetstream(t *testing.T) {
 	require.NoError(t, err)
 	assert.IsType(t, &nats.Client{}, c)
 }
+
+// Ensure the NATS client implements the companion interfaces of MessageClient
+var (
+	_ MessageClientWithContext         = (*nats.Client)(nil)
+	_ MessageClientWithHandle          = (*nats.Client)(nil)
+	_ MessageClientWithRequestMany     = (*nats.Client)(nil)
+	_ MessageClientWithConnectionState = (*nats.Client)(nil)
+)
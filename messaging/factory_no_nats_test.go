@@ -14,7 +14,7 @@
 // limitations under the License.
 //
 
-//go:build !include_nats_messaging
+//go:build !include_nats_messaging && !no_messagebus
 
 package messaging_test
 
@@ -43,6 +43,7 @@ func TestNewMessageClientNatsCore(t *testing.T) {
 	_, err := messaging.NewMessageClient(messageBusConfig)
 
 	require.Error(t, err)
+	require.NotContains(t, messaging.RegisteredTypes(), messageBusConfig.Type)
 }
 
 func TestNewMessageClientNatsJetstream(t *testing.T) {
@@ -53,4 +54,5 @@ func TestNewMessageClientNatsJetstream(t *testing.T) {
 	_, err := messaging.NewMessageClient(messageBusConfig)
 
 	require.Error(t, err)
+	require.NotContains(t, messaging.RegisteredTypes(), messageBusConfig.Type)
 }
@@ -14,13 +14,18 @@
 // limitations under the License.
 //
 
-package messaging
+//go:build !no_messagebus
+
+package messaging_test
 
 import (
 	"testing"
 
+	"github.com/edgexfoundry/go-mod-messaging/v3/messaging"
+	_ "github.com/edgexfoundry/go-mod-messaging/v3/messaging/memory"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var msgConfig = types.MessageBusConfig{
@@ -33,7 +38,7 @@ var msgConfig = types.MessageBusConfig{
 
 func TestNewMessageClientMQTT(t *testing.T) {
 	messageBusConfig := msgConfig
-	messageBusConfig.Type = MQTT
+	messageBusConfig.Type = messaging.MQTT
 	messageBusConfig.Optional = map[string]string{
 		"Username":          "TestUser",
 		"Password":          "TestPassword",
@@ -45,7 +50,7 @@ func TestNewMessageClientMQTT(t *testing.T) {
 		"ConnectionPayload": "TestConnectionPayload",
 	}
 
-	_, err := NewMessageClient(messageBusConfig)
+	_, err := messaging.NewMessageClient(messageBusConfig)
 
 	if assert.NoError(t, err, "New Message client failed: ", err) == false {
 		t.Fatal()
@@ -56,7 +61,7 @@ func TestNewMessageClientBogusType(t *testing.T) {
 
 	msgConfig.Type = "zero"
 
-	_, err := NewMessageClient(msgConfig)
+	_, err := messaging.NewMessageClient(msgConfig)
 	if assert.Error(t, err, "Expected message type error") == false {
 		t.Fatal()
 	}
@@ -66,8 +71,41 @@ func TestNewMessageClientEmptyHostAndPortNumber(t *testing.T) {
 
 	msgConfig.Broker.Host = ""
 	msgConfig.Broker.Port = 0
-	_, err := NewMessageClient(msgConfig)
+	_, err := messaging.NewMessageClient(msgConfig)
 	if assert.Error(t, err, "Expected message type error") == false {
 		t.Fatal()
 	}
 }
+
+func TestNewMessageClientMemory(t *testing.T) {
+	messageBusConfig := types.MessageBusConfig{Type: "Memory"}
+
+	client, err := messaging.NewMessageClient(messageBusConfig)
+	require.NoError(t, err, "broker info is not required for the memory message client")
+	assert.NotNil(t, client)
+}
+
+func TestNewMessageClientRetryPolicy(t *testing.T) {
+	messageBusConfig := types.MessageBusConfig{Type: "Memory", Optional: map[string]string{"RetryMaxAttempts": "3"}}
+
+	client, err := messaging.NewMessageClient(messageBusConfig)
+	require.NoError(t, err)
+	assert.IsType(t, messaging.WithRetry(nil, messaging.RetryPolicy{}), client)
+
+	messageBusConfig.Optional["RetryJitter"] = "2"
+	_, err = messaging.NewMessageClient(messageBusConfig)
+	require.Error(t, err)
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	for _, typeName := range []string{messaging.MQTT, messaging.Redis, messaging.Memory} {
+		assert.Contains(t, messaging.RegisteredTypes(), typeName)
+	}
+}
+
+func TestNewMessageClientUnknownType(t *testing.T) {
+	_, err := messaging.NewMessageClient(types.MessageBusConfig{Type: "zero"})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "unknown message type 'zero'")
+	assert.ErrorContains(t, err, "memory, mqtt")
+}
@@ -17,6 +17,8 @@
 package messaging
 
 import (
+	"context"
+	"iter"
 	"time"
 
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
@@ -41,10 +43,10 @@ type MessageClient interface {
 	// the function returns error for any subscribe error
 	Subscribe(topics []types.TopicChannel, messageErrors chan error) error
 
-	// Request publishes a request containing a RequestID to the specified topic,
-	// then subscribes to a response topic which contains the RequestID. Once the response is received, the
-	// response topic is unsubscribed and the response data is returned. If no response is received within
-	// the timeout period, a timed out  error returned.
+	// Request publishes a request containing a RequestID to the specified topic and waits for the response published
+	// to the response topic <responseTopicPrefix>/<RequestID>. The response topic prefix is subscribed with a wildcard
+	// by the first request using it and stays subscribed for the following requests, which are matched with their
+	// responses by the RequestID. If no response is received within the timeout period, a timed out error returned.
 	Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error)
 
 	// Unsubscribe to unsubscribe from the specified topics.
@@ -60,3 +62,65 @@ type MessageClient interface {
 	// SubscribeBinaryData receives binary data from the specified topic, and wrap it in MessageEnvelope.
 	SubscribeBinaryData(topics []types.TopicChannel, messageErrors chan error) error
 }
+
+// MessageClientWithContext is the companion interface of MessageClient for implementations which allow the
+// messaging operations to be cancelled through a context.Context.
+type MessageClientWithContext interface {
+	// PublishWithContext is the same as Publish, but stops waiting for the publish to complete once ctx is done
+	// and returns the error of the context.
+	PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error
+
+	// SubscribeWithContext is the same as Subscribe, but the subscriptions it created for the topics are removed once
+	// ctx is done. The other subscriptions to the same topics are kept.
+	SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error
+
+	// RequestWithContext is the same as Request, but waits for the response until ctx is done rather than for an
+	// explicit timeout. Use context.WithTimeout or context.WithDeadline to limit the time waiting for the response.
+	RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error)
+}
+
+// MessageClientWithHandle is the companion interface of MessageClient for implementations which return a handle of
+// every subscription, so it can be unsubscribed on its own.
+type MessageClientWithHandle interface {
+	// SubscribeWithHandle is to receive messages from the topic channel like Subscribe, but returns a handle of the
+	// subscription. Several handles can be created for the same topic, the subscription on the broker is shared
+	// between them and only removed once the last handle is unsubscribed.
+	// Unsubscribe removes the subscription on the broker and closes all the handles for the topic.
+	SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error)
+
+	// SubscribeFunc is to receive messages from the topic by invoking the handler for every received message.
+	// The options specify how many workers invoke the handler and where the errors returned by the handler
+	// and the errors receiving messages are sent. The handler is no longer invoked once the returned
+	// subscription is unsubscribed.
+	SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error)
+
+	// Messages returns an iterator over the messages received on the topic and the errors receiving them, i.e.
+	// `for msg, err := range client.Messages(ctx, "edgex/events/#")`. The topic is subscribed when the loop starts and
+	// unsubscribed once the loop exits, either by breaking out of it or because ctx is done.
+	Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error]
+}
+
+// MessageClientWithRequestMany is the companion interface of MessageClient for implementations which collect the
+// responses of several responders to a request.
+type MessageClientWithRequestMany interface {
+	// RequestMany publishes a request like Request, but collects all the responses published to the response topic
+	// until the window closes, i.e. the request is handled by several services. It returns early once maxResponses
+	// responses are received, a maxResponses of 0 collects all the responses received within the window. The responses
+	// received are returned without error when the window closes.
+	RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error)
+}
+
+// MessageClientWithConnectionState is the companion interface of MessageClient for implementations which report the
+// state of their connection to the message bus.
+type MessageClientWithConnectionState interface {
+	// IsConnected reports whether the client is currently connected to the message bus
+	IsConnected() bool
+
+	// State returns the current state of the connection to the message bus
+	State() types.ConnectionState
+
+	// Events returns the channel the changes of the connection to the message bus are emitted on, i.e. the connection
+	// being lost and re-established with the subscriptions restored. The channel is shared by all the callers and
+	// buffers the latest events, older events are dropped when the events aren't received fast enough.
+	Events() <-chan types.ConnectionEvent
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/memory"
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/mqtt"
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/redis"
+)
+
+// Ensure the message clients implement the companion interfaces of MessageClient
+var (
+	_ MessageClientWithContext         = (*mqtt.Client)(nil)
+	_ MessageClientWithHandle          = (*mqtt.Client)(nil)
+	_ MessageClientWithRequestMany     = (*mqtt.Client)(nil)
+	_ MessageClientWithConnectionState = (*mqtt.Client)(nil)
+	_ MessageClientWithContext         = redis.Client{}
+	_ MessageClientWithHandle          = redis.Client{}
+	_ MessageClientWithRequestMany     = redis.Client{}
+	_ MessageClientWithConnectionState = redis.Client{}
+	_ MessageClientWithContext         = (*memory.Client)(nil)
+	_ MessageClientWithHandle          = (*memory.Client)(nil)
+	_ MessageClientWithRequestMany     = (*memory.Client)(nil)
+	_ MessageClientWithConnectionState = (*memory.Client)(nil)
+)
@@ -0,0 +1,7 @@
+// Copyright (C) 2024 IOTech Ltd
+
+// Package memory registers the in-process message client for the messaging.Memory Type. It is imported for its side
+// effect only:
+//
+//	import _ "github.com/edgexfoundry/go-mod-messaging/v3/messaging/memory"
+package memory
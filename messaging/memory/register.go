@@ -0,0 +1,17 @@
+// Copyright (C) 2024 IOTech Ltd
+
+//go:build !no_messagebus
+
+package memory
+
+import (
+	memoryClient "github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/memory"
+	"github.com/edgexfoundry/go-mod-messaging/v3/messaging"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func init() {
+	messaging.Register(messaging.Memory, func(config types.MessageBusConfig) (messaging.MessageClient, error) {
+		return memoryClient.NewClient(config)
+	})
+}
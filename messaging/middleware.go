@@ -0,0 +1,331 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Direction is the direction of a message passing through a middleware chain.
+type Direction int
+
+const (
+	// Outbound messages are published by the client, i.e. by Publish, PublishBinaryData or as a request
+	Outbound Direction = iota
+	// Inbound messages are received by the client, i.e. delivered to a subscription or as a response
+	Inbound
+)
+
+func (d Direction) String() string {
+	if d == Inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// Message is a message passing through a middleware chain. The middleware may modify the Topic and the Envelope before
+// passing the message to the next handler. The Topic of an inbound message is its ReceivedTopic. The Envelope of a
+// message published with PublishBinaryData only contains the data as Payload.
+type Message struct {
+	Direction Direction
+	Topic     string
+	Envelope  types.MessageEnvelope
+}
+
+// MiddlewareHandler handles a message passing through a middleware chain.
+type MiddlewareHandler func(ctx context.Context, message *Message) error
+
+// Middleware wraps the next handler of the chain. A middleware rejects the message by returning an error without
+// calling next, the rejected message is neither published nor delivered.
+type Middleware func(next MiddlewareHandler) MiddlewareHandler
+
+// WithMiddleware wraps the client so that every published and received message passes through the middleware chain,
+// in the order specified for both directions, i.e. the first middleware is the outermost one.
+//
+// The error of an outbound message rejected by a middleware is returned by Publish, PublishBinaryData or Request.
+// Inbound messages rejected by a middleware are reported on the messageErrors channel of the subscription and
+// responses rejected by a middleware fail Request and are dropped by RequestMany.
+func WithMiddleware(client MessageClient, middleware ...Middleware) MessageClient {
+	return &middlewareClient{
+		client:        wrappedClient{client},
+		middleware:    middleware,
+		subscriptions: make(map[string]chan struct{}),
+	}
+}
+
+// middlewareClient is the MessageClient returned by WithMiddleware. The messages received by the subscriptions of the
+// wrapped client are passed through the chain by a go func per topic, which forwards them to the subscriber.
+type middlewareClient struct {
+	client        wrappedClient
+	middleware    []Middleware
+	subscriptions map[string]chan struct{}
+	mutex         sync.Mutex
+}
+
+func (c *middlewareClient) Connect() error {
+	return c.client.Connect()
+}
+
+func (c *middlewareClient) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishWithContext(context.Background(), message, topic)
+}
+
+func (c *middlewareClient) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	return c.chain(func(ctx context.Context, message *Message) error {
+		if contextClient, ok := c.client.MessageClient.(MessageClientWithContext); ok {
+			return contextClient.PublishWithContext(ctx, message.Envelope, message.Topic)
+		}
+
+		return pkg.RunWithContext(ctx, func() error {
+			return c.client.Publish(message.Envelope, message.Topic)
+		})
+	})(ctx, &Message{Direction: Outbound, Topic: topic, Envelope: message})
+}
+
+func (c *middlewareClient) PublishBinaryData(data []byte, topic string) error {
+	return c.chain(func(ctx context.Context, message *Message) error {
+		return c.client.PublishBinaryData(message.Envelope.Payload, message.Topic)
+	})(context.Background(), &Message{Direction: Outbound, Topic: topic, Envelope: types.MessageEnvelope{Payload: data}})
+}
+
+func (c *middlewareClient) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, c.client.Subscribe)
+}
+
+func (c *middlewareClient) SubscribeBinaryData(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, c.client.SubscribeBinaryData)
+}
+
+func (c *middlewareClient) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return pkg.SubscribeWithContext(ctx, c.SubscribeWithHandle, topics, messageErrors)
+}
+
+// subscribe subscribes the wrapped client to the topics with channels of its own, which are forwarded to the channels
+// of the subscriber once the messages passed through the chain. Subscribing again to a topic replaces its forwarding.
+func (c *middlewareClient) subscribe(
+	topics []types.TopicChannel,
+	messageErrors chan error,
+	subscribe func(topics []types.TopicChannel, messageErrors chan error) error) error {
+	wrapped := make([]types.TopicChannel, 0, len(topics))
+	for _, topic := range topics {
+		wrapped = append(wrapped, types.TopicChannel{Topic: topic.Topic, Messages: make(chan types.MessageEnvelope)})
+	}
+
+	if err := subscribe(wrapped, messageErrors); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, topic := range topics {
+		if done, exists := c.subscriptions[topic.Topic]; exists {
+			close(done)
+		}
+
+		done := make(chan struct{})
+		c.subscriptions[topic.Topic] = done
+		go c.forward(wrapped[i].Messages, topic, messageErrors, done)
+	}
+
+	return nil
+}
+
+func (c *middlewareClient) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	messages := make(chan types.MessageEnvelope)
+	subscription, err := c.client.SubscribeWithHandle(types.TopicChannel{Topic: topic.Topic, Messages: messages}, messageErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.forward(messages, topic, messageErrors, subscription.Done())
+
+	return subscription, nil
+}
+
+// SubscribeFunc subscribes with the SubscribeFunc of the wrapped client, passing the received messages through the
+// chain before invoking the handler. The errors of the messages rejected by a middleware are passed to the
+// ErrorHandler of the options.
+func (c *middlewareClient) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("unable to subscribe to topic '%s': handler is required", topic)
+	}
+
+	return c.client.SubscribeFunc(topic, func(message types.MessageEnvelope) error {
+		received, err := c.receive(context.Background(), message)
+		if err != nil {
+			return err
+		}
+
+		return handler(received)
+	}, options)
+}
+
+func (c *middlewareClient) Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error] {
+	return pkg.Messages(ctx, c.SubscribeWithHandle, topic)
+}
+
+// forward passes the messages received on the channel of the wrapped client through the chain and forwards them to the
+// channel of the subscriber, according to its overflow policy, until done is closed.
+func (c *middlewareClient) forward(
+	received <-chan types.MessageEnvelope,
+	topic types.TopicChannel,
+	messageErrors chan error,
+	done <-chan struct{}) {
+	deliverer := pkg.NewDeliverer(topic, messageErrors)
+	for {
+		select {
+		case <-done:
+			return
+
+		case message := <-received:
+			message, err := c.receive(context.Background(), message)
+			if err != nil {
+				select {
+				case messageErrors <- err:
+				case <-done:
+					return
+				}
+				continue
+			}
+
+			deliverer.Deliver(message, done)
+		}
+	}
+}
+
+// receive passes the inbound message through the chain and returns the message as modified by the middleware.
+func (c *middlewareClient) receive(ctx context.Context, envelope types.MessageEnvelope) (types.MessageEnvelope, error) {
+	var received types.MessageEnvelope
+	err := c.chain(func(ctx context.Context, message *Message) error {
+		received = message.Envelope
+		received.ReceivedTopic = message.Topic
+		return nil
+	})(ctx, &Message{Direction: Inbound, Topic: envelope.ReceivedTopic, Envelope: envelope})
+
+	return received, err
+}
+
+func (c *middlewareClient) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestWithContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+func (c *middlewareClient) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	var response *types.MessageEnvelope
+	err := c.chain(func(ctx context.Context, message *Message) error {
+		var err error
+		if contextClient, ok := c.client.MessageClient.(MessageClientWithContext); ok {
+			response, err = contextClient.RequestWithContext(ctx, message.Envelope, message.Topic, responseTopicPrefix)
+		} else {
+			response, err = c.client.Request(message.Envelope, message.Topic, responseTopicPrefix, timeoutFromContext(ctx))
+		}
+		return err
+	})(ctx, &Message{Direction: Outbound, Topic: requestTopic, Envelope: message})
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := c.receive(ctx, *response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &received, nil
+}
+
+func (c *middlewareClient) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	var responses []types.MessageEnvelope
+	requestErr := c.chain(func(ctx context.Context, message *Message) error {
+		var err error
+		responses, err = c.client.RequestMany(message.Envelope, message.Topic, responseTopicPrefix, window, maxResponses)
+		return err
+	})(context.Background(), &Message{Direction: Outbound, Topic: requestTopic, Envelope: message})
+
+	received := make([]types.MessageEnvelope, 0, len(responses))
+	for _, response := range responses {
+		if response, err := c.receive(context.Background(), response); err == nil {
+			received = append(received, response)
+		}
+	}
+
+	return received, requestErr
+}
+
+func (c *middlewareClient) Unsubscribe(topics ...string) error {
+	err := c.client.Unsubscribe(topics...)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, topic := range topics {
+		if done, exists := c.subscriptions[topic]; exists {
+			close(done)
+			delete(c.subscriptions, topic)
+		}
+	}
+
+	return err
+}
+
+func (c *middlewareClient) Disconnect() error {
+	err := c.client.Disconnect()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for topic, done := range c.subscriptions {
+		close(done)
+		delete(c.subscriptions, topic)
+	}
+
+	return err
+}
+
+func (c *middlewareClient) IsConnected() bool {
+	return c.client.IsConnected()
+}
+
+func (c *middlewareClient) State() types.ConnectionState {
+	return c.client.State()
+}
+
+func (c *middlewareClient) Events() <-chan types.ConnectionEvent {
+	return c.client.Events()
+}
+
+// chain wraps the handler with the middleware, the first middleware being the outermost one.
+func (c *middlewareClient) chain(handler MiddlewareHandler) MiddlewareHandler {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		handler = c.middleware[i](handler)
+	}
+
+	return handler
+}
+
+// timeoutFromContext returns the time left until the deadline of ctx, for the clients which only accept a timeout.
+func timeoutFromContext(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Until(deadline)
+}
+
+var (
+	_ MessageClientWithContext         = (*middlewareClient)(nil)
+	_ MessageClientWithHandle          = (*middlewareClient)(nil)
+	_ MessageClientWithRequestMany     = (*middlewareClient)(nil)
+	_ MessageClientWithConnectionState = (*middlewareClient)(nil)
+)
@@ -0,0 +1,204 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// recordingMiddleware records the direction and topic of the messages passing through it as
+// "<name> <direction> <topic>".
+func recordingMiddleware(name string, records *[]string, mutex *sync.Mutex) Middleware {
+	return func(next MiddlewareHandler) MiddlewareHandler {
+		return func(ctx context.Context, message *Message) error {
+			mutex.Lock()
+			*records = append(*records, strings.Join([]string{name, message.Direction.String(), message.Topic}, " "))
+			mutex.Unlock()
+			return next(ctx, message)
+		}
+	}
+}
+
+func TestWithMiddlewareOrder(t *testing.T) {
+	var records []string
+	mutex := sync.Mutex{}
+	client := WithMiddleware(newMemoryClient(t), recordingMiddleware("first", &records, &mutex), recordingMiddleware("second", &records, &mutex))
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{Payload: []byte("data")}, "edgex/events"))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "data", string(message.Payload))
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{
+		"first outbound edgex/events",
+		"second outbound edgex/events",
+		"first inbound edgex/events",
+		"second inbound edgex/events",
+	}, records)
+}
+
+func TestWithMiddlewareMutate(t *testing.T) {
+	rewrite := func(next MiddlewareHandler) MiddlewareHandler {
+		return func(ctx context.Context, message *Message) error {
+			if message.Direction == Outbound {
+				message.Topic = strings.Replace(message.Topic, "legacy/", "edgex/", 1)
+				message.Envelope.CorrelationID = "correlation"
+			} else {
+				message.Topic = strings.ToUpper(message.Topic)
+			}
+			return next(ctx, message)
+		}
+	}
+
+	client := WithMiddleware(newMemoryClient(t), rewrite)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	binaryMessages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.SubscribeBinaryData([]types.TopicChannel{{Topic: "edgex/binary", Messages: binaryMessages}}, make(chan error)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "legacy/events"))
+	require.NoError(t, client.PublishBinaryData([]byte("binary"), "legacy/binary"))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "EDGEX/EVENTS", message.ReceivedTopic)
+		assert.Equal(t, "correlation", message.CorrelationID)
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+
+	select {
+	case message := <-binaryMessages:
+		assert.Equal(t, "EDGEX/BINARY", message.ReceivedTopic)
+		assert.Equal(t, "binary", string(message.Payload))
+	case <-time.After(time.Second):
+		require.Fail(t, "binary data not received")
+	}
+}
+
+func TestWithMiddlewareReject(t *testing.T) {
+	rejectErr := errors.New("rejected")
+	reject := func(next MiddlewareHandler) MiddlewareHandler {
+		return func(ctx context.Context, message *Message) error {
+			if string(message.Envelope.Payload) == "invalid" {
+				return rejectErr
+			}
+			return next(ctx, message)
+		}
+	}
+
+	memoryClient := newMemoryClient(t)
+	client := WithMiddleware(memoryClient, reject)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 1)
+	subscription, err := client.(MessageClientWithHandle).SubscribeWithHandle(types.TopicChannel{Topic: "edgex/events", Messages: messages}, messageErrors)
+	require.NoError(t, err)
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	require.ErrorIs(t, client.Publish(types.MessageEnvelope{Payload: []byte("invalid")}, "edgex/events"), rejectErr)
+
+	// Inbound messages are rejected too, i.e. published by a client without the middleware
+	require.NoError(t, memoryClient.Publish(types.MessageEnvelope{Payload: []byte("invalid")}, "edgex/events"))
+	select {
+	case err := <-messageErrors:
+		require.ErrorIs(t, err, rejectErr)
+	case <-time.After(time.Second):
+		require.Fail(t, "rejection not reported")
+	}
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{Payload: []byte("valid")}, "edgex/events"))
+	select {
+	case message := <-messages:
+		assert.Equal(t, "valid", string(message.Payload))
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+}
+
+func TestWithMiddlewareRequest(t *testing.T) {
+	memoryClient := newMemoryClient(t)
+
+	responder := NewResponder(memoryClient, "edgex/response", ResponderOptions{})
+	defer func() { _ = responder.Close() }()
+	require.NoError(t, responder.Handle("edgex/request", func(request Request) (Response, error) {
+		return Response{Payload: request.Payload}, nil
+	}))
+
+	var records []string
+	mutex := sync.Mutex{}
+	client := WithMiddleware(memoryClient, recordingMiddleware("recorder", &records, &mutex))
+
+	request := types.NewMessageEnvelopeForRequest([]byte("ping"), nil)
+	response, err := client.Request(request, "edgex/request", "edgex/response", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(response.Payload))
+
+	responses, err := client.(MessageClientWithRequestMany).RequestMany(types.NewMessageEnvelopeForRequest([]byte("ping"), nil), "edgex/request", "edgex/response", time.Second, 1)
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{
+		"recorder outbound edgex/request",
+		"recorder inbound edgex/response/" + request.RequestID,
+		"recorder outbound edgex/request",
+		"recorder inbound edgex/response/" + responses[0].RequestID,
+	}, records)
+}
+
+func TestWithMiddlewareSubscribeFunc(t *testing.T) {
+	calls := 0
+	mutex := sync.Mutex{}
+	count := func(next MiddlewareHandler) MiddlewareHandler {
+		return func(ctx context.Context, message *Message) error {
+			mutex.Lock()
+			calls++
+			mutex.Unlock()
+			return next(ctx, message)
+		}
+	}
+
+	client := WithMiddleware(newMemoryClient(t), count)
+
+	received := make(chan types.MessageEnvelope, 1)
+	subscription, err := client.(MessageClientWithHandle).SubscribeFunc("edgex/events", func(message types.MessageEnvelope) error {
+		received <- message
+		return nil
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+
+	require.NoError(t, subscription.Unsubscribe())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 2, calls)
+}
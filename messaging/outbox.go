@@ -0,0 +1,477 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// DefaultOutboxReplayInterval is the interval between the attempts to replay the stored messages when not specified
+	DefaultOutboxReplayInterval = 5 * time.Second
+
+	outboxRecordExtension = ".json"
+)
+
+// OutboxOptions defines where an OutboxClient stores the messages which couldn't be published and for how long.
+type OutboxOptions struct {
+	// Directory is the directory the messages are stored in, one file per message. It is created if it doesn't exist.
+	// The messages found in the directory are replayed, i.e. those stored before the service restarted.
+	Directory string
+	// MaxSize is the maximum size in bytes of the stored messages, the oldest messages are dropped to make room for the
+	// new ones once it is reached. A MaxSize of 0 doesn't limit the size.
+	MaxSize int64
+	// MaxAge is the maximum age of the stored messages, older messages are dropped rather than replayed. A MaxAge of 0
+	// doesn't limit the age.
+	MaxAge time.Duration
+	// ReplayInterval is the interval between the attempts to replay the stored messages, DefaultOutboxReplayInterval
+	// when not specified.
+	ReplayInterval time.Duration
+	// ErrorHandler receives the errors of the outbox, i.e. a message which couldn't be stored or was dropped. Errors
+	// are discarded when not set.
+	ErrorHandler func(err error)
+}
+
+// OutboxClient is a MessageClient which stores the messages it couldn't publish because the message bus is unreachable
+// in an on-disk outbox, and replays them in order once the message bus is reachable again. While the outbox isn't
+// empty, the new messages are stored after the ones waiting to be replayed so that the order is preserved.
+//
+// A message is only stored if its publish failed with a retryable error, see IsRetryable, the other errors, i.e. an
+// invalid topic, are returned to the caller. The requests are never stored, as their response is expected right away.
+// A stored message is synced to the disk before the publish returns, so it survives a crash of the service.
+type OutboxClient struct {
+	wrappedClient
+	options OutboxOptions
+	records []outboxRecord
+	size    int64
+	// sequence is the sequence number of the last stored message, which orders the files of the outbox
+	sequence uint64
+	mutex    sync.Mutex
+	// sendMutex is held for reading by the publishes while they check the outbox is empty and publish, and for writing
+	// by Replay while it publishes a stored message, so a new message doesn't overtake the stored ones
+	sendMutex sync.RWMutex
+	// replayMutex serializes the replays, so the messages are replayed once and in order
+	replayMutex sync.Mutex
+	stop        chan struct{}
+	stopped     chan struct{}
+}
+
+// outboxRecord is a message stored in the outbox.
+type outboxRecord struct {
+	sequence uint64
+	size     int64
+
+	Topic    string                 `json:"topic"`
+	Time     time.Time              `json:"time"`
+	Binary   bool                   `json:"binary,omitempty"`
+	Envelope *types.MessageEnvelope `json:"envelope,omitempty"`
+	Data     []byte                 `json:"data,omitempty"`
+}
+
+// WithOutbox wraps the client with an on-disk outbox. The messages stored in the directory of the outbox are loaded
+// and replayed once Connect is called.
+func WithOutbox(client MessageClient, options OutboxOptions) (*OutboxClient, error) {
+	if options.Directory == "" {
+		return nil, errors.New("unable to create outbox: Directory must be specified")
+	}
+
+	if options.ReplayInterval <= 0 {
+		options.ReplayInterval = DefaultOutboxReplayInterval
+	}
+
+	if err := os.MkdirAll(options.Directory, 0750); err != nil {
+		return nil, fmt.Errorf("unable to create outbox directory %s: %w", options.Directory, err)
+	}
+
+	outbox := &OutboxClient{wrappedClient: wrappedClient{client}, options: options}
+	if err := outbox.load(); err != nil {
+		return nil, err
+	}
+
+	return outbox, nil
+}
+
+// Connect connects the wrapped client and starts replaying the stored messages.
+func (o *OutboxClient) Connect() error {
+	if err := o.MessageClient.Connect(); err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.stop == nil {
+		o.stop = make(chan struct{})
+		o.stopped = make(chan struct{})
+		go o.replayLoop(o.stop, o.stopped)
+	}
+
+	return nil
+}
+
+// Disconnect stops replaying the stored messages and disconnects the wrapped client. The messages not replayed yet stay
+// in the outbox.
+func (o *OutboxClient) Disconnect() error {
+	o.mutex.Lock()
+	stop, stopped := o.stop, o.stopped
+	o.stop, o.stopped = nil, nil
+	o.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+
+	return o.MessageClient.Disconnect()
+}
+
+// Publish publishes the message, or stores it in the outbox if the publish failed with a retryable error or messages
+// are waiting to be replayed. A nil error is returned once the message is stored.
+func (o *OutboxClient) Publish(message types.MessageEnvelope, topic string) error {
+	return o.publish(context.Background(), outboxRecord{Topic: topic, Envelope: &message})
+}
+
+// PublishWithContext publishes the message like Publish, but stops waiting for the publish to complete once ctx is
+// done. The message isn't stored in the outbox then, as the publish may still complete, and the error of the context
+// is returned.
+func (o *OutboxClient) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return o.publish(ctx, outboxRecord{Topic: topic, Envelope: &message})
+}
+
+// PublishBinaryData publishes the data, or stores it in the outbox like Publish.
+func (o *OutboxClient) PublishBinaryData(data []byte, topic string) error {
+	return o.publish(context.Background(), outboxRecord{Topic: topic, Binary: true, Data: data})
+}
+
+// SubscribeWithContext subscribes with the wrapped client, the subscriptions are removed once ctx is done.
+func (o *OutboxClient) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if contextClient, ok := o.MessageClient.(MessageClientWithContext); ok {
+		return contextClient.SubscribeWithContext(ctx, topics, messageErrors)
+	}
+
+	return pkg.SubscribeWithContext(ctx, o.SubscribeWithHandle, topics, messageErrors)
+}
+
+// RequestWithContext sends the request with the wrapped client and waits for the response until ctx is done. The
+// request is never stored in the outbox.
+func (o *OutboxClient) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	if contextClient, ok := o.MessageClient.(MessageClientWithContext); ok {
+		return contextClient.RequestWithContext(ctx, message, requestTopic, responseTopicPrefix)
+	}
+
+	return o.MessageClient.Request(message, requestTopic, responseTopicPrefix, timeoutFromContext(ctx))
+}
+
+// Depth returns the number of messages waiting in the outbox to be replayed.
+func (o *OutboxClient) Depth() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.records)
+}
+
+// Replay publishes the stored messages in order until the outbox is empty or a message can't be published. The stored
+// messages are replayed periodically once connected, Replay allows replaying them right away.
+func (o *OutboxClient) Replay() error {
+	o.replayMutex.Lock()
+	defer o.replayMutex.Unlock()
+
+	for {
+		o.mutex.Lock()
+		errs := o.dropExpired()
+		empty := len(o.records) == 0
+		var record outboxRecord
+		if !empty {
+			record = o.records[0]
+		}
+		o.mutex.Unlock()
+
+		o.handleErrors(errs)
+		errs = nil
+		if empty {
+			return nil
+		}
+
+		o.sendMutex.Lock()
+		err := o.send(context.Background(), record)
+		if err != nil && IsRetryable(err) {
+			o.sendMutex.Unlock()
+			return fmt.Errorf("unable to replay message to topic %s: %w", record.Topic, err)
+		}
+
+		o.mutex.Lock()
+		// The record may have been dropped to make room for new ones while it was replayed
+		if len(o.records) > 0 && o.records[0].sequence == record.sequence {
+			errs = append(errs, o.remove())
+		}
+		o.mutex.Unlock()
+		o.sendMutex.Unlock()
+
+		if err != nil {
+			// The message can't be published however many times it's replayed, so it would block the outbox forever
+			errs = append(errs, fmt.Errorf("dropped message to topic %s from outbox: %w", record.Topic, err))
+		}
+		o.handleErrors(errs)
+	}
+}
+
+func (o *OutboxClient) replayLoop(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(o.options.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		// The failed replays are attempted again on the next tick, so their error isn't reported
+		_ = o.Replay()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *OutboxClient) publish(ctx context.Context, record outboxRecord) error {
+	o.sendMutex.RLock()
+	errs, err := o.publishOrStore(ctx, record)
+	o.sendMutex.RUnlock()
+
+	// The handler may call the OutboxClient, so it must be invoked once the mutexes are unlocked
+	o.handleErrors(errs)
+	return err
+}
+
+// publishOrStore publishes the record if the outbox is empty, and stores it if it isn't or the publish failed with a
+// retryable error. Returns the errors of the outbox to report along with the error of the publish. Must be called with
+// the sendMutex locked for reading.
+func (o *OutboxClient) publishOrStore(ctx context.Context, record outboxRecord) ([]error, error) {
+	if o.Depth() == 0 {
+		err := o.send(ctx, record)
+		if err == nil || !IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	record.Time = time.Now()
+	errs, err := o.store(record)
+	if err != nil {
+		return errs, fmt.Errorf("unable to store message to topic %s in outbox: %w", record.Topic, err)
+	}
+
+	return errs, nil
+}
+
+// send publishes the record with the wrapped client, waiting for the publish to complete until ctx is done.
+func (o *OutboxClient) send(ctx context.Context, record outboxRecord) error {
+	// A context which is never done, i.e. context.Background(), doesn't need the publish to be watched
+	if ctx.Done() == nil {
+		if record.Binary {
+			return o.MessageClient.PublishBinaryData(record.Data, record.Topic)
+		}
+
+		return o.MessageClient.Publish(*record.Envelope, record.Topic)
+	}
+
+	if contextClient, ok := o.MessageClient.(MessageClientWithContext); ok && !record.Binary {
+		return contextClient.PublishWithContext(ctx, *record.Envelope, record.Topic)
+	}
+
+	return pkg.RunWithContext(ctx, func() error {
+		return o.send(context.Background(), record)
+	})
+}
+
+// store writes the record to the outbox directory, dropping the oldest records if needed to stay within MaxSize.
+// Returns the errors of the dropped records to report along with the error storing the record.
+func (o *OutboxClient) store(record outboxRecord) ([]error, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	record.size = int64(len(data))
+	if o.options.MaxSize > 0 && record.size > o.options.MaxSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the outbox MaxSize", record.size)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	errs := o.dropExpired()
+	for o.options.MaxSize > 0 && len(o.records) > 0 && o.size+record.size > o.options.MaxSize {
+		errs = append(errs, fmt.Errorf("dropped message to topic %s from outbox: MaxSize reached", o.records[0].Topic), o.remove())
+	}
+
+	record.sequence = o.sequence + 1
+	if err = o.write(record.sequence, data); err != nil {
+		return errs, err
+	}
+
+	o.sequence = record.sequence
+	o.records = append(o.records, record)
+	o.size += record.size
+
+	return errs, nil
+}
+
+// write writes the file of the record and syncs it, along with the directory entry of the file, to the disk. The file
+// is removed if it couldn't be written or synced, as the message isn't stored then.
+func (o *OutboxClient) write(sequence uint64, data []byte) error {
+	path := o.path(sequence)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = syncDirectory(o.options.Directory)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// syncDirectory syncs the entries of the directory to the disk. Syncing a directory isn't supported on Windows, so it
+// is skipped there.
+func syncDirectory(directory string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// dropExpired removes the records older than MaxAge and returns the errors to report. Must be called with the mutex
+// locked.
+func (o *OutboxClient) dropExpired() []error {
+	if o.options.MaxAge <= 0 {
+		return nil
+	}
+
+	var errs []error
+	for len(o.records) > 0 && time.Since(o.records[0].Time) > o.options.MaxAge {
+		errs = append(errs, fmt.Errorf("dropped message to topic %s from outbox: MaxAge exceeded", o.records[0].Topic), o.remove())
+	}
+
+	return errs
+}
+
+// remove removes the oldest record and its file, returning the error removing the file if any. Must be called with the
+// mutex locked.
+func (o *OutboxClient) remove() error {
+	record := o.records[0]
+	o.records = o.records[1:]
+	o.size -= record.size
+
+	if err := os.Remove(o.path(record.sequence)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove message from outbox: %w", err)
+	}
+
+	return nil
+}
+
+// load reads the records stored in the outbox directory, in order. Files which can't be read are removed.
+func (o *OutboxClient) load() error {
+	entries, err := os.ReadDir(o.options.Directory)
+	if err != nil {
+		return fmt.Errorf("unable to read outbox directory %s: %w", o.options.Directory, err)
+	}
+
+	// The entries are sorted by name, which is the zero padded sequence number
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, outboxRecordExtension) {
+			continue
+		}
+
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(name, outboxRecordExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var record outboxRecord
+		data, err := os.ReadFile(o.path(sequence))
+		if err == nil {
+			err = json.Unmarshal(data, &record)
+		}
+		if err == nil && !record.Binary && record.Envelope == nil {
+			err = errors.New("message envelope is missing")
+		}
+		if err != nil {
+			o.handleErrors([]error{fmt.Errorf("removed unreadable message %s from outbox: %w", name, err)})
+			_ = os.Remove(o.path(sequence))
+			continue
+		}
+
+		record.sequence = sequence
+		record.size = int64(len(data))
+		o.records = append(o.records, record)
+		o.size += record.size
+		o.sequence = sequence
+	}
+
+	return nil
+}
+
+func (o *OutboxClient) path(sequence uint64) string {
+	return filepath.Join(o.options.Directory, fmt.Sprintf("%020d%s", sequence, outboxRecordExtension))
+}
+
+// handleErrors passes the errors which aren't nil to the ErrorHandler.
+func (o *OutboxClient) handleErrors(errs []error) {
+	if o.options.ErrorHandler == nil {
+		return
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			o.options.ErrorHandler(err)
+		}
+	}
+}
+
+var (
+	_ MessageClientWithContext         = (*OutboxClient)(nil)
+	_ MessageClientWithHandle          = (*OutboxClient)(nil)
+	_ MessageClientWithRequestMany     = (*OutboxClient)(nil)
+	_ MessageClientWithConnectionState = (*OutboxClient)(nil)
+)
@@ -0,0 +1,232 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// unreliableClient is a MessageClient whose message bus can be made unreachable, or hang the publishes until stalled
+// is closed. The wrapped client keeps reporting it's connected, like a client which didn't notice the connection loss
+// yet.
+type unreliableClient struct {
+	wrappedClient
+	offline atomic.Bool
+	stalled chan struct{}
+}
+
+func (c *unreliableClient) Publish(message types.MessageEnvelope, topic string) error {
+	if c.stalled != nil {
+		<-c.stalled
+	}
+	if c.offline.Load() {
+		return pkg.NewConnectionErr(errors.New("connection refused"))
+	}
+	if topic == "invalid" {
+		return errors.New("invalid topic")
+	}
+	return c.MessageClient.Publish(message, topic)
+}
+
+func (c *unreliableClient) PublishBinaryData(data []byte, topic string) error {
+	if c.offline.Load() {
+		return pkg.NewConnectionErr(errors.New("connection refused"))
+	}
+	return c.MessageClient.PublishBinaryData(data, topic)
+}
+
+// receiveAll returns the payloads of the messages received within the timeout.
+func receiveAll(messages <-chan types.MessageEnvelope, timeout time.Duration) []string {
+	var payloads []string
+	for {
+		select {
+		case message := <-messages:
+			payloads = append(payloads, string(message.Payload))
+		case <-time.After(timeout):
+			return payloads
+		}
+	}
+}
+
+func TestOutboxClient(t *testing.T) {
+	memoryClient := newMemoryClient(t)
+	client := &unreliableClient{wrappedClient: wrappedClient{memoryClient}}
+
+	outbox, err := WithOutbox(client, OutboxOptions{Directory: t.TempDir(), ReplayInterval: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Connect())
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, memoryClient.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte("1")}, "edgex/events"))
+	assert.Equal(t, 0, outbox.Depth())
+
+	client.offline.Store(true)
+	require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte("2")}, "edgex/events"), "the message must be stored")
+	require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte("3")}, "edgex/events"))
+	assert.Equal(t, 2, outbox.Depth())
+	require.Error(t, outbox.Replay(), "replaying fails while offline")
+
+	client.offline.Store(false)
+	// Stored after the waiting messages to preserve the order, even though online
+	require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte("4")}, "edgex/events"))
+	assert.Equal(t, 3, outbox.Depth())
+
+	require.NoError(t, outbox.Replay())
+	assert.Equal(t, 0, outbox.Depth())
+	assert.Equal(t, []string{"1", "2", "3", "4"}, receiveAll(messages, 100*time.Millisecond))
+
+	// Errors which aren't retryable are returned
+	require.ErrorContains(t, outbox.Publish(types.MessageEnvelope{}, "invalid"), "invalid topic")
+	assert.Equal(t, 0, outbox.Depth())
+
+	require.NoError(t, outbox.Disconnect())
+}
+
+func TestOutboxClientWithContext(t *testing.T) {
+	memoryClient := newMemoryClient(t)
+	client := &unreliableClient{wrappedClient: wrappedClient{memoryClient}}
+
+	outbox, err := WithOutbox(client, OutboxOptions{Directory: t.TempDir(), ReplayInterval: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, outbox.SubscribeWithContext(ctx, []types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, nil))
+
+	require.NoError(t, outbox.PublishWithContext(ctx, types.MessageEnvelope{Payload: []byte("1")}, "edgex/events"))
+	client.offline.Store(true)
+	require.NoError(t, outbox.PublishWithContext(ctx, types.MessageEnvelope{Payload: []byte("2")}, "edgex/events"), "the message must be stored")
+	assert.Equal(t, 1, outbox.Depth())
+	client.offline.Store(false)
+	require.NoError(t, outbox.Replay())
+	assert.Equal(t, []string{"1", "2"}, receiveAll(messages, 100*time.Millisecond))
+
+	// The messages whose publish is abandoned aren't stored, as the publish may still complete
+	client.stalled = make(chan struct{})
+	defer close(client.stalled)
+	timeout, cancelTimeout := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelTimeout()
+	client.offline.Store(true)
+	require.ErrorIs(t, outbox.PublishWithContext(timeout, types.MessageEnvelope{Payload: []byte("3")}, "edgex/events"), context.DeadlineExceeded)
+	assert.Equal(t, 0, outbox.Depth())
+
+	cancel()
+	require.ErrorIs(t, outbox.PublishWithContext(ctx, types.MessageEnvelope{}, "edgex/events"), context.Canceled)
+}
+
+func TestOutboxClientReplayLoop(t *testing.T) {
+	memoryClient := newMemoryClient(t)
+	client := &unreliableClient{wrappedClient: wrappedClient{memoryClient}}
+	client.offline.Store(true)
+
+	outbox, err := WithOutbox(client, OutboxOptions{Directory: t.TempDir(), ReplayInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Connect())
+	defer func() { _ = outbox.Disconnect() }()
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, memoryClient.SubscribeBinaryData([]types.TopicChannel{{Topic: "edgex/binary", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, outbox.PublishBinaryData([]byte("binary"), "edgex/binary"))
+	assert.Equal(t, 1, outbox.Depth())
+
+	client.offline.Store(false)
+	require.Eventually(t, func() bool { return outbox.Depth() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"binary"}, receiveAll(messages, 100*time.Millisecond))
+}
+
+func TestOutboxClientPersistence(t *testing.T) {
+	directory := t.TempDir()
+	memoryClient := newMemoryClient(t)
+	client := &unreliableClient{wrappedClient: wrappedClient{memoryClient}}
+	client.offline.Store(true)
+
+	outbox, err := WithOutbox(client, OutboxOptions{Directory: directory, ReplayInterval: time.Hour})
+	require.NoError(t, err)
+	for _, payload := range []string{"1", "2", "3"} {
+		require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte(payload)}, "edgex/events"))
+	}
+
+	// An unreadable file is removed rather than blocking the outbox
+	require.NoError(t, os.WriteFile(outbox.path(2), []byte("{"), 0640))
+
+	var handledErrors []error
+	mutex := sync.Mutex{}
+	restarted, err := WithOutbox(client, OutboxOptions{
+		Directory:      directory,
+		ReplayInterval: time.Hour,
+		ErrorHandler: func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			handledErrors = append(handledErrors, err)
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, restarted.Depth())
+	require.Len(t, handledErrors, 1)
+	assert.ErrorContains(t, handledErrors[0], "removed unreadable message")
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, memoryClient.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	client.offline.Store(false)
+	require.NoError(t, restarted.Publish(types.MessageEnvelope{Payload: []byte("4")}, "edgex/events"))
+	require.NoError(t, restarted.Replay())
+	assert.Equal(t, []string{"1", "3", "4"}, receiveAll(messages, 100*time.Millisecond))
+
+	entries, err := os.ReadDir(directory)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxClientLimits(t *testing.T) {
+	client := &unreliableClient{wrappedClient: wrappedClient{newMemoryClient(t)}}
+	client.offline.Store(true)
+
+	var handledErrors []error
+	mutex := sync.Mutex{}
+	errorHandler := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		handledErrors = append(handledErrors, err)
+	}
+
+	outbox, err := WithOutbox(client, OutboxOptions{Directory: t.TempDir(), MaxSize: 400, ErrorHandler: errorHandler})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, outbox.Publish(types.MessageEnvelope{Payload: []byte("reading")}, "edgex/events"))
+	}
+	depth := outbox.Depth()
+	assert.Less(t, depth, 10, "the oldest messages must be dropped once MaxSize is reached")
+	assert.LessOrEqual(t, outbox.size, int64(400))
+	assert.Len(t, handledErrors, 10-depth)
+
+	require.ErrorContains(t, outbox.Publish(types.MessageEnvelope{Payload: make([]byte, 400)}, "edgex/events"), "exceeds the outbox MaxSize")
+
+	aged, err := WithOutbox(client, OutboxOptions{Directory: t.TempDir(), MaxAge: 10 * time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, aged.Publish(types.MessageEnvelope{}, "edgex/events"))
+	time.Sleep(20 * time.Millisecond)
+
+	client.offline.Store(false)
+	require.NoError(t, aged.Replay())
+	assert.Equal(t, 0, aged.Depth())
+
+	_, err = WithOutbox(client, OutboxOptions{})
+	require.Error(t, err)
+}
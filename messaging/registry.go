@@ -0,0 +1,81 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// MQTT messaging implementation
+	MQTT = "mqtt"
+
+	// Redis Pub/Sub messaging implementation
+	Redis = "redis"
+
+	// NatsCore implementation, only registered when building with the include_nats_messaging flag
+	NatsCore = "nats-core"
+
+	// NatsJetStream implementation, only registered when building with the include_nats_messaging flag
+	NatsJetStream = "nats-jetstream"
+
+	// Memory in-process implementation, which doesn't need a broker, registered by importing messaging/memory
+	Memory = "memory"
+)
+
+// ClientFactory creates the MessageClient of a message bus Type from the configuration.
+type ClientFactory func(config types.MessageBusConfig) (MessageClient, error)
+
+var (
+	factories      = make(map[string]ClientFactory)
+	factoriesMutex sync.RWMutex
+)
+
+// Register makes a MessageClient implementation available to NewMessageClient for the specified Type. The Type is
+// matched case-insensitively. Register is meant to be called from the init function of the package implementing the
+// client and panics if the Type is empty, the factory is nil or the Type is already registered.
+func Register(typeName string, factory ClientFactory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if typeName == "" {
+		panic("messaging: Register with empty message bus type")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("messaging: Register factory is nil for message bus type '%s'", typeName))
+	}
+
+	lowerTypeName := strings.ToLower(typeName)
+	if _, exists := factories[lowerTypeName]; exists {
+		panic(fmt.Sprintf("messaging: Register called twice for message bus type '%s'", typeName))
+	}
+
+	factories[lowerTypeName] = factory
+}
+
+// RegisteredTypes returns the sorted list of the message bus Types which have been registered.
+func RegisteredTypes() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	typeNames := make([]string, 0, len(factories))
+	for typeName := range factories {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	return typeNames
+}
+
+func lookupFactory(typeName string) (ClientFactory, bool) {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	factory, exists := factories[strings.ToLower(typeName)]
+	return factory, exists
+}
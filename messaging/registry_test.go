@@ -0,0 +1,45 @@
+// Copyright (C) 2024 IOTech Ltd
+
+//go:build !no_messagebus
+
+package messaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/memory"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func TestRegister(t *testing.T) {
+	var created types.MessageBusConfig
+	Register("Test-Registry", func(config types.MessageBusConfig) (MessageClient, error) {
+		created = config
+		return memory.NewClient(config)
+	})
+	t.Cleanup(func() {
+		factoriesMutex.Lock()
+		delete(factories, "test-registry")
+		factoriesMutex.Unlock()
+	})
+
+	assert.Contains(t, RegisteredTypes(), "test-registry")
+	assert.IsIncreasing(t, RegisteredTypes())
+
+	config := types.MessageBusConfig{Type: "TEST-registry", Optional: map[string]string{"Key": "Value"}}
+	client, err := NewMessageClient(config)
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+	assert.Equal(t, config, created)
+
+	assert.Panics(t, func() {
+		Register("test-registry", func(config types.MessageBusConfig) (MessageClient, error) { return nil, nil })
+	}, "registering the same type twice must panic")
+	assert.Panics(t, func() { Register("nil-factory", nil) })
+	assert.Panics(t, func() {
+		Register("", func(config types.MessageBusConfig) (MessageClient, error) { return nil, nil })
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	commonDTO "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Request is a request received by a Responder.
+type Request struct {
+	types.MessageEnvelope
+	// Params are the levels of the request topic matched by the "+" wildcards of the handler topic, in order. The
+	// levels matched by a trailing "#" wildcard are the last param, joined by "/".
+	Params []string
+}
+
+// Response is the response returned by a RequestHandler.
+type Response struct {
+	// Payload is the payload of the response
+	Payload []byte
+	// ContentType is the content type of the payload, the ContentType of the request is used when empty
+	ContentType string
+}
+
+// RequestHandler handles a request received by a Responder. The error returned is sent to the requester as the
+// payload of a response with ErrorCode 1.
+type RequestHandler func(request Request) (Response, error)
+
+// ResponderOptions defines how a Responder invokes the handlers.
+type ResponderOptions struct {
+	// Concurrency is the number of requests each handler handles concurrently. Values lower than 2 handle the
+	// requests serially in the order they are received.
+	Concurrency int
+	// ErrorHandler receives the errors which can't be sent to the requester, i.e. publishing the response failed, as
+	// well as the panics recovered from the handlers. Errors are discarded when not set.
+	ErrorHandler func(err error)
+}
+
+// Responder answers the requests sent with Request on the topics it has handlers for. The response is published
+// to <responseTopicPrefix>/<RequestID>.
+type Responder struct {
+	client              MessageClient
+	responseTopicPrefix string
+	options             ResponderOptions
+	subscriptions       []types.Subscription
+	closed              bool
+	mutex               sync.Mutex
+}
+
+// NewResponder creates a Responder which receives the requests and publishes the responses with the client.
+func NewResponder(client MessageClient, responseTopicPrefix string, options ResponderOptions) *Responder {
+	return &Responder{
+		client:              client,
+		responseTopicPrefix: responseTopicPrefix,
+		options:             options,
+	}
+}
+
+// Handle subscribes to the topic and invokes the handler for every request received on it. The topic may contain "+"
+// and "#" wildcards, the levels they match are passed in the Params of the request. A request matching the topics of
+// several handlers is handled, and responded to, by each of them.
+func (r *Responder) Handle(topic string, handler RequestHandler) error {
+	if handler == nil {
+		return fmt.Errorf("unable to handle requests on topic '%s': handler must be specified", topic)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return fmt.Errorf("unable to handle requests on topic '%s': responder is closed", topic)
+	}
+
+	subscription, err := wrappedClient{r.client}.SubscribeFunc(topic, func(request types.MessageEnvelope) error {
+		return r.respond(topic, handler, request)
+	}, types.SubscribeOptions{Concurrency: r.options.Concurrency, ErrorHandler: r.options.ErrorHandler})
+	if err != nil {
+		return fmt.Errorf("unable to subscribe to request topic '%s': %w", topic, err)
+	}
+
+	r.subscriptions = append(r.subscriptions, subscription)
+
+	return nil
+}
+
+// Close unsubscribes the request topics of all the handlers. Requests being handled are still responded to.
+func (r *Responder) Close() error {
+	r.mutex.Lock()
+	subscriptions := r.subscriptions
+	r.subscriptions = nil
+	r.closed = true
+	r.mutex.Unlock()
+
+	var errs []error
+	for _, subscription := range subscriptions {
+		if err := subscription.Unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// respond invokes the handler for the request and publishes its response.
+func (r *Responder) respond(topic string, handler RequestHandler, request types.MessageEnvelope) error {
+	if request.RequestID == "" {
+		return fmt.Errorf("unable to respond to request received on topic '%s': RequestID is empty", request.ReceivedTopic)
+	}
+
+	response, err := invokeHandler(handler, Request{MessageEnvelope: request, Params: pkg.TopicParams(topic, request.ReceivedTopic)})
+
+	var panicErr *handlerPanicError
+	var envelope types.MessageEnvelope
+	if errors.As(err, &panicErr) {
+		// Don't disclose the stack of the panic to the requester
+		envelope = types.NewMessageEnvelopeWithError(request.RequestID, fmt.Sprintf("handler panicked: %v", panicErr.value))
+	} else if err != nil {
+		envelope = types.NewMessageEnvelopeWithError(request.RequestID, err.Error())
+	} else {
+		envelope = types.MessageEnvelope{
+			Versionable: commonDTO.NewVersionable(),
+			RequestID:   request.RequestID,
+			Payload:     response.Payload,
+			ContentType: responseContentType(response.ContentType, request.ContentType),
+			QueryParams: make(map[string]string),
+		}
+	}
+
+	if request.CorrelationID != "" {
+		envelope.CorrelationID = request.CorrelationID
+	}
+
+	responseTopic := strings.Join([]string{r.responseTopicPrefix, request.RequestID}, "/")
+	if publishErr := r.client.Publish(envelope, responseTopic); publishErr != nil {
+		return fmt.Errorf("unable to publish response to %s: %w", responseTopic, publishErr)
+	}
+
+	if panicErr != nil {
+		return panicErr
+	}
+
+	return nil
+}
+
+// handlerPanicError is the error of a handler which panicked.
+type handlerPanicError struct {
+	topic string
+	value any
+	stack []byte
+}
+
+func (e *handlerPanicError) Error() string {
+	return fmt.Sprintf("handler for request received on topic '%s' panicked: %v\n%s", e.topic, e.value, e.stack)
+}
+
+// invokeHandler invokes the handler, recovering from a panic as an error.
+func invokeHandler(handler RequestHandler, request Request) (response Response, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &handlerPanicError{topic: request.ReceivedTopic, value: value, stack: debug.Stack()}
+		}
+	}()
+
+	return handler(request)
+}
+
+func responseContentType(responseContentType string, requestContentType string) string {
+	if responseContentType != "" {
+		return responseContentType
+	}
+
+	if requestContentType != "" {
+		return requestContentType
+	}
+
+	return common.ContentTypeJSON
+}
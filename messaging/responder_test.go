@@ -0,0 +1,129 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func TestResponder(t *testing.T) {
+	client := newMemoryClient(t)
+
+	responder := NewResponder(client, "edgex/response", ResponderOptions{})
+	defer func() { _ = responder.Close() }()
+
+	err := responder.Handle("edgex/request/+/+/get", func(request Request) (Response, error) {
+		if request.Params[0] == "unknown" {
+			return Response{}, errors.New("device not found")
+		}
+		return Response{Payload: []byte(request.Params[0] + ":" + request.Params[1])}, nil
+	})
+	require.NoError(t, err)
+
+	request := types.NewMessageEnvelopeForRequest(nil, nil)
+	response, err := client.Request(request, "edgex/request/device/command/get", "edgex/response", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 0, response.ErrorCode)
+	assert.Equal(t, "device:command", string(response.Payload))
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, request.CorrelationID, response.CorrelationID)
+	assert.Equal(t, common.ContentTypeJSON, response.ContentType)
+
+	response, err = client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request/unknown/command/get", "edgex/response", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.ErrorCode)
+	assert.Equal(t, "device not found", string(response.Payload))
+
+	require.Error(t, responder.Handle("edgex/request/#", nil))
+}
+
+func TestResponderPanic(t *testing.T) {
+	client := newMemoryClient(t)
+
+	handlerErrors := make(chan error, 1)
+	responder := NewResponder(client, "edgex/response", ResponderOptions{ErrorHandler: func(err error) { handlerErrors <- err }})
+	defer func() { _ = responder.Close() }()
+
+	require.NoError(t, responder.Handle("edgex/request", func(request Request) (Response, error) {
+		panic("boom")
+	}))
+
+	response, err := client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request", "edgex/response", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.ErrorCode)
+	assert.Equal(t, "handler panicked: boom", string(response.Payload), "the stack must not be sent to the requester")
+
+	select {
+	case err := <-handlerErrors:
+		assert.ErrorContains(t, err, "panicked: boom")
+		assert.ErrorContains(t, err, "goroutine", "the stack must be passed to the ErrorHandler")
+	case <-time.After(time.Second):
+		require.Fail(t, "panic not passed to the ErrorHandler")
+	}
+
+	// The responder keeps handling requests after a panic
+	_, err = client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request", "edgex/response", time.Second)
+	require.NoError(t, err)
+}
+
+func TestResponderConcurrency(t *testing.T) {
+	client := newMemoryClient(t)
+
+	responder := NewResponder(client, "edgex/response", ResponderOptions{Concurrency: 2})
+	defer func() { _ = responder.Close() }()
+
+	var active, maxActive atomic.Int32
+	require.NoError(t, responder.Handle("edgex/request", func(request Request) (Response, error) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			previous := maxActive.Load()
+			if current <= previous || maxActive.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return Response{ContentType: common.ContentTypeText}, nil
+	}))
+
+	responses := make(chan *types.MessageEnvelope, 6)
+	for i := 0; i < 6; i++ {
+		go func() {
+			response, _ := client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request", "edgex/response", time.Second)
+			responses <- response
+		}()
+	}
+	for i := 0; i < 6; i++ {
+		response := <-responses
+		require.NotNil(t, response)
+		assert.Equal(t, common.ContentTypeText, response.ContentType)
+	}
+
+	assert.Equal(t, int32(2), maxActive.Load())
+}
+
+func TestResponderClose(t *testing.T) {
+	client := newMemoryClient(t)
+
+	responder := NewResponder(client, "edgex/response", ResponderOptions{})
+	require.NoError(t, responder.Handle("edgex/request", func(request Request) (Response, error) {
+		return Response{}, nil
+	}))
+	require.NoError(t, responder.Close())
+
+	_, err := client.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/request", "edgex/response", 50*time.Millisecond)
+	require.Error(t, err, "no response expected once the responder is closed")
+
+	require.Error(t, responder.Handle("edgex/request", func(request Request) (Response, error) {
+		return Response{}, nil
+	}))
+}
@@ -0,0 +1,224 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// DefaultRetryBaseBackoff is the backoff before the first retry when not specified
+	DefaultRetryBaseBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxBackoff is the maximum backoff between two attempts when not specified
+	DefaultRetryMaxBackoff = 10 * time.Second
+)
+
+// RetryableError is implemented by the errors which tell whether the failed operation may succeed once retried, i.e.
+// the errors caused by the broker being unreachable. A request timing out isn't retryable, as it may have been handled.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
+// IsRetryable reports whether err, or an error it wraps, is a RetryableError for which the operation may succeed once
+// retried. Errors which don't implement RetryableError aren't retryable.
+func IsRetryable(err error) bool {
+	var retryableErr RetryableError
+	return errors.As(err, &retryableErr) && retryableErr.Retryable()
+}
+
+// RetryPolicy defines how many times and how often a failed operation is attempted again.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. A MaxAttempts of 1 disables the retries.
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry, which doubles for every following retry.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the backoff between two attempts.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of the backoff which is randomly removed from it, so the clients which
+	// failed at the same time don't retry all at once.
+	Jitter float64
+	// Retryable classifies the errors which are retried, IsRetryable when not set.
+	Retryable func(err error) bool
+}
+
+// retryPolicyConfig is the retry policy configuration loaded from the Optional properties of the MessageBusConfig.
+type retryPolicyConfig struct {
+	RetryMaxAttempts int
+	RetryBaseBackoff time.Duration
+	RetryMaxBackoff  time.Duration
+	RetryJitter      float64
+}
+
+// NewRetryPolicy creates the RetryPolicy from the Optional properties of the MessageBusConfig, i.e. RetryMaxAttempts,
+// RetryBaseBackoff, RetryMaxBackoff and RetryJitter. The retries are disabled unless RetryMaxAttempts is specified.
+func NewRetryPolicy(optional map[string]string) (RetryPolicy, error) {
+	config := retryPolicyConfig{
+		RetryMaxAttempts: 1,
+		RetryBaseBackoff: DefaultRetryBaseBackoff,
+		RetryMaxBackoff:  DefaultRetryMaxBackoff,
+	}
+	if err := pkg.Load(optional, &config); err != nil {
+		return RetryPolicy{}, fmt.Errorf("unable to load retry policy configuration: %w", err)
+	}
+
+	policy := RetryPolicy{
+		MaxAttempts: config.RetryMaxAttempts,
+		BaseBackoff: config.RetryBaseBackoff,
+		MaxBackoff:  config.RetryMaxBackoff,
+		Jitter:      config.RetryJitter,
+	}
+	if err := policy.validate(); err != nil {
+		return RetryPolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func (p RetryPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("invalid retry policy: %s must be at least 1", pkg.RetryMaxAttempts)
+	case p.BaseBackoff < 0:
+		return fmt.Errorf("invalid retry policy: %s must not be negative", pkg.RetryBaseBackoff)
+	case p.MaxBackoff < p.BaseBackoff:
+		return fmt.Errorf("invalid retry policy: %s must not be less than %s", pkg.RetryMaxBackoff, pkg.RetryBaseBackoff)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("invalid retry policy: %s must be between 0 and 1", pkg.RetryJitter)
+	}
+
+	return nil
+}
+
+// Backoff returns the time to wait after the failed attempt, attempts being counted from 1. The backoff grows
+// exponentially from BaseBackoff up to MaxBackoff, minus a random part of up to Jitter of it.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	if jitter := int64(float64(backoff) * p.Jitter); jitter > 0 {
+		backoff -= time.Duration(rand.Int64N(jitter + 1))
+	}
+
+	return backoff
+}
+
+// Do invokes the operation until it succeeds, fails with an error which isn't retryable or MaxAttempts is reached, and
+// returns the error of the last attempt. The error of ctx is returned if it is done while waiting between attempts, the
+// operation isn't attempted again once ctx is done.
+func (p RetryPolicy) Do(ctx context.Context, operation func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithRetry wraps the client so that Publish, PublishBinaryData and Request are retried according to the policy when
+// they fail with a retryable error. A request is only sent again if it couldn't be published, not once it timed out
+// waiting for its response, as it may have been handled already. Every attempt of a Request waits for the response for
+// the full timeout, while the attempts of RequestWithContext share the deadline of the context. The other operations
+// aren't retried.
+//
+// NewMessageClient wraps the clients it creates with the RetryPolicy of the configuration when RetryMaxAttempts is
+// greater than 1.
+func WithRetry(client MessageClient, policy RetryPolicy) MessageClient {
+	return &retryClient{wrappedClient: wrappedClient{client}, policy: policy}
+}
+
+// retryClient is the MessageClient returned by WithRetry.
+type retryClient struct {
+	wrappedClient
+	policy RetryPolicy
+}
+
+func (c *retryClient) Publish(message types.MessageEnvelope, topic string) error {
+	return c.policy.Do(context.Background(), func() error {
+		return c.MessageClient.Publish(message, topic)
+	})
+}
+
+func (c *retryClient) PublishWithContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	return c.policy.Do(ctx, func() error {
+		if contextClient, ok := c.MessageClient.(MessageClientWithContext); ok {
+			return contextClient.PublishWithContext(ctx, message, topic)
+		}
+
+		return pkg.RunWithContext(ctx, func() error {
+			return c.MessageClient.Publish(message, topic)
+		})
+	})
+}
+
+func (c *retryClient) PublishBinaryData(data []byte, topic string) error {
+	return c.policy.Do(context.Background(), func() error {
+		return c.MessageClient.PublishBinaryData(data, topic)
+	})
+}
+
+func (c *retryClient) SubscribeWithContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if contextClient, ok := c.MessageClient.(MessageClientWithContext); ok {
+		return contextClient.SubscribeWithContext(ctx, topics, messageErrors)
+	}
+
+	return pkg.SubscribeWithContext(ctx, c.SubscribeWithHandle, topics, messageErrors)
+}
+
+func (c *retryClient) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	var response *types.MessageEnvelope
+	err := c.policy.Do(context.Background(), func() error {
+		var err error
+		response, err = c.MessageClient.Request(message, requestTopic, responseTopicPrefix, timeout)
+		return err
+	})
+
+	return response, err
+}
+
+func (c *retryClient) RequestWithContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	var response *types.MessageEnvelope
+	err := c.policy.Do(ctx, func() error {
+		var err error
+		if contextClient, ok := c.MessageClient.(MessageClientWithContext); ok {
+			response, err = contextClient.RequestWithContext(ctx, message, requestTopic, responseTopicPrefix)
+		} else {
+			response, err = c.MessageClient.Request(message, requestTopic, responseTopicPrefix, timeoutFromContext(ctx))
+		}
+		return err
+	})
+
+	return response, err
+}
+
+var (
+	_ MessageClientWithContext         = (*retryClient)(nil)
+	_ MessageClientWithHandle          = (*retryClient)(nil)
+	_ MessageClientWithRequestMany     = (*retryClient)(nil)
+	_ MessageClientWithConnectionState = (*retryClient)(nil)
+)
@@ -0,0 +1,201 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// failingClient is a MessageClient whose publishes and requests fail with err the specified number of times.
+type failingClient struct {
+	MessageClient
+	failures atomic.Int32
+	attempts atomic.Int32
+	err      error
+}
+
+func (c *failingClient) fail() error {
+	c.attempts.Add(1)
+	if c.failures.Add(-1) >= 0 {
+		return c.err
+	}
+	return nil
+}
+
+func (c *failingClient) Publish(message types.MessageEnvelope, topic string) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.MessageClient.Publish(message, topic)
+}
+
+func (c *failingClient) PublishBinaryData(data []byte, topic string) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.MessageClient.PublishBinaryData(data, topic)
+}
+
+func (c *failingClient) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	if err := c.fail(); err != nil {
+		return nil, err
+	}
+	return c.MessageClient.Request(message, requestTopic, responseTopicPrefix, timeout)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(pkg.NewConnectionErr(errors.New("connection refused"))))
+	assert.False(t, IsRetryable(fmt.Errorf("wrapped: %w", pkg.NewRequestTimeoutErr("edgex/response"))), "a timed out request may have been handled")
+	assert.True(t, errors.Is(pkg.NewRequestTimeoutErr("edgex/response"), context.DeadlineExceeded))
+	assert.False(t, IsRetryable(pkg.NewInvalidTopicErr("#", "invalid")))
+	assert.False(t, IsRetryable(pkg.NewMissingConfigurationErr("ClientId", "missing")))
+	assert.False(t, IsRetryable(errors.New("unknown")))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy, err := NewRetryPolicy(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, policy.MaxAttempts)
+	assert.Equal(t, DefaultRetryBaseBackoff, policy.BaseBackoff)
+	assert.Equal(t, DefaultRetryMaxBackoff, policy.MaxBackoff)
+
+	policy, err = NewRetryPolicy(map[string]string{
+		pkg.RetryMaxAttempts: "5",
+		pkg.RetryBaseBackoff: "50ms",
+		pkg.RetryMaxBackoff:  "2s",
+		pkg.RetryJitter:      "0.2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, RetryPolicy{MaxAttempts: 5, BaseBackoff: 50 * time.Millisecond, MaxBackoff: 2 * time.Second, Jitter: 0.2}, policy)
+
+	tests := []struct {
+		name     string
+		optional map[string]string
+	}{
+		{"invalid duration", map[string]string{pkg.RetryBaseBackoff: "50"}},
+		{"invalid jitter", map[string]string{pkg.RetryJitter: "high"}},
+		{"no attempts", map[string]string{pkg.RetryMaxAttempts: "0"}},
+		{"jitter out of range", map[string]string{pkg.RetryJitter: "1.5"}},
+		{"max less than base", map[string]string{pkg.RetryBaseBackoff: "2s", pkg.RetryMaxBackoff: "1s"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewRetryPolicy(test.optional)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, policy.Backoff(4))
+	assert.Equal(t, time.Second, policy.Backoff(5))
+	assert.Equal(t, time.Second, policy.Backoff(100))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(2)
+		assert.GreaterOrEqual(t, backoff, 100*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 200*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	connectionErr := pkg.NewConnectionErr(errors.New("connection refused"))
+
+	attempts := 0
+	err := policy.Do(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return connectionErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = policy.Do(context.Background(), func() error {
+		attempts++
+		return connectionErr
+	})
+	require.ErrorIs(t, err, connectionErr)
+	assert.Equal(t, 3, attempts, "the attempts must stop once MaxAttempts is reached")
+
+	attempts = 0
+	err = policy.Do(context.Background(), func() error {
+		attempts++
+		return errors.New("invalid message")
+	})
+	require.EqualError(t, err, "invalid message")
+	assert.Equal(t, 1, attempts, "errors which aren't retryable must not be retried")
+
+	policy.Retryable = func(err error) bool { return true }
+	attempts = 0
+	_ = policy.Do(context.Background(), func() error {
+		attempts++
+		return errors.New("invalid message")
+	})
+	assert.Equal(t, 3, attempts, "the classifier of the policy must be used")
+
+	policy = RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Hour, MaxBackoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = policy.Do(ctx, func() error { return connectionErr })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithRetry(t *testing.T) {
+	memoryClient := newMemoryClient(t)
+	client := &failingClient{MessageClient: memoryClient, err: pkg.NewConnectionErr(errors.New("connection refused"))}
+	retryClient := WithRetry(client, RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, memoryClient.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	client.failures.Store(2)
+	require.NoError(t, retryClient.Publish(types.MessageEnvelope{Payload: []byte("1")}, "edgex/events"))
+	assert.Equal(t, int32(3), client.attempts.Load())
+
+	client.failures.Store(3)
+	client.attempts.Store(0)
+	require.ErrorIs(t, retryClient.PublishBinaryData([]byte("2"), "edgex/events"), client.err)
+	assert.Equal(t, int32(3), client.attempts.Load())
+
+	client.failures.Store(1)
+	require.NoError(t, retryClient.(MessageClientWithContext).PublishWithContext(context.Background(), types.MessageEnvelope{Payload: []byte("3")}, "edgex/events"))
+	assert.Equal(t, []string{"1", "3"}, receiveAll(messages, 100*time.Millisecond))
+
+	responder := NewResponder(memoryClient, "edgex/responses", ResponderOptions{})
+	defer func() { _ = responder.Close() }()
+	require.NoError(t, responder.Handle("edgex/requests", func(request Request) (Response, error) {
+		return Response{Payload: []byte("response")}, nil
+	}))
+
+	client.failures.Store(2)
+	response, err := retryClient.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/requests", "edgex/responses", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "response", string(response.Payload))
+
+	// A request which timed out isn't sent again
+	requests := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, memoryClient.Subscribe([]types.TopicChannel{{Topic: "edgex/commands", Messages: requests}}, make(chan error)))
+	_, err = retryClient.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/commands", "edgex/responses", 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, receiveAll(requests, 100*time.Millisecond), 1)
+}
@@ -0,0 +1,698 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	commonDTO "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/google/uuid"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// DefaultStreamChunkSize is the size in bytes of the chunks of the published streams when not specified
+	DefaultStreamChunkSize = 64 * 1024
+	// DefaultStreamWindow is the number of chunks published ahead of the acknowledgements when not specified
+	DefaultStreamWindow = 8
+	// DefaultStreamAckTimeout is the time waited for an acknowledgement before retransmitting when not specified
+	DefaultStreamAckTimeout = 5 * time.Second
+	// DefaultStreamMaxRetransmits is the number of retransmissions without acknowledgement when not specified
+	DefaultStreamMaxRetransmits = 3
+	// DefaultStreamIdleTimeout is the time a received stream waits for its next chunk when not specified
+	DefaultStreamIdleTimeout = 30 * time.Second
+	// DefaultStreamAckTopicPrefix is the prefix of the topics the acknowledgements are published to when not specified
+	DefaultStreamAckTopicPrefix = "edgex/stream/ack"
+
+	streamIdHeader       = "X-Edgex-Stream-Id"
+	streamSequenceHeader = "X-Edgex-Stream-Sequence"
+	streamAckTopicHeader = "X-Edgex-Stream-Ack-Topic"
+	streamEndHeader      = "X-Edgex-Stream-End"
+	streamErrorHeader    = "X-Edgex-Stream-Error"
+	streamAckHeader      = "X-Edgex-Stream-Ack"
+	streamCanceledHeader = "X-Edgex-Stream-Canceled"
+
+	streamContentType = "application/octet-stream"
+)
+
+var (
+	// ErrStreamCanceled is the error of a stream canceled by the receiver, i.e. closed before being read to the end
+	ErrStreamCanceled = errors.New("stream canceled by the receiver")
+	// ErrStreamAborted is the error of a stream aborted by the publisher, i.e. its reader failed
+	ErrStreamAborted = errors.New("stream aborted by the publisher")
+	// ErrStreamTimeout is the error of a stream whose chunks or acknowledgements stopped arriving
+	ErrStreamTimeout = errors.New("stream timed out")
+	// ErrStreamClosed is the error of a stream read once closed or unsubscribed
+	ErrStreamClosed = errors.New("stream closed")
+)
+
+// StreamOptions defines how a Streamer splits the streams and controls their flow.
+type StreamOptions struct {
+	// ChunkSize is the size in bytes of the chunks the published streams are split into, DefaultStreamChunkSize when
+	// not specified. It must leave room for the envelope within the maximum message size of the broker.
+	ChunkSize int
+	// Window is the number of chunks published without being acknowledged by the receiver, DefaultStreamWindow when
+	// not specified. The receiver buffers up to Window chunks per stream, which bounds its memory usage.
+	Window int
+	// AckTimeout is the time waited for an acknowledgement before the unacknowledged chunks are retransmitted,
+	// DefaultStreamAckTimeout when not specified.
+	AckTimeout time.Duration
+	// MaxRetransmits is the number of retransmissions without any acknowledgement after which publishing fails,
+	// DefaultStreamMaxRetransmits when not specified.
+	MaxRetransmits int
+	// IdleTimeout is the time a received stream waits for its next chunk, or to be read, before failing,
+	// DefaultStreamIdleTimeout when not specified.
+	IdleTimeout time.Duration
+	// AckTopicPrefix is the prefix of the topics the acknowledgements are published to, <AckTopicPrefix>/<stream id>,
+	// DefaultStreamAckTopicPrefix when not specified.
+	AckTopicPrefix string
+	// ErrorHandler receives the errors of the received streams which can't be returned by a Read, i.e. an invalid
+	// chunk or an acknowledgement which couldn't be published. Errors are discarded when not set.
+	ErrorHandler func(err error)
+}
+
+// Streamer publishes and receives streams of data too large to be held in a single message, i.e. files, over the
+// connection of the client. A stream is split into chunks which are acknowledged by the receiver once read, and the
+// publisher only publishes Window chunks ahead of the acknowledgements, so neither side holds more than Window chunks
+// in memory and the publisher is slowed down to the pace of the reader. The lost chunks are retransmitted.
+type Streamer struct {
+	client  MessageClient
+	options StreamOptions
+}
+
+// NewStreamer creates a Streamer which publishes and receives the streams with the client.
+func NewStreamer(client MessageClient, options StreamOptions) *Streamer {
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = DefaultStreamChunkSize
+	}
+	if options.Window <= 0 {
+		options.Window = DefaultStreamWindow
+	}
+	if options.AckTimeout <= 0 {
+		options.AckTimeout = DefaultStreamAckTimeout
+	}
+	if options.MaxRetransmits <= 0 {
+		options.MaxRetransmits = DefaultStreamMaxRetransmits
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = DefaultStreamIdleTimeout
+	}
+	if options.AckTopicPrefix == "" {
+		options.AckTopicPrefix = DefaultStreamAckTopicPrefix
+	}
+
+	return &Streamer{client: client, options: options}
+}
+
+// PublishStream publishes the data of the reader to the topic until it returns io.EOF, along with the metadata which
+// is passed to the receiver before the data. Returns once the receiver acknowledged the whole stream, or with
+// ErrStreamCanceled if the receiver canceled it. The receiver is notified when publishing fails because the reader
+// failed or the acknowledgements stopped arriving.
+func (s *Streamer) PublishStream(topic string, reader io.Reader, metadata map[string]string) error {
+	if reader == nil {
+		return fmt.Errorf("unable to publish stream to topic '%s': reader must be specified", topic)
+	}
+
+	streamID := uuid.NewString()
+	ackTopic := s.options.AckTopicPrefix + "/" + streamID
+	acks := &streamAcks{notify: make(chan struct{}, 1)}
+
+	subscription, err := wrappedClient{s.client}.SubscribeFunc(ackTopic, acks.receive, types.SubscribeOptions{ErrorHandler: s.options.ErrorHandler})
+	if err != nil {
+		return fmt.Errorf("unable to subscribe to stream acknowledgement topic '%s': %w", ackTopic, err)
+	}
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	// pending are the chunks published but not acknowledged yet, from the sequence acked to next
+	var pending []types.MessageEnvelope
+	var acked, next uint64
+	ended := false
+	retransmits := 0
+
+	timeout := time.NewTimer(s.options.AckTimeout)
+	defer timeout.Stop()
+
+	for {
+		for !ended && next < acked+uint64(s.options.Window) {
+			chunk, err := s.readChunk(reader, streamID, next)
+			if err != nil {
+				s.abort(topic, streamID, next, err)
+				return fmt.Errorf("unable to publish stream to topic '%s': %w", topic, err)
+			}
+
+			if next == 0 {
+				chunk.QueryParams = maps.Clone(metadata)
+			}
+			chunk.Headers[streamAckTopicHeader] = []string{ackTopic}
+			ended = len(chunk.Headers[streamEndHeader]) > 0
+
+			if err := s.client.Publish(chunk, topic); err != nil {
+				return fmt.Errorf("unable to publish stream chunk %d to topic '%s': %w", next, topic, err)
+			}
+
+			pending = append(pending, chunk)
+			next++
+		}
+
+		if ended && acked == next {
+			return nil
+		}
+
+		timeout.Reset(s.options.AckTimeout)
+		select {
+		case <-acks.notify:
+			progress, canceled := acks.state()
+			if canceled {
+				return fmt.Errorf("unable to publish stream to topic '%s': %w", topic, ErrStreamCanceled)
+			}
+
+			progress = min(progress, next)
+			if progress > acked {
+				pending = pending[progress-acked:]
+				acked = progress
+			}
+			retransmits = 0
+
+		case <-timeout.C:
+			retransmits++
+			if retransmits > s.options.MaxRetransmits {
+				err := fmt.Errorf("%w: no acknowledgement received after %d retransmissions", ErrStreamTimeout, s.options.MaxRetransmits)
+				s.abort(topic, streamID, next, err)
+				return fmt.Errorf("unable to publish stream to topic '%s': %w", topic, err)
+			}
+
+			for _, chunk := range pending {
+				if err := s.client.Publish(chunk, topic); err != nil {
+					return fmt.Errorf("unable to retransmit stream chunk %s to topic '%s': %w", chunk.Headers[streamSequenceHeader][0], topic, err)
+				}
+			}
+		}
+	}
+}
+
+// readChunk reads the chunk of the stream with the sequence from the reader. The chunk read once the reader returned
+// io.EOF is the end of the stream, which may be empty.
+func (s *Streamer) readChunk(reader io.Reader, streamID string, sequence uint64) (types.MessageEnvelope, error) {
+	payload := make([]byte, s.options.ChunkSize)
+	n, err := io.ReadFull(reader, payload)
+
+	chunk := newStreamMessage(streamID, streamSequenceHeader, strconv.FormatUint(sequence, 10))
+	chunk.Payload = payload[:n]
+
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		chunk.Headers[streamEndHeader] = []string{"true"}
+	case err != nil:
+		return types.MessageEnvelope{}, fmt.Errorf("unable to read stream: %w", err)
+	}
+
+	return chunk, nil
+}
+
+// abort notifies the receiver of the stream that publishing failed with the error, on a best effort basis.
+func (s *Streamer) abort(topic string, streamID string, sequence uint64, err error) {
+	message := newStreamMessage(streamID, streamSequenceHeader, strconv.FormatUint(sequence, 10))
+	message.Headers[streamErrorHeader] = []string{err.Error()}
+
+	if publishErr := s.client.Publish(message, topic); publishErr != nil {
+		s.handleError(fmt.Errorf("unable to abort stream %s on topic '%s': %w", streamID, topic, publishErr))
+	}
+}
+
+func (s *Streamer) handleError(err error) {
+	if s.options.ErrorHandler != nil {
+		s.options.ErrorHandler(err)
+	}
+}
+
+// newStreamMessage creates a message of the stream with the header. Each message has its own CorrelationID, so that
+// the brokers deduplicating the messages by CorrelationID don't drop the chunks and acknowledgements.
+func newStreamMessage(streamID string, header string, value string) types.MessageEnvelope {
+	return types.MessageEnvelope{
+		Versionable:   commonDTO.NewVersionable(),
+		CorrelationID: uuid.NewString(),
+		ContentType:   streamContentType,
+		QueryParams:   make(map[string]string),
+		Headers: map[string][]string{
+			streamIdHeader: {streamID},
+			header:         {value},
+		},
+	}
+}
+
+// streamAcks collects the acknowledgements of a published stream.
+type streamAcks struct {
+	mutex sync.Mutex
+	// next is the sequence of the next chunk to be read by the receiver, all the previous chunks are acknowledged
+	next     uint64
+	canceled bool
+	notify   chan struct{}
+}
+
+func (a *streamAcks) receive(message types.MessageEnvelope) error {
+	a.mutex.Lock()
+	if len(message.Headers[streamCanceledHeader]) > 0 {
+		a.canceled = true
+	} else if values := message.Headers[streamAckHeader]; len(values) > 0 {
+		next, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			a.mutex.Unlock()
+			return fmt.Errorf("invalid stream acknowledgement received on topic '%s': %w", message.ReceivedTopic, err)
+		}
+		a.next = max(a.next, next)
+	}
+	a.mutex.Unlock()
+
+	// Every acknowledgement is notified, even without progress, as it shows the receiver is still reading
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (a *streamAcks) state() (uint64, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.next, a.canceled
+}
+
+// StreamSubscription receives the streams published to a topic.
+type StreamSubscription struct {
+	streamer     *Streamer
+	subscription types.Subscription
+	streams      chan *StreamReader
+	mutex        sync.Mutex
+	readers      map[string]*StreamReader
+	// finished are the streams read to the end or canceled, whose acknowledgement is published again if their
+	// chunks are retransmitted because it was lost
+	finished  map[string]finishedStream
+	closed    bool
+	done      chan struct{}
+	waitGroup sync.WaitGroup
+}
+
+// finishedStream is the last acknowledgement of a stream, kept until it expires.
+type finishedStream struct {
+	ackTopic string
+	ack      types.MessageEnvelope
+	expiry   time.Time
+}
+
+// streamAck is an acknowledgement to publish once the locks are released.
+type streamAck struct {
+	topic   string
+	message types.MessageEnvelope
+}
+
+// SubscribeStream subscribes to the streams published to the topic, which are received from Streams.
+func (s *Streamer) SubscribeStream(topic string) (*StreamSubscription, error) {
+	subscription := &StreamSubscription{
+		streamer: s,
+		streams:  make(chan *StreamReader),
+		readers:  make(map[string]*StreamReader),
+		finished: make(map[string]finishedStream),
+		done:     make(chan struct{}),
+	}
+
+	var err error
+	subscription.subscription, err = wrappedClient{s.client}.SubscribeFunc(topic, subscription.receive, types.SubscribeOptions{ErrorHandler: s.options.ErrorHandler})
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to stream topic '%s': %w", topic, err)
+	}
+
+	return subscription, nil
+}
+
+// Streams returns the channel receiving a StreamReader for every stream published to the topic, which is closed once
+// unsubscribed. Each StreamReader must be closed once read, and the streams not received within the IdleTimeout are
+// canceled.
+func (s *StreamSubscription) Streams() <-chan *StreamReader {
+	return s.streams
+}
+
+// Unsubscribe unsubscribes from the topic and cancels the streams not read to the end.
+func (s *StreamSubscription) Unsubscribe() error {
+	err := s.subscription.Unsubscribe()
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return err
+	}
+	s.closed = true
+	readers := s.readers
+	s.readers = nil
+	s.finished = nil
+	close(s.done)
+	s.mutex.Unlock()
+
+	for _, reader := range readers {
+		if reader.fail(ErrStreamClosed) {
+			s.publishAcks([]streamAck{reader.cancelAck()})
+		}
+	}
+
+	s.waitGroup.Wait()
+	close(s.streams)
+
+	return err
+}
+
+// receive adds the received chunk to the reader of its stream, creating it with the first chunk.
+func (s *StreamSubscription) receive(message types.MessageEnvelope) error {
+	streamID := streamHeader(message, streamIdHeader)
+	ackTopic := streamHeader(message, streamAckTopicHeader)
+	sequence, err := strconv.ParseUint(streamHeader(message, streamSequenceHeader), 10, 64)
+	if streamID == "" || err != nil {
+		return fmt.Errorf("invalid stream chunk received on topic '%s': stream id or sequence missing", message.ReceivedTopic)
+	}
+
+	now := time.Now()
+	acks, err := s.add(streamID, ackTopic, sequence, message, now)
+	s.publishAcks(acks)
+
+	return err
+}
+
+func (s *StreamSubscription) add(streamID string, ackTopic string, sequence uint64, message types.MessageEnvelope, now time.Time) ([]streamAck, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, nil
+	}
+
+	acks := s.expire(now)
+
+	reader, exists := s.readers[streamID]
+	if errorMessage := streamHeader(message, streamErrorHeader); errorMessage != "" {
+		if exists {
+			delete(s.readers, streamID)
+			reader.fail(fmt.Errorf("%w: %s", ErrStreamAborted, errorMessage))
+		}
+		return acks, nil
+	}
+
+	// The acknowledgements are only published to the topic of the stream, so a forged chunk can't have them published
+	// to another topic
+	if ackTopic != s.streamer.options.AckTopicPrefix+"/"+streamID {
+		return acks, fmt.Errorf("invalid stream chunk received on topic '%s': invalid acknowledgement topic '%s'", message.ReceivedTopic, ackTopic)
+	}
+
+	if !exists {
+		if finished, exists := s.finished[streamID]; exists {
+			return append(acks, streamAck{topic: finished.ackTopic, message: finished.ack}), nil
+		}
+		if sequence != 0 {
+			// The first chunk was lost, the publisher retransmits it along with the following ones
+			return acks, nil
+		}
+
+		reader = newStreamReader(s, streamID, ackTopic, message, now)
+		s.readers[streamID] = reader
+
+		s.waitGroup.Add(1)
+		go func() {
+			defer s.waitGroup.Done()
+			select {
+			case s.streams <- reader:
+			case <-s.done:
+			}
+		}()
+	}
+
+	if ack, duplicate := reader.add(sequence, message.Payload, streamHeader(message, streamEndHeader) != "", now); duplicate {
+		acks = append(acks, ack)
+	}
+
+	return acks, nil
+}
+
+// expire cancels the streams which weren't read nor received any chunk within the IdleTimeout, and forgets the
+// finished streams once expired. Must be called with the mutex locked.
+func (s *StreamSubscription) expire(now time.Time) []streamAck {
+	var acks []streamAck
+	for streamID, reader := range s.readers {
+		if reader.idle(now) {
+			delete(s.readers, streamID)
+			if reader.fail(fmt.Errorf("%w: no chunk received or read for %s", ErrStreamTimeout, s.streamer.options.IdleTimeout)) {
+				ack := reader.cancelAck()
+				s.finished[streamID] = finishedStream{ackTopic: ack.topic, ack: ack.message, expiry: now.Add(s.streamer.options.IdleTimeout)}
+				acks = append(acks, ack)
+			}
+		}
+	}
+
+	for streamID, finished := range s.finished {
+		if now.After(finished.expiry) {
+			delete(s.finished, streamID)
+		}
+	}
+
+	return acks
+}
+
+// finish removes the reader of a stream read to the end or canceled, keeping its last acknowledgement in case the
+// publisher didn't receive it, and publishes it.
+func (s *StreamSubscription) finish(reader *StreamReader, ack streamAck) {
+	s.mutex.Lock()
+	if !s.closed && s.readers[reader.id] == reader {
+		delete(s.readers, reader.id)
+		s.finished[reader.id] = finishedStream{ackTopic: ack.topic, ack: ack.message, expiry: time.Now().Add(s.streamer.options.IdleTimeout)}
+	}
+	s.mutex.Unlock()
+
+	s.publishAcks([]streamAck{ack})
+}
+
+func (s *StreamSubscription) publishAcks(acks []streamAck) {
+	for _, ack := range acks {
+		if err := s.streamer.client.Publish(ack.message, ack.topic); err != nil {
+			s.streamer.handleError(fmt.Errorf("unable to publish stream acknowledgement to topic '%s': %w", ack.topic, err))
+		}
+	}
+}
+
+// StreamReader reads a received stream. The chunks are acknowledged once read, so the publisher only publishes the
+// following ones as fast as they are read.
+type StreamReader struct {
+	subscription *StreamSubscription
+	id           string
+	topic        string
+	ackTopic     string
+	metadata     map[string]string
+	mutex        sync.Mutex
+	// chunks are the chunks received but not read yet, from the sequence next up to Window chunks ahead
+	chunks map[uint64][]byte
+	next   uint64
+	// end is the sequence of the last chunk once received
+	end   uint64
+	ended bool
+	// err is returned by Read once the chunks are read, io.EOF once the stream is read to the end
+	err          error
+	closed       bool
+	lastActivity time.Time
+	notify       chan struct{}
+}
+
+func newStreamReader(subscription *StreamSubscription, streamID string, ackTopic string, first types.MessageEnvelope, now time.Time) *StreamReader {
+	return &StreamReader{
+		subscription: subscription,
+		id:           streamID,
+		topic:        first.ReceivedTopic,
+		ackTopic:     ackTopic,
+		metadata:     first.QueryParams,
+		chunks:       make(map[uint64][]byte),
+		lastActivity: now,
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// ID returns the id of the stream, which is unique per published stream.
+func (r *StreamReader) ID() string {
+	return r.id
+}
+
+// Topic returns the topic the stream is received on.
+func (r *StreamReader) Topic() string {
+	return r.topic
+}
+
+// Metadata returns the metadata published along with the stream.
+func (r *StreamReader) Metadata() map[string]string {
+	return maps.Clone(r.metadata)
+}
+
+// Read reads the data of the stream, waiting up to the IdleTimeout for the next chunk. Returns io.EOF once the stream
+// is read to the end, or an error wrapping ErrStreamAborted, ErrStreamTimeout or ErrStreamClosed if the stream
+// failed.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	idleTimeout := r.subscription.streamer.options.IdleTimeout
+
+	r.mutex.Lock()
+	for {
+		if r.closed {
+			r.mutex.Unlock()
+			return 0, ErrStreamClosed
+		}
+		if r.err != nil {
+			err := r.err
+			r.mutex.Unlock()
+			return 0, err
+		}
+		if _, exists := r.chunks[r.next]; exists {
+			break
+		}
+		r.mutex.Unlock()
+
+		select {
+		case <-r.notify:
+		case <-time.After(idleTimeout):
+			if r.fail(fmt.Errorf("%w: no chunk received for %s", ErrStreamTimeout, idleTimeout)) {
+				r.subscription.finish(r, r.cancelAck())
+			}
+		}
+
+		r.mutex.Lock()
+	}
+
+	chunk := r.chunks[r.next]
+	n := copy(p, chunk)
+	r.lastActivity = time.Now()
+	if n < len(chunk) {
+		r.chunks[r.next] = chunk[n:]
+		r.mutex.Unlock()
+		return n, nil
+	}
+
+	delete(r.chunks, r.next)
+	ended := r.ended && r.next == r.end
+	r.next++
+	if ended {
+		r.err = io.EOF
+	}
+	ack := r.ack(r.next)
+	r.mutex.Unlock()
+
+	if ended {
+		r.subscription.finish(r, ack)
+		if n == 0 {
+			return 0, io.EOF
+		}
+	} else {
+		r.subscription.publishAcks([]streamAck{ack})
+	}
+
+	return n, nil
+}
+
+// Close closes the reader, canceling the stream if it isn't read to the end so that the publisher stops publishing it.
+func (r *StreamReader) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.chunks = nil
+	failed := r.err != nil
+	r.err = ErrStreamClosed
+	r.mutex.Unlock()
+
+	r.wake()
+
+	if !failed {
+		r.subscription.finish(r, r.cancelAck())
+	}
+
+	return nil
+}
+
+// add adds the chunk with the sequence, which is ignored if it isn't within the window. Returns the acknowledgement to
+// publish again if the chunk was already received, as the publisher retransmits the chunks when it doesn't receive
+// their acknowledgement.
+func (r *StreamReader) add(sequence uint64, payload []byte, end bool, now time.Time) (streamAck, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil || r.closed {
+		return streamAck{}, false
+	}
+
+	if _, exists := r.chunks[sequence]; exists || sequence < r.next {
+		return r.ack(r.next), true
+	}
+
+	if sequence >= r.next+uint64(r.subscription.streamer.options.Window) {
+		return streamAck{}, false
+	}
+
+	r.chunks[sequence] = payload
+	if end {
+		r.end = sequence
+		r.ended = true
+	}
+	r.lastActivity = now
+	r.wake()
+
+	return streamAck{}, false
+}
+
+// fail fails the stream with the error, returning false if it already failed, ended or was closed.
+func (r *StreamReader) fail(err error) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil || r.closed {
+		return false
+	}
+	r.err = err
+	r.chunks = nil
+	r.wake()
+
+	return true
+}
+
+// idle returns whether the stream didn't receive any chunk nor was read within the IdleTimeout.
+func (r *StreamReader) idle(now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return now.Sub(r.lastActivity) > r.subscription.streamer.options.IdleTimeout
+}
+
+func (r *StreamReader) wake() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (r *StreamReader) ack(next uint64) streamAck {
+	return streamAck{topic: r.ackTopic, message: newStreamMessage(r.id, streamAckHeader, strconv.FormatUint(next, 10))}
+}
+
+func (r *StreamReader) cancelAck() streamAck {
+	return streamAck{topic: r.ackTopic, message: newStreamMessage(r.id, streamCanceledHeader, "true")}
+}
+
+// streamHeader returns the first value of the header of the message.
+func streamHeader(message types.MessageEnvelope, key string) string {
+	if values := message.Headers[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,251 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func subscribeStream(t *testing.T, streamer *Streamer, topic string) *StreamSubscription {
+	subscription, err := streamer.SubscribeStream(topic)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = subscription.Unsubscribe() })
+	return subscription
+}
+
+func receiveStream(t *testing.T, subscription *StreamSubscription) *StreamReader {
+	select {
+	case reader := <-subscription.Streams():
+		return reader
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "stream not received")
+		return nil
+	}
+}
+
+// lossyClient drops the first publish of the messages accepted by drop.
+type lossyClient struct {
+	wrappedClient
+	mutex   sync.Mutex
+	drop    func(message types.MessageEnvelope) bool
+	dropped map[string]bool
+}
+
+func (c *lossyClient) Publish(message types.MessageEnvelope, topic string) error {
+	c.mutex.Lock()
+	if c.drop(message) && !c.dropped[message.CorrelationID] {
+		c.dropped[message.CorrelationID] = true
+		c.mutex.Unlock()
+		return nil
+	}
+	c.mutex.Unlock()
+
+	return c.MessageClient.Publish(message, topic)
+}
+
+func TestStream(t *testing.T) {
+	data := make([]byte, 1024*1024+100)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	client := newMemoryClient(t)
+	streamer := NewStreamer(client, StreamOptions{ChunkSize: 4096, Window: 4})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	published := make(chan error, 1)
+	go func() {
+		published <- streamer.PublishStream("edgex/files", bytes.NewReader(data), map[string]string{"name": "firmware.bin"})
+	}()
+
+	reader := receiveStream(t, subscription)
+	assert.Equal(t, "edgex/files", reader.Topic())
+	assert.Equal(t, map[string]string{"name": "firmware.bin"}, reader.Metadata())
+
+	received, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, received)
+	require.NoError(t, reader.Close())
+
+	require.NoError(t, <-published)
+}
+
+func TestStreamEmpty(t *testing.T) {
+	client := newMemoryClient(t)
+	streamer := NewStreamer(client, StreamOptions{})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	published := make(chan error, 1)
+	go func() { published <- streamer.PublishStream("edgex/files", bytes.NewReader(nil), nil) }()
+
+	reader := receiveStream(t, subscription)
+	received, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, received)
+	require.NoError(t, reader.Close())
+
+	require.NoError(t, <-published)
+
+	require.Error(t, streamer.PublishStream("edgex/files", nil, nil))
+}
+
+func TestStreamRetransmit(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	client := &lossyClient{
+		wrappedClient: wrappedClient{newMemoryClient(t)},
+		dropped:       make(map[string]bool),
+		drop: func(message types.MessageEnvelope) bool {
+			// Drops a chunk and an acknowledgement
+			return message.Headers[streamSequenceHeader] != nil && message.Headers[streamSequenceHeader][0] == "3" ||
+				message.Headers[streamAckHeader] != nil && message.Headers[streamAckHeader][0] == "6"
+		},
+	}
+	streamer := NewStreamer(client, StreamOptions{ChunkSize: 1000, Window: 3, AckTimeout: 20 * time.Millisecond})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	published := make(chan error, 1)
+	go func() { published <- streamer.PublishStream("edgex/files", bytes.NewReader(data), nil) }()
+
+	reader := receiveStream(t, subscription)
+	received, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, received)
+	require.NoError(t, reader.Close())
+
+	require.NoError(t, <-published)
+	// The acknowledgements published again for the retransmitted chunks are dropped as well
+	assert.GreaterOrEqual(t, len(client.dropped), 2)
+}
+
+func TestStreamCanceled(t *testing.T) {
+	client := newMemoryClient(t)
+	streamer := NewStreamer(client, StreamOptions{ChunkSize: 100, Window: 2})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	published := make(chan error, 1)
+	go func() {
+		published <- streamer.PublishStream("edgex/files", bytes.NewReader(make([]byte, 10000)), nil)
+	}()
+
+	reader := receiveStream(t, subscription)
+	_, err := io.ReadFull(reader, make([]byte, 150))
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+
+	_, err = reader.Read(make([]byte, 10))
+	require.ErrorIs(t, err, ErrStreamClosed)
+
+	select {
+	case err := <-published:
+		require.ErrorIs(t, err, ErrStreamCanceled)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "stream not canceled")
+	}
+}
+
+func TestStreamAborted(t *testing.T) {
+	client := newMemoryClient(t)
+	streamer := NewStreamer(client, StreamOptions{ChunkSize: 100, Window: 2})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	readErr := errors.New("disk failure")
+	published := make(chan error, 1)
+	go func() {
+		reader := io.MultiReader(bytes.NewReader(make([]byte, 150)), iotest.ErrReader(readErr))
+		published <- streamer.PublishStream("edgex/files", reader, nil)
+	}()
+
+	reader := receiveStream(t, subscription)
+	_, err := io.ReadAll(reader)
+	require.ErrorIs(t, err, ErrStreamAborted)
+	assert.ErrorContains(t, err, "disk failure")
+	require.NoError(t, reader.Close())
+
+	require.ErrorIs(t, <-published, readErr)
+}
+
+func TestStreamTimeout(t *testing.T) {
+	client := newMemoryClient(t)
+
+	t.Run("no acknowledgement", func(t *testing.T) {
+		streamer := NewStreamer(client, StreamOptions{AckTimeout: 10 * time.Millisecond, MaxRetransmits: 2})
+
+		err := streamer.PublishStream("edgex/nobody", bytes.NewReader([]byte("data")), nil)
+		require.ErrorIs(t, err, ErrStreamTimeout)
+	})
+
+	t.Run("no chunk", func(t *testing.T) {
+		streamer := NewStreamer(client, StreamOptions{IdleTimeout: 50 * time.Millisecond})
+		subscription := subscribeStream(t, streamer, "edgex/files")
+
+		chunk := newStreamMessage("stream", streamSequenceHeader, "0")
+		chunk.Headers[streamAckTopicHeader] = []string{"edgex/stream/ack/stream"}
+		chunk.Payload = []byte("data")
+		require.NoError(t, client.Publish(chunk, "edgex/files"))
+
+		reader := receiveStream(t, subscription)
+		received, err := io.ReadAll(reader)
+		require.ErrorIs(t, err, ErrStreamTimeout)
+		assert.Equal(t, "data", string(received))
+		require.NoError(t, reader.Close())
+	})
+}
+
+func TestStreamForgedAckTopic(t *testing.T) {
+	client := newMemoryClient(t)
+
+	streamErrors := make(chan error, 1)
+	streamer := NewStreamer(client, StreamOptions{ErrorHandler: func(err error) { streamErrors <- err }})
+	subscription := subscribeStream(t, streamer, "edgex/files")
+
+	commands := make(chan types.MessageEnvelope, 1)
+	commandSubscription, err := client.SubscribeFunc("edgex/core/command/#", func(message types.MessageEnvelope) error {
+		commands <- message
+		return nil
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+	defer func() { _ = commandSubscription.Unsubscribe() }()
+
+	chunk := newStreamMessage("stream", streamSequenceHeader, "0")
+	chunk.Headers[streamAckTopicHeader] = []string{"edgex/core/command/request/device"}
+	require.NoError(t, client.Publish(chunk, "edgex/files"))
+
+	select {
+	case err := <-streamErrors:
+		assert.ErrorContains(t, err, "invalid acknowledgement topic")
+	case <-time.After(time.Second):
+		require.Fail(t, "forged chunk not refused")
+	}
+
+	select {
+	case <-subscription.Streams():
+		require.Fail(t, "no stream must be created by the forged chunk")
+	case message := <-commands:
+		require.Failf(t, "acknowledgement published to the forged topic", "%v", message.Headers)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStreamUnsubscribe(t *testing.T) {
+	client := newMemoryClient(t)
+	streamer := NewStreamer(client, StreamOptions{})
+
+	subscription, err := streamer.SubscribeStream("edgex/files")
+	require.NoError(t, err)
+	require.NoError(t, subscription.Unsubscribe())
+
+	_, open := <-subscription.Streams()
+	assert.False(t, open)
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// TypedHandler processes the value decoded from the payload of a message received by SubscribeTyped. The envelope
+// provides the metadata of the message, such as the ReceivedTopic and CorrelationID.
+type TypedHandler[T any] func(value T, envelope types.MessageEnvelope) error
+
+// EncodePayload encodes the value with the codec for the content type. An empty content type encodes the value as JSON.
+func EncodePayload(value any, contentType string) ([]byte, error) {
+	mediaType, err := payloadMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mediaType {
+	case common.ContentTypeJSON:
+		return json.Marshal(value)
+	case common.ContentTypeCBOR:
+		return cbor.Marshal(value)
+	default:
+		return nil, fmt.Errorf("unsupported content type '%s'", contentType)
+	}
+}
+
+// DecodePayload decodes the payload of the envelope into a value of type T with the codec for the ContentType of the
+// envelope. An empty ContentType decodes the payload as JSON. A *DecodeError is returned if the payload can't be
+// decoded.
+func DecodePayload[T any](envelope types.MessageEnvelope) (T, error) {
+	var value T
+
+	mediaType, err := payloadMediaType(envelope.ContentType)
+	if err == nil {
+		switch mediaType {
+		case common.ContentTypeJSON:
+			err = json.Unmarshal(envelope.Payload, &value)
+		case common.ContentTypeCBOR:
+			err = cbor.Unmarshal(envelope.Payload, &value)
+		default:
+			err = fmt.Errorf("unsupported content type '%s'", envelope.ContentType)
+		}
+	}
+
+	if err != nil {
+		var zero T
+		return zero, &DecodeError{Topic: envelope.ReceivedTopic, ContentType: envelope.ContentType, Err: err}
+	}
+
+	return value, nil
+}
+
+// PublishTyped encodes the value with the codec for the content type and publishes it to the topic.
+func PublishTyped[T any](client MessageClient, value T, topic string, contentType string) error {
+	envelope, err := newTypedEnvelope(value, contentType)
+	if err != nil {
+		return err
+	}
+
+	return client.Publish(envelope, topic)
+}
+
+// SubscribeTyped subscribes to the topic and invokes the handler with the value decoded from the payload of every
+// received message. Messages which can't be decoded are not passed to the handler, instead a *DecodeError is passed
+// to the ErrorHandler of the options.
+func SubscribeTyped[T any](client MessageClient, topic string, handler TypedHandler[T], options types.SubscribeOptions) (types.Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("unable to subscribe to topic '%s': handler must be specified", topic)
+	}
+
+	return wrappedClient{client}.SubscribeFunc(topic, func(envelope types.MessageEnvelope) error {
+		value, err := DecodePayload[T](envelope)
+		if err != nil {
+			return err
+		}
+
+		return handler(value, envelope)
+	}, options)
+}
+
+// RequestTyped encodes the request with the codec for the content type, sends it with Request and decodes the payload
+// of the response with the codec for the ContentType of the response. A *ResponseError is returned if the response
+// indicates an error and a *DecodeError if its payload can't be decoded.
+func RequestTyped[Req any, Resp any](
+	client MessageClient,
+	request Req,
+	requestTopic string,
+	responseTopicPrefix string,
+	contentType string,
+	timeout time.Duration) (Resp, error) {
+	var zero Resp
+
+	payload, err := EncodePayload(request, contentType)
+	if err != nil {
+		return zero, err
+	}
+
+	requestEnvelope := types.NewMessageEnvelopeForRequest(payload, nil)
+	if contentType != "" {
+		requestEnvelope.ContentType = contentType
+	}
+
+	responseEnvelope, err := client.Request(requestEnvelope, requestTopic, responseTopicPrefix, timeout)
+	if err != nil {
+		return zero, err
+	}
+
+	if responseEnvelope.ErrorCode != 0 {
+		return zero, &ResponseError{
+			RequestID: responseEnvelope.RequestID,
+			ErrorCode: responseEnvelope.ErrorCode,
+			Message:   string(responseEnvelope.Payload),
+		}
+	}
+
+	return DecodePayload[Resp](*responseEnvelope)
+}
+
+func newTypedEnvelope(value any, contentType string) (types.MessageEnvelope, error) {
+	payload, err := EncodePayload(value, contentType)
+	if err != nil {
+		return types.MessageEnvelope{}, err
+	}
+
+	envelope := types.NewMessageEnvelopeForRequest(payload, nil)
+	envelope.RequestID = ""
+	if contentType != "" {
+		envelope.ContentType = contentType
+	}
+
+	return envelope, nil
+}
+
+// payloadMediaType returns the media type of the content type without any parameters, defaulting to JSON.
+func payloadMediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return common.ContentTypeJSON, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type '%s': %w", contentType, err)
+	}
+
+	return mediaType, nil
+}
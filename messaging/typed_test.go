@@ -0,0 +1,168 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg/memory"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+type testReading struct {
+	Name  string `json:"name" cbor:"name"`
+	Value int    `json:"value" cbor:"value"`
+}
+
+func newMemoryClient(t *testing.T) *memory.Client {
+	client, err := memory.NewClient(types.MessageBusConfig{Type: Memory, Broker: types.HostInfo{Host: t.Name()}})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+	return client
+}
+
+func TestEncodeDecodePayload(t *testing.T) {
+	expected := testReading{Name: "temperature", Value: 21}
+
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{"default", ""},
+		{"json", common.ContentTypeJSON},
+		{"json with parameters", common.ContentTypeJSON + "; charset=utf-8"},
+		{"cbor", common.ContentTypeCBOR},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := EncodePayload(expected, test.contentType)
+			require.NoError(t, err)
+
+			actual, err := DecodePayload[testReading](types.MessageEnvelope{Payload: payload, ContentType: test.contentType})
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	_, err := EncodePayload(expected, "application/xml")
+	require.Error(t, err)
+}
+
+func TestDecodePayloadError(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope types.MessageEnvelope
+	}{
+		{"invalid json", types.MessageEnvelope{Payload: []byte("{"), ContentType: common.ContentTypeJSON}},
+		{"invalid cbor", types.MessageEnvelope{Payload: []byte{0xff}, ContentType: common.ContentTypeCBOR}},
+		{"wrong type", types.MessageEnvelope{Payload: []byte(`"text"`), ContentType: common.ContentTypeJSON}},
+		{"unsupported content type", types.MessageEnvelope{Payload: []byte("<xml/>"), ContentType: "application/xml"}},
+		{"invalid content type", types.MessageEnvelope{Payload: []byte("{}"), ContentType: ";"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.envelope.ReceivedTopic = "edgex/test"
+
+			value, err := DecodePayload[testReading](test.envelope)
+			require.Error(t, err)
+			assert.Zero(t, value)
+
+			var decodeErr *DecodeError
+			require.True(t, errors.As(err, &decodeErr))
+			assert.Equal(t, "edgex/test", decodeErr.Topic)
+			assert.Equal(t, test.envelope.ContentType, decodeErr.ContentType)
+			assert.Error(t, decodeErr.Unwrap())
+		})
+	}
+}
+
+func TestPublishSubscribeTyped(t *testing.T) {
+	client := newMemoryClient(t)
+
+	received := make(chan testReading, 1)
+	metadata := make(chan types.MessageEnvelope, 1)
+	handlerErrors := make(chan error, 1)
+	subscription, err := SubscribeTyped(client, "edgex/readings/#", func(value testReading, envelope types.MessageEnvelope) error {
+		received <- value
+		metadata <- envelope
+		return nil
+	}, types.SubscribeOptions{ErrorHandler: func(err error) { handlerErrors <- err }})
+	require.NoError(t, err)
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	expected := testReading{Name: "temperature", Value: 21}
+	require.NoError(t, PublishTyped(client, expected, "edgex/readings/device", common.ContentTypeCBOR))
+
+	assert.Equal(t, expected, <-received)
+	envelope := <-metadata
+	assert.Equal(t, "edgex/readings/device", envelope.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeCBOR, envelope.ContentType)
+	assert.NotEmpty(t, envelope.CorrelationID)
+
+	// The handler isn't invoked for a payload which can't be decoded
+	require.NoError(t, PublishTyped(client, "text", "edgex/readings/device", ""))
+	select {
+	case err := <-handlerErrors:
+		var decodeErr *DecodeError
+		assert.True(t, errors.As(err, &decodeErr))
+	case <-time.After(time.Second):
+		require.Fail(t, "decode error not received")
+	}
+	assert.Empty(t, received)
+
+	_, err = SubscribeTyped[testReading](client, "edgex/readings/#", nil, types.SubscribeOptions{})
+	require.Error(t, err)
+}
+
+func TestRequestTyped(t *testing.T) {
+	client := newMemoryClient(t)
+
+	_, err := client.SubscribeFunc("edgex/request", func(request types.MessageEnvelope) error {
+		reading, err := DecodePayload[testReading](request)
+		if err != nil {
+			return err
+		}
+
+		var response types.MessageEnvelope
+		if reading.Value < 0 {
+			response = types.NewMessageEnvelopeWithError(request.RequestID, "negative value")
+		} else {
+			reading.Value *= 2
+			payload, _ := EncodePayload(reading, request.ContentType)
+			response, _ = types.NewMessageEnvelopeForResponse(payload, request.RequestID, request.CorrelationID, request.ContentType)
+		}
+		return client.Publish(response, "edgex/response/"+request.RequestID)
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	response, err := RequestTyped[testReading, testReading](client, testReading{Name: "doubled", Value: 21},
+		"edgex/request", "edgex/response", common.ContentTypeCBOR, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, testReading{Name: "doubled", Value: 42}, response)
+
+	_, err = RequestTyped[testReading, testReading](client, testReading{Value: -1},
+		"edgex/request", "edgex/response", "", time.Second)
+	var responseErr *ResponseError
+	require.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, 1, responseErr.ErrorCode)
+	assert.Equal(t, "negative value", responseErr.Message)
+
+	// The response is a testReading which can't be decoded as a string
+	_, err = RequestTyped[testReading, string](client, testReading{Value: 1},
+		"edgex/request", "edgex/response", common.ContentTypeJSON, time.Second)
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+
+	_, err = RequestTyped[testReading, testReading](client, testReading{Value: 1},
+		"edgex/request", "edgex/response", "application/xml", time.Second)
+	require.Error(t, err, "the request can't be encoded for an unsupported content type")
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/internal/pkg"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// wrappedClient is embedded by the clients wrapping a MessageClient. It implements the companion interfaces of
+// MessageClient by forwarding the calls to the wrapped client, which fail if the wrapped client doesn't implement them.
+type wrappedClient struct {
+	MessageClient
+}
+
+func (w wrappedClient) SubscribeWithHandle(topic types.TopicChannel, messageErrors chan error) (types.Subscription, error) {
+	handleClient, ok := w.MessageClient.(MessageClientWithHandle)
+	if !ok {
+		return nil, w.unsupported(fmt.Sprintf("subscribe to topic '%s'", topic.Topic), "MessageClientWithHandle")
+	}
+
+	return handleClient.SubscribeWithHandle(topic, messageErrors)
+}
+
+func (w wrappedClient) SubscribeFunc(topic string, handler types.MessageHandler, options types.SubscribeOptions) (types.Subscription, error) {
+	handleClient, ok := w.MessageClient.(MessageClientWithHandle)
+	if !ok {
+		return nil, w.unsupported(fmt.Sprintf("subscribe to topic '%s'", topic), "MessageClientWithHandle")
+	}
+
+	return handleClient.SubscribeFunc(topic, handler, options)
+}
+
+func (w wrappedClient) Messages(ctx context.Context, topic string) iter.Seq2[types.MessageEnvelope, error] {
+	if handleClient, ok := w.MessageClient.(MessageClientWithHandle); ok {
+		return handleClient.Messages(ctx, topic)
+	}
+
+	return pkg.Messages(ctx, w.SubscribeWithHandle, topic)
+}
+
+func (w wrappedClient) RequestMany(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, window time.Duration, maxResponses int) ([]types.MessageEnvelope, error) {
+	requestManyClient, ok := w.MessageClient.(MessageClientWithRequestMany)
+	if !ok {
+		return nil, w.unsupported(fmt.Sprintf("send request to topic '%s'", requestTopic), "MessageClientWithRequestMany")
+	}
+
+	return requestManyClient.RequestMany(message, requestTopic, responseTopicPrefix, window, maxResponses)
+}
+
+// IsConnected reports whether the wrapped client is connected, a client which doesn't report the state of its
+// connection is never reported as connected.
+func (w wrappedClient) IsConnected() bool {
+	if stateClient, ok := w.MessageClient.(MessageClientWithConnectionState); ok {
+		return stateClient.IsConnected()
+	}
+
+	return false
+}
+
+// State returns the state of the connection of the wrapped client, Disconnected if it doesn't report it.
+func (w wrappedClient) State() types.ConnectionState {
+	if stateClient, ok := w.MessageClient.(MessageClientWithConnectionState); ok {
+		return stateClient.State()
+	}
+
+	return types.Disconnected
+}
+
+// Events returns the connection events of the wrapped client, a nil channel on which no event is ever emitted if it
+// doesn't report them.
+func (w wrappedClient) Events() <-chan types.ConnectionEvent {
+	if stateClient, ok := w.MessageClient.(MessageClientWithConnectionState); ok {
+		return stateClient.Events()
+	}
+
+	return nil
+}
+
+func (w wrappedClient) unsupported(operation string, companion string) error {
+	return fmt.Errorf("unable to %s: %T doesn't implement %s", operation, w.MessageClient, companion)
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/messaging/mocks"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func TestWrappedClientCompanions(t *testing.T) {
+	client := WithRetry(newMemoryClient(t), RetryPolicy{MaxAttempts: 1})
+
+	stateClient, ok := client.(MessageClientWithConnectionState)
+	require.True(t, ok)
+	assert.True(t, stateClient.IsConnected())
+	assert.Equal(t, types.Connected, stateClient.State())
+
+	received := make(chan types.MessageEnvelope, 1)
+	_, err := client.(MessageClientWithHandle).SubscribeFunc("edgex/events", func(message types.MessageEnvelope) error {
+		received <- message
+		return nil
+	}, types.SubscribeOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{Payload: []byte("event")}, "edgex/events"))
+	select {
+	case message := <-received:
+		assert.Equal(t, "event", string(message.Payload))
+	case <-time.After(time.Second):
+		require.Fail(t, "message not received")
+	}
+}
+
+func TestWrappedClientUnsupportedCompanions(t *testing.T) {
+	client := WithRetry(&mocks.MessageClient{}, RetryPolicy{MaxAttempts: 1})
+
+	_, err := client.(MessageClientWithHandle).SubscribeFunc("edgex/events", func(types.MessageEnvelope) error { return nil }, types.SubscribeOptions{})
+	require.ErrorContains(t, err, "MessageClientWithHandle")
+
+	_, err = SubscribeTyped[testReading](client, "edgex/readings", func(testReading, types.MessageEnvelope) error { return nil }, types.SubscribeOptions{})
+	require.ErrorContains(t, err, "MessageClientWithHandle")
+
+	for _, err = range client.(MessageClientWithHandle).Messages(context.Background(), "edgex/events") {
+		break
+	}
+	require.ErrorContains(t, err, "MessageClientWithHandle")
+
+	_, err = client.(MessageClientWithRequestMany).RequestMany(types.MessageEnvelope{}, "edgex/request", "edgex/response", time.Second, 0)
+	require.ErrorContains(t, err, "MessageClientWithRequestMany")
+
+	stateClient := client.(MessageClientWithConnectionState)
+	assert.False(t, stateClient.IsConnected())
+	assert.Equal(t, types.Disconnected, stateClient.State())
+	assert.Nil(t, stateClient.Events())
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// jsonCodec encodes the envelope as JSON, which is detected by its opening brace.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return JSON
+}
+
+func (jsonCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return json.Unmarshal(data, envelope)
+}
+
+func (jsonCodec) Detect(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
+
+// selfDescribedCBOR is the self-described CBOR tag 55799 prefixing the envelopes encoded by cborCodec.
+var selfDescribedCBOR = []byte{0xd9, 0xd9, 0xf7}
+
+// cborCodec encodes the envelope as a CBOR map prefixed with the self-described CBOR tag, which is detected along
+// with the CBOR maps without the tag.
+type cborCodec struct{}
+
+func (cborCodec) Name() string {
+	return CBOR
+}
+
+func (cborCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	data, err := cbor.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte(nil), selfDescribedCBOR...), data...), nil
+}
+
+func (cborCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return cbor.Unmarshal(bytes.TrimPrefix(data, selfDescribedCBOR), envelope)
+}
+
+func (cborCodec) Detect(data []byte) bool {
+	// Major type 5 is a map
+	return bytes.HasPrefix(data, selfDescribedCBOR) || (len(data) > 0 && data[0]>>5 == 5)
+}
+
+// rawMagic prefixes the envelopes encoded by rawCodec. The leading zero byte can't start a JSON or CBOR envelope.
+var rawMagic = []byte("\x00EXR1")
+
+// rawCodec encodes the envelope as rawMagic, the length of its fields as a big-endian uint32, its fields but the
+// payload as JSON, then its raw payload. The payload isn't encoded, so it is neither escaped nor base64-encoded.
+type rawCodec struct{}
+
+func (rawCodec) Name() string {
+	return Raw
+}
+
+func (rawCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	payload := envelope.Payload
+	envelope.Payload = nil
+
+	fields, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(rawMagic)+4+len(fields)+len(payload))
+	data = append(data, rawMagic...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(fields)))
+	data = append(data, fields...)
+	data = append(data, payload...)
+
+	return data, nil
+}
+
+func (rawCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	data, found := bytes.CutPrefix(data, rawMagic)
+	if !found || len(data) < 4 {
+		return errors.New("invalid raw envelope: missing header")
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint64(length) > uint64(len(data)) {
+		return fmt.Errorf("invalid raw envelope: fields length %d exceeds the %d bytes available", length, len(data))
+	}
+
+	if err := json.Unmarshal(data[:length], envelope); err != nil {
+		return fmt.Errorf("invalid raw envelope: %w", err)
+	}
+
+	envelope.Payload = append([]byte(nil), data[length:]...)
+
+	return nil
+}
+
+func (rawCodec) Detect(data []byte) bool {
+	return bytes.HasPrefix(data, rawMagic)
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2024 IOTech Ltd
+
+// Package codec defines the codecs the message clients encode the MessageEnvelope with, and the registry the codec
+// is selected from by the Format of the Optional configuration. The received messages are decoded with the codec they
+// were encoded with whatever the configured Format, so clients configured with different formats interoperate.
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+const (
+	// JSON is the name of the codec encoding the MessageEnvelope as JSON, the default one
+	JSON = "json"
+	// CBOR is the name of the codec encoding the MessageEnvelope as self-described CBOR
+	CBOR = "cbor"
+	// Raw is the name of the codec encoding the fields of the MessageEnvelope followed by its raw payload
+	Raw = "raw"
+)
+
+// Codec encodes and decodes a MessageEnvelope.
+type Codec interface {
+	// Name returns the name the codec is registered and selected with, which is case-insensitive.
+	Name() string
+	// Encode encodes the envelope.
+	Encode(envelope types.MessageEnvelope) ([]byte, error)
+	// Decode decodes the data encoded by Encode into the envelope.
+	Decode(data []byte, envelope *types.MessageEnvelope) error
+	// Detect reports whether the data looks encoded by the codec. It must not match the data of the other codecs,
+	// i.e. by checking a prefix specific to the codec.
+	Detect(data []byte) bool
+}
+
+var registry = struct {
+	codecs map[string]Codec
+	// ordered holds the codecs in the order they were registered, which is the order they are detected in
+	ordered []Codec
+	mutex   sync.RWMutex
+}{codecs: make(map[string]Codec)}
+
+func init() {
+	for _, codec := range []Codec{rawCodec{}, cborCodec{}, jsonCodec{}} {
+		if err := Register(codec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds the codec to the registry, so it can be selected by its name and its messages are detected. Returns
+// an error if a codec is already registered with the same name.
+func Register(codec Codec) error {
+	name := strings.ToLower(codec.Name())
+	if name == "" {
+		return fmt.Errorf("unable to register codec: name is empty")
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, exists := registry.codecs[name]; exists {
+		return fmt.Errorf("unable to register codec: a codec named '%s' is already registered", name)
+	}
+
+	registry.codecs[name] = codec
+	registry.ordered = append(registry.ordered, codec)
+
+	return nil
+}
+
+// Get returns the codec registered with the name, the JSON codec if the name is empty.
+func Get(name string) (Codec, error) {
+	if name == "" {
+		name = JSON
+	}
+
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	codec, exists := registry.codecs[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unknown codec '%s'", name)
+	}
+
+	return codec, nil
+}
+
+// Detect returns the registered codec the data looks encoded with, the JSON codec if none is detected.
+func Detect(data []byte) Codec {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	for _, codec := range registry.ordered {
+		if codec.Detect(data) {
+			return codec
+		}
+	}
+
+	return registry.codecs[JSON]
+}
+
+// Decode decodes the data into the envelope with the codec it was encoded with.
+func Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return Detect(data).Decode(data, envelope)
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	commonDTO "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+func testEnvelope() types.MessageEnvelope {
+	return types.MessageEnvelope{
+		Versionable:   commonDTO.NewVersionable(),
+		CorrelationID: "fa1def22-96de-4d44-8811-00333438c8e3",
+		RequestID:     "3ab0e022-464b-4bfe-bf7f-b0154093ddad",
+		ErrorCode:     1,
+		Payload:       []byte{0x00, 0xff, '{', '"', '\n'},
+		ContentType:   common.ContentTypeCBOR,
+		QueryParams:   map[string]string{"ds-pushevent": "true"},
+		Headers:       map[string][]string{"route": {"core-data", "app:a"}},
+		TraceParent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TraceState:    "vendor=value",
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	for _, name := range []string{JSON, CBOR, Raw} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := Get(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, codec.Name())
+
+			expected := testEnvelope()
+			data, err := codec.Encode(expected)
+			require.NoError(t, err)
+			assert.True(t, codec.Detect(data))
+			assert.Equal(t, name, Detect(data).Name(), "the codec must be detected from the encoded data")
+
+			var actual types.MessageEnvelope
+			require.NoError(t, Decode(data, &actual))
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	codec, err := Get("")
+	require.NoError(t, err)
+	assert.Equal(t, JSON, codec.Name(), "JSON must be the default codec")
+
+	codec, err = Get("CBOR")
+	require.NoError(t, err)
+	assert.Equal(t, CBOR, codec.Name(), "the names must be case-insensitive")
+
+	_, err = Get("xml")
+	require.Error(t, err)
+}
+
+func TestDetect(t *testing.T) {
+	jsonData, err := json.Marshal(testEnvelope())
+	require.NoError(t, err)
+
+	assert.Equal(t, JSON, Detect(append([]byte(" \n"), jsonData...)).Name())
+	assert.Equal(t, CBOR, Detect([]byte{0xa1, 0x61, 'a', 0x01}).Name(), "CBOR maps without the self-described tag must be detected")
+	assert.Equal(t, JSON, Detect([]byte("not an envelope")).Name(), "JSON must be used when no codec is detected")
+
+	var envelope types.MessageEnvelope
+	require.Error(t, Decode([]byte("not an envelope"), &envelope))
+}
+
+func TestRawCodecInvalid(t *testing.T) {
+	codec, err := Get(Raw)
+	require.NoError(t, err)
+
+	var envelope types.MessageEnvelope
+	require.Error(t, codec.Decode([]byte("\x00EXR1"), &envelope))
+	require.Error(t, codec.Decode([]byte("\x00EXR1\x00\x00\x00\xff{}"), &envelope))
+	require.Error(t, codec.Decode([]byte("\x00EXR1\x00\x00\x00\x02{x"), &envelope))
+}
+
+// upperCodec is a codec encoding the envelope as JSON prefixed with "UPPER".
+type upperCodec struct{}
+
+func (upperCodec) Name() string { return "Upper" }
+
+func (upperCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	data, err := json.Marshal(envelope)
+	return append([]byte("UPPER"), data...), err
+}
+
+func (upperCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return json.Unmarshal(data[len("UPPER"):], envelope)
+}
+
+func (upperCodec) Detect(data []byte) bool {
+	return len(data) >= len("UPPER") && string(data[:len("UPPER")]) == "UPPER"
+}
+
+func TestRegister(t *testing.T) {
+	require.NoError(t, Register(upperCodec{}))
+	require.Error(t, Register(upperCodec{}), "a codec can't be registered twice")
+	require.Error(t, Register(jsonCodec{}))
+
+	codec, err := Get("upper")
+	require.NoError(t, err)
+	data, err := codec.Encode(testEnvelope())
+	require.NoError(t, err)
+
+	var envelope types.MessageEnvelope
+	require.NoError(t, Decode(data, &envelope))
+	assert.Equal(t, testEnvelope(), envelope)
+}
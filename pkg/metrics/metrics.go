@@ -0,0 +1,108 @@
+// Copyright (C) 2024 IOTech Ltd
+
+// Package metrics defines the hook the message clients record the metrics of the message bus traffic with, and the
+// in-memory Registry recording them by default.
+package metrics
+
+import (
+	"sync/atomic"
+)
+
+// Names of the metrics recorded by the message clients. All the metrics are labelled with the topic, which is the
+// subscribed topic rather than the topic the message was received on for the received messages, and the request topic
+// for the requests.
+const (
+	// MessagesPublished is the counter of the messages published
+	MessagesPublished = "edgex_messagebus_messages_published_total"
+	// BytesPublished is the counter of the bytes of the payloads of the messages published
+	BytesPublished = "edgex_messagebus_bytes_published_total"
+	// PublishErrors is the counter of the messages which couldn't be published
+	PublishErrors = "edgex_messagebus_publish_errors_total"
+	// PublishDuration is the histogram of the time taken to publish a message, in seconds
+	PublishDuration = "edgex_messagebus_publish_duration_seconds"
+	// CompressionInputBytes is the counter of the bytes of the published payloads which were compressed
+	CompressionInputBytes = "edgex_messagebus_compression_input_bytes_total"
+	// CompressionOutputBytes is the counter of the bytes the published payloads were compressed to
+	CompressionOutputBytes = "edgex_messagebus_compression_output_bytes_total"
+	// CompressionRatio is the histogram of the size of the compressed payloads relative to their uncompressed size
+	CompressionRatio = "edgex_messagebus_compression_ratio"
+	// ChunksPublished is the counter of the chunks the messages exceeding the maximum message size were split into
+	ChunksPublished = "edgex_messagebus_chunks_published_total"
+	// MessagesReceived is the counter of the messages received
+	MessagesReceived = "edgex_messagebus_messages_received_total"
+	// BytesReceived is the counter of the bytes of the payloads of the messages received
+	BytesReceived = "edgex_messagebus_bytes_received_total"
+	// DecodeErrors is the counter of the received messages which couldn't be decoded
+	DecodeErrors = "edgex_messagebus_decode_errors_total"
+	// MessagesDropped is the counter of the received messages dropped by the overflow policy of their subscription
+	MessagesDropped = "edgex_messagebus_messages_dropped_total"
+	// ErrorsDropped is the counter of the errors of the received messages which were dropped rather than reported to
+	// the errors channel of their subscription, because the subscriber didn't receive them fast enough
+	ErrorsDropped = "edgex_messagebus_errors_dropped_total"
+	// ChunkTransfersDropped is the counter of the chunked messages dropped before being reassembled, because their
+	// chunks timed out or they exceeded the reassembly memory of their subscription
+	ChunkTransfersDropped = "edgex_messagebus_chunk_transfers_dropped_total"
+	// RequestDuration is the histogram of the round-trip time of the requests which received a response, in seconds
+	RequestDuration = "edgex_messagebus_request_duration_seconds"
+	// RequestTimeouts is the counter of the requests which timed out waiting for their response
+	RequestTimeouts = "edgex_messagebus_request_timeouts_total"
+	// RequestsInFlight is the gauge of the requests waiting for their response
+	RequestsInFlight = "edgex_messagebus_requests_in_flight"
+	// Connections is the gauge of the message clients connected to the message bus, without topic label
+	Connections = "edgex_messagebus_connections"
+)
+
+// TopicLabel is the name of the label holding the topic of a metric.
+const TopicLabel = "topic"
+
+// Labels are the labels of a metric, by name.
+type Labels map[string]string
+
+// Recorder records the metrics of the message clients. The implementations must be safe for concurrent use and must
+// not block, as the metrics are recorded while publishing and receiving the messages.
+type Recorder interface {
+	// IncCounter increases the counter by delta.
+	IncCounter(name string, labels Labels, delta float64)
+	// AddGauge adds delta, which may be negative, to the gauge.
+	AddGauge(name string, labels Labels, delta float64)
+	// ObserveHistogram records the value in the histogram.
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// DefaultRegistry is the Registry the metrics are recorded to unless SetDefault is called.
+var DefaultRegistry = NewRegistry()
+
+// recorderHolder allows storing the Recorder interface in an atomic.Pointer.
+type recorderHolder struct {
+	recorder Recorder
+}
+
+var defaultRecorder atomic.Pointer[recorderHolder]
+
+func init() {
+	SetDefault(DefaultRegistry)
+}
+
+// Default returns the Recorder the message clients record their metrics to.
+func Default() Recorder {
+	return defaultRecorder.Load().recorder
+}
+
+// SetDefault replaces the Recorder the message clients record their metrics to, i.e. to record them with the metrics
+// library of the service. A nil Recorder disables the metrics.
+func SetDefault(recorder Recorder) {
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+
+	defaultRecorder.Store(&recorderHolder{recorder: recorder})
+}
+
+// nopRecorder discards the metrics.
+type nopRecorder struct{}
+
+func (nopRecorder) IncCounter(string, Labels, float64) {}
+
+func (nopRecorder) AddGauge(string, Labels, float64) {}
+
+func (nopRecorder) ObserveHistogram(string, Labels, float64) {}
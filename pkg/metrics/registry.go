@@ -0,0 +1,297 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultMaxSeries is the maximum number of label combinations recorded for a metric when not specified
+	DefaultMaxSeries = 1000
+
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets when not specified, in seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Kind is the kind of a metric.
+type Kind int
+
+const (
+	Counter Kind = iota
+	Gauge
+	Histogram
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Gauge:
+		return "gauge"
+	case Histogram:
+		return "histogram"
+	default:
+		return "counter"
+	}
+}
+
+// Metric is the snapshot of a metric for a combination of labels.
+type Metric struct {
+	Name   string
+	Kind   Kind
+	Labels Labels
+	// Value is the value of a counter or a gauge
+	Value float64
+	// Buckets are the cumulative counts of the observations of a histogram, by upper bound. The +Inf bucket is Count.
+	Buckets []Bucket
+	// Sum is the sum of the observations of a histogram
+	Sum float64
+	// Count is the number of observations of a histogram
+	Count uint64
+}
+
+// Bucket is a bucket of a histogram.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// RegistryOptions defines the limits of a Registry.
+type RegistryOptions struct {
+	// Buckets are the upper bounds of the histogram buckets, DefaultBuckets when not specified
+	Buckets []float64
+	// MaxSeries is the maximum number of label combinations recorded for a metric, DefaultMaxSeries when not
+	// specified. The new label combinations are ignored once it is reached, which protects the registry against topics
+	// with unbounded levels such as the response topics.
+	MaxSeries int
+}
+
+// Registry is a Recorder keeping the metrics in memory, which can be exported in the Prometheus text format.
+type Registry struct {
+	options RegistryOptions
+	metrics map[string]*family
+	mutex   sync.Mutex
+}
+
+// family holds the series of a metric, by label combination.
+type family struct {
+	kind   Kind
+	series map[string]*Metric
+}
+
+// NewRegistry creates an empty Registry with the default options.
+func NewRegistry() *Registry {
+	return NewRegistryWithOptions(RegistryOptions{})
+}
+
+// NewRegistryWithOptions creates an empty Registry with the specified options.
+func NewRegistryWithOptions(options RegistryOptions) *Registry {
+	if len(options.Buckets) == 0 {
+		options.Buckets = DefaultBuckets
+	}
+	if options.MaxSeries <= 0 {
+		options.MaxSeries = DefaultMaxSeries
+	}
+
+	buckets := append([]float64(nil), options.Buckets...)
+	sort.Float64s(buckets)
+	options.Buckets = buckets
+
+	return &Registry{options: options, metrics: make(map[string]*family)}
+}
+
+// IncCounter increases the counter by delta.
+func (r *Registry) IncCounter(name string, labels Labels, delta float64) {
+	r.record(name, Counter, labels, func(metric *Metric) {
+		metric.Value += delta
+	})
+}
+
+// AddGauge adds delta to the gauge.
+func (r *Registry) AddGauge(name string, labels Labels, delta float64) {
+	r.record(name, Gauge, labels, func(metric *Metric) {
+		metric.Value += delta
+	})
+}
+
+// ObserveHistogram records the value in the histogram.
+func (r *Registry) ObserveHistogram(name string, labels Labels, value float64) {
+	r.record(name, Histogram, labels, func(metric *Metric) {
+		for i := range metric.Buckets {
+			if value <= metric.Buckets[i].UpperBound {
+				metric.Buckets[i].Count++
+			}
+		}
+		metric.Sum += value
+		metric.Count++
+	})
+}
+
+// record applies the update to the series of the metric for the labels. Updates of a metric recorded with another
+// kind before are ignored.
+func (r *Registry) record(name string, kind Kind, labels Labels, update func(metric *Metric)) {
+	key := labelsKey(labels)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	f, exists := r.metrics[name]
+	if !exists {
+		f = &family{kind: kind, series: make(map[string]*Metric)}
+		r.metrics[name] = f
+	}
+	if f.kind != kind {
+		return
+	}
+
+	metric, exists := f.series[key]
+	if !exists {
+		if len(f.series) >= r.options.MaxSeries {
+			return
+		}
+
+		metric = &Metric{Name: name, Kind: kind, Labels: copyLabels(labels)}
+		if kind == Histogram {
+			metric.Buckets = make([]Bucket, len(r.options.Buckets))
+			for i, upperBound := range r.options.Buckets {
+				metric.Buckets[i].UpperBound = upperBound
+			}
+		}
+		f.series[key] = metric
+	}
+
+	update(metric)
+}
+
+// Snapshot returns a copy of the metrics, sorted by name and labels.
+func (r *Registry) Snapshot() []Metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	type keyedMetric struct {
+		key    string
+		metric Metric
+	}
+
+	var keyed []keyedMetric
+	for _, f := range r.metrics {
+		for key, metric := range f.series {
+			snapshot := *metric
+			snapshot.Labels = copyLabels(metric.Labels)
+			snapshot.Buckets = append([]Bucket(nil), metric.Buckets...)
+			keyed = append(keyed, keyedMetric{key: key, metric: snapshot})
+		}
+	}
+
+	sort.Slice(keyed, func(i, j int) bool {
+		if keyed[i].metric.Name != keyed[j].metric.Name {
+			return keyed[i].metric.Name < keyed[j].metric.Name
+		}
+		return keyed[i].key < keyed[j].key
+	})
+
+	metrics := make([]Metric, len(keyed))
+	for i := range keyed {
+		metrics[i] = keyed[i].metric
+	}
+
+	return metrics
+}
+
+// WritePrometheus writes the snapshot of the metrics in the Prometheus text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	previous := ""
+	for _, metric := range r.Snapshot() {
+		if metric.Name != previous {
+			_, _ = fmt.Fprintf(writer, "# TYPE %s %s\n", metric.Name, metric.Kind)
+			previous = metric.Name
+		}
+
+		if metric.Kind != Histogram {
+			_, _ = fmt.Fprintf(writer, "%s%s %s\n", metric.Name, formatLabels(metric.Labels, ""), formatValue(metric.Value))
+			continue
+		}
+
+		for _, bucket := range metric.Buckets {
+			_, _ = fmt.Fprintf(writer, "%s_bucket%s %d\n", metric.Name, formatLabels(metric.Labels, formatValue(bucket.UpperBound)), bucket.Count)
+		}
+		_, _ = fmt.Fprintf(writer, "%s_bucket%s %d\n", metric.Name, formatLabels(metric.Labels, "+Inf"), metric.Count)
+		_, _ = fmt.Fprintf(writer, "%s_sum%s %s\n", metric.Name, formatLabels(metric.Labels, ""), formatValue(metric.Sum))
+		_, _ = fmt.Fprintf(writer, "%s_count%s %d\n", metric.Name, formatLabels(metric.Labels, ""), metric.Count)
+	}
+
+	return writer.Flush()
+}
+
+// Handler returns an http.Handler serving the snapshot of the metrics in the Prometheus text exposition format, to be
+// scraped by Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		_ = r.WritePrometheus(w)
+	})
+}
+
+// labelsKey returns a key identifying the label combination, independently of the order of the map.
+func labelsKey(labels Labels) string {
+	return formatLabels(labels, "")
+}
+
+// formatLabels formats the labels sorted by name, with the le label of a histogram bucket if le isn't empty.
+func formatLabels(labels Labels, le string) string {
+	if len(labels) == 0 && le == "" {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(labels[name])))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=\"%s\"", le))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func copyLabels(labels Labels) Labels {
+	copied := make(Labels, len(labels))
+	for name, value := range labels {
+		copied[name] = value
+	}
+	return copied
+}
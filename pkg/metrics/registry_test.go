@@ -0,0 +1,113 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistryWithOptions(RegistryOptions{Buckets: []float64{1, 0.1}})
+	labels := Labels{TopicLabel: "edgex/events"}
+
+	registry.IncCounter(MessagesPublished, labels, 1)
+	registry.IncCounter(MessagesPublished, Labels{TopicLabel: "edgex/events"}, 2)
+	registry.AddGauge(RequestsInFlight, labels, 2)
+	registry.AddGauge(RequestsInFlight, labels, -1)
+	registry.ObserveHistogram(PublishDuration, labels, 0.05)
+	registry.ObserveHistogram(PublishDuration, labels, 0.5)
+	registry.ObserveHistogram(PublishDuration, labels, 5)
+	// A metric can't change of kind once recorded
+	registry.AddGauge(MessagesPublished, labels, 10)
+
+	snapshot := registry.Snapshot()
+	require.Len(t, snapshot, 3)
+
+	assert.Equal(t, Metric{Name: MessagesPublished, Kind: Counter, Labels: labels, Value: 3}, snapshot[0])
+	assert.Equal(t, Metric{
+		Name:    PublishDuration,
+		Kind:    Histogram,
+		Labels:  labels,
+		Buckets: []Bucket{{UpperBound: 0.1, Count: 1}, {UpperBound: 1, Count: 2}},
+		Sum:     5.55,
+		Count:   3,
+	}, snapshot[1])
+	assert.Equal(t, Metric{Name: RequestsInFlight, Kind: Gauge, Labels: labels, Value: 1}, snapshot[2])
+}
+
+func TestRegistryMaxSeries(t *testing.T) {
+	registry := NewRegistryWithOptions(RegistryOptions{MaxSeries: 2})
+
+	for i := 0; i < 5; i++ {
+		registry.IncCounter(MessagesReceived, Labels{TopicLabel: fmt.Sprintf("edgex/response/%d", i)}, 1)
+	}
+	registry.IncCounter(MessagesReceived, Labels{TopicLabel: "edgex/response/0"}, 1)
+
+	snapshot := registry.Snapshot()
+	require.Len(t, snapshot, 2, "new label combinations must be ignored once MaxSeries is reached")
+	assert.Equal(t, float64(2), snapshot[0].Value, "the existing series must still be recorded")
+}
+
+func TestRegistryWritePrometheus(t *testing.T) {
+	registry := NewRegistryWithOptions(RegistryOptions{Buckets: []float64{0.1, 1}})
+	registry.IncCounter(MessagesPublished, Labels{TopicLabel: "edgex/events"}, 2)
+	registry.IncCounter(MessagesPublished, Labels{TopicLabel: `a"b\c`}, 1)
+	registry.AddGauge(Connections, nil, 1)
+	registry.ObserveHistogram(RequestDuration, Labels{TopicLabel: "edgex/requests"}, 0.5)
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, registry.WritePrometheus(buffer))
+
+	expected := `# TYPE edgex_messagebus_connections gauge
+edgex_messagebus_connections 1
+# TYPE edgex_messagebus_messages_published_total counter
+edgex_messagebus_messages_published_total{topic="a\"b\\c"} 1
+edgex_messagebus_messages_published_total{topic="edgex/events"} 2
+# TYPE edgex_messagebus_request_duration_seconds histogram
+edgex_messagebus_request_duration_seconds_bucket{topic="edgex/requests",le="0.1"} 0
+edgex_messagebus_request_duration_seconds_bucket{topic="edgex/requests",le="1"} 1
+edgex_messagebus_request_duration_seconds_bucket{topic="edgex/requests",le="+Inf"} 1
+edgex_messagebus_request_duration_seconds_sum{topic="edgex/requests"} 0.5
+edgex_messagebus_request_duration_seconds_count{topic="edgex/requests"} 1
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestRegistryHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.IncCounter(MessagesReceived, Labels{TopicLabel: "edgex/events"}, 1)
+
+	server := httptest.NewServer(registry.Handler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, prometheusContentType, response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `edgex_messagebus_messages_received_total{topic="edgex/events"} 1`)
+}
+
+func TestSetDefault(t *testing.T) {
+	defer SetDefault(DefaultRegistry)
+
+	registry := NewRegistry()
+	SetDefault(registry)
+	Default().IncCounter(MessagesPublished, nil, 1)
+	assert.Len(t, registry.Snapshot(), 1)
+
+	SetDefault(nil)
+	assert.NotPanics(t, func() { Default().IncCounter(MessagesPublished, nil, 1) })
+	assert.Len(t, registry.Snapshot(), 1)
+}
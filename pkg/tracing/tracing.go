@@ -0,0 +1,85 @@
+// Copyright (C) 2024 IOTech Ltd
+
+// Package tracing defines the hook the message clients trace the messages with, i.e. to record the spans with
+// OpenTelemetry. The trace context is propagated in the TraceParent and TraceState of the MessageEnvelope whether or
+// not a Tracer is set.
+package tracing
+
+import (
+	"sync/atomic"
+
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+)
+
+// Operation is the operation a span is started for.
+type Operation string
+
+const (
+	// Publish is the span of a message being published, from the client to the broker
+	Publish Operation = "publish"
+	// Deliver is the span of a message received from the broker being delivered to its subscription
+	Deliver Operation = "deliver"
+	// Process is the span of a received message being handled by the handler of a SubscribeFunc
+	Process Operation = "process"
+	// Request is the span of a request, from its publishing to the reception of its response
+	Request Operation = "request"
+)
+
+// Tracer starts the spans of the message clients. The implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start starts the span of the operation on the topic, which is the child of the span of parent unless parent is
+	// empty, i.e. the message isn't traced yet.
+	Start(operation Operation, topic string, parent types.TraceContext) Span
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// TraceContext returns the trace context identifying the span, which is propagated with the message so the spans
+	// of its next hops are its children.
+	TraceContext() types.TraceContext
+	// End ends the span, err being the error the operation failed with if not nil.
+	End(err error)
+}
+
+// tracerHolder allows storing the Tracer interface in an atomic.Pointer.
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var defaultTracer atomic.Pointer[tracerHolder]
+
+func init() {
+	SetDefault(nil)
+}
+
+// Default returns the Tracer the message clients trace the messages with.
+func Default() Tracer {
+	return defaultTracer.Load().tracer
+}
+
+// SetDefault replaces the Tracer the message clients trace the messages with. A nil Tracer, the default, doesn't
+// record any span but still propagates the trace context of the messages.
+func SetDefault(tracer Tracer) {
+	if tracer == nil {
+		tracer = nopTracer{}
+	}
+
+	defaultTracer.Store(&tracerHolder{tracer: tracer})
+}
+
+// nopTracer starts spans which propagate the trace context of their parent.
+type nopTracer struct{}
+
+func (nopTracer) Start(_ Operation, _ string, parent types.TraceContext) Span {
+	return nopSpan{parent: parent}
+}
+
+type nopSpan struct {
+	parent types.TraceContext
+}
+
+func (s nopSpan) TraceContext() types.TraceContext {
+	return s.parent
+}
+
+func (nopSpan) End(error) {}
@@ -0,0 +1,60 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"time"
+)
+
+// ConnectionState is the state of the connection of a MessageClient to the message bus.
+type ConnectionState int
+
+const (
+	// Disconnected is the state before Connect and after Disconnect, or once the connection was lost
+	Disconnected ConnectionState = iota
+	// Connected is the state once the connection is established
+	Connected
+	// Reconnecting is the state while the client attempts to re-establish a lost connection
+	Reconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connected:
+		return "Connected"
+	case Reconnecting:
+		return "Reconnecting"
+	default:
+		return "Disconnected"
+	}
+}
+
+// ConnectionEventType is the type of a ConnectionEvent.
+type ConnectionEventType string
+
+const (
+	// ConnectedEvent is emitted when the connection is established by Connect
+	ConnectedEvent ConnectionEventType = "Connected"
+	// DisconnectedEvent is emitted when the connection is closed by Disconnect or lost, the Err of a lost connection
+	// is its cause
+	DisconnectedEvent ConnectionEventType = "Disconnected"
+	// ReconnectingEvent is emitted when the client attempts to re-establish a lost connection
+	ReconnectingEvent ConnectionEventType = "Reconnecting"
+	// ReconnectedEvent is emitted when a lost connection is re-established
+	ReconnectedEvent ConnectionEventType = "Reconnected"
+	// SubscriptionRestoredEvent is emitted for every subscription restored after the connection was re-established,
+	// the Err is set if the subscription couldn't be restored
+	SubscriptionRestoredEvent ConnectionEventType = "SubscriptionRestored"
+)
+
+// ConnectionEvent is a change of the connection of a MessageClient to the message bus.
+type ConnectionEvent struct {
+	// Type is the type of the event
+	Type ConnectionEventType
+	// Time is when the event occurred
+	Time time.Time
+	// Topic is the topic of the subscription for a SubscriptionRestoredEvent
+	Topic string
+	// Err is the cause of the event, i.e. why the connection was lost, if known
+	Err error
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeadLetter is the payload of the messages published to the DeadLetterTopic. It wraps a received message which
+// couldn't be processed, i.e. couldn't be decoded or was rejected by its handler.
+type DeadLetter struct {
+	// Topic is the topic the message was received on
+	Topic string `json:"topic"`
+	// Reason is the error which prevented the message from being processed
+	Reason string `json:"reason"`
+	// Timestamp is the time the message was dead-lettered
+	Timestamp time.Time `json:"timestamp"`
+	// ContentType is the content type of the payload, empty when the message couldn't be decoded
+	ContentType string `json:"contentType,omitempty"`
+	// Payload is the payload of the message, or the raw bytes received when the message couldn't be decoded
+	Payload []byte `json:"payload"`
+}
+
+// DeadLetterError is returned by a MessageHandler to have the message published to the DeadLetterTopic, see
+// NewDeadLetterError.
+type DeadLetterError struct {
+	// Err is the reason the message is dead-lettered
+	Err error
+}
+
+// NewDeadLetterError wraps the error returned by a MessageHandler so that the message is published to the
+// DeadLetterTopic, if one is configured. The error is passed to the ErrorHandler of the subscription as usual.
+func NewDeadLetterError(err error) error {
+	return &DeadLetterError{Err: err}
+}
+
+func (e *DeadLetterError) Error() string {
+	return fmt.Sprintf("message dead-lettered: %v", e.Err)
+}
+
+func (e *DeadLetterError) Unwrap() error {
+	return e.Err
+}
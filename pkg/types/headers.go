@@ -0,0 +1,34 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"context"
+)
+
+type headersKey struct{}
+
+// ContextWithHeaders returns a copy of ctx carrying the headers, which NewMessageEnvelope sets on the messages created
+// with the returned context. The headers set on ctx before are replaced.
+func ContextWithHeaders(ctx context.Context, headers map[string][]string) context.Context {
+	return context.WithValue(ctx, headersKey{}, cloneHeaders(headers))
+}
+
+// HeadersFromContext returns a copy of the headers carried by ctx, nil if it doesn't carry any.
+func HeadersFromContext(ctx context.Context) map[string][]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string][]string)
+	return cloneHeaders(headers)
+}
+
+func cloneHeaders(headers map[string][]string) map[string][]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	cloned := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		cloned[key] = append([]string(nil), values...)
+	}
+
+	return cloned
+}
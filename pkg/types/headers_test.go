@@ -0,0 +1,37 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessageEnvelopeHeaders(t *testing.T) {
+	headers := map[string][]string{"route": {"core-data:events", "app"}, "Tenant": {"a"}}
+	ctx := ContextWithHeaders(context.Background(), headers)
+	headers["route"][0] = "modified"
+
+	envelope := NewMessageEnvelope([]byte(testPayload), ctx)
+	expected := map[string][]string{"route": {"core-data:events", "app"}, "Tenant": {"a"}}
+	assert.Equal(t, expected, envelope.Headers, "the headers must be copied")
+
+	envelope.Headers["Tenant"] = append(envelope.Headers["Tenant"], "b")
+	assert.Equal(t, expected, HeadersFromContext(ctx), "the headers of the context must not be shared")
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	var decoded MessageEnvelope
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, envelope.Headers, decoded.Headers)
+
+	envelope = NewMessageEnvelope([]byte(testPayload), context.Background())
+	assert.Nil(t, envelope.Headers)
+	data, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "headers", "the headers must be omitted when not set")
+}
@@ -47,16 +47,36 @@ type MessageEnvelope struct {
 	ContentType string `json:"contentType"`
 	// QueryParams is optionally provided key/value pairs.
 	QueryParams map[string]string `json:"queryParams,omitempty"`
+	// Headers is optionally provided metadata, i.e. for routing the message, with one or more values per key. The keys
+	// are case-sensitive.
+	Headers map[string][]string `json:"headers,omitempty"`
+	// TraceParent is the W3C traceparent of the span the message was sent from, empty if the message isn't traced.
+	TraceParent string `json:"traceParent,omitempty"`
+	// TraceState is the W3C tracestate propagated along with TraceParent.
+	TraceState string `json:"traceState,omitempty"`
+	// ContentEncoding is the compression the Payload is encoded with, i.e. gzip or zstd, empty if it isn't compressed.
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// EncryptionKeyID is the id of the key the Payload is encrypted with by AES-GCM, empty if the Payload is plaintext.
+	EncryptionKeyID string `json:"encryptionKeyId,omitempty"`
+	// Signer is the identity of the publisher which signed the message. It is only set on the received messages once
+	// their Signature is verified with a key of this identity.
+	Signer string `json:"signer,omitempty"`
+	// Signature is the signature of the message by the Signer, which is removed from the received messages once verified.
+	Signature []byte `json:"signature,omitempty"`
 }
 
 // NewMessageEnvelope creates a new MessageEnvelope for the specified payload with attributes from the specified context
 func NewMessageEnvelope(payload []byte, ctx context.Context) MessageEnvelope {
+	traceContext := TraceContextFromContext(ctx)
 	envelope := MessageEnvelope{
 		Versionable:   commonDTO.NewVersionable(),
 		CorrelationID: fromContext(ctx, common.CorrelationHeader),
 		ContentType:   fromContext(ctx, common.ContentType),
 		Payload:       payload,
 		QueryParams:   make(map[string]string),
+		Headers:       HeadersFromContext(ctx),
+		TraceParent:   traceContext.TraceParent,
+		TraceState:    traceContext.TraceState,
 	}
 
 	return envelope
@@ -0,0 +1,73 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import "errors"
+
+// ErrSubscriptionOverflow is wrapped by the error reported for a message dropped by the OverflowError policy.
+var ErrSubscriptionOverflow = errors.New("subscription overflow")
+
+// Subscription is the handle of a subscription to a topic. Several subscriptions to the same topic share the
+// subscription on the broker, which is only removed once the last of them is unsubscribed.
+type Subscription interface {
+	// Topic returns the topic of the subscription
+	Topic() string
+	// Unsubscribe removes the subscription. Calling Unsubscribe more than once has no effect.
+	Unsubscribe() error
+	// Stats returns the statistics of the subscription
+	Stats() SubscriptionStats
+	// Done returns a channel which is closed once the subscription is removed, either by Unsubscribe or when
+	// the topic is unsubscribed from the message client.
+	Done() <-chan struct{}
+}
+
+// SubscriptionStats contains the statistics of a Subscription
+type SubscriptionStats struct {
+	// Received is the number of messages delivered to the subscription
+	Received uint64
+	// Errors is the number of errors delivered to the subscription
+	Errors uint64
+	// Dropped is the number of messages dropped by the overflow policy of the subscription
+	Dropped uint64
+}
+
+// OverflowPolicy defines what happens to a message received for a subscription whose Messages channel is full, i.e.
+// the subscriber doesn't receive the messages fast enough.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber receives the message. Depending on the message client, this also stalls
+	// the delivery of the messages of the other subscriptions.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the received message.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest message buffered in the Messages channel to make room for the received
+	// message, the capacity of the channel bounds the buffer. The received message is dropped if the channel isn't
+	// buffered.
+	OverflowDropOldest
+	// OverflowError drops the received message like OverflowDropNewest and reports an error wrapping
+	// ErrSubscriptionOverflow on the messageErrors channel, unless that channel is full too.
+	OverflowError
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowError:
+		return "error"
+	default:
+		return "block"
+	}
+}
+
+// Backpressure defines how a subscription handles the messages received while its subscriber is busy.
+type Backpressure struct {
+	// Policy is the overflow policy of the subscription, OverflowBlock when not set
+	Policy OverflowPolicy
+	// OnOverflow is invoked with every message dropped by the policy. It must not block, as it is invoked while
+	// receiving the messages, and may be invoked concurrently.
+	OnOverflow func(dropped MessageEnvelope)
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// TraceParentHeader is the W3C Trace Context header identifying the trace and the parent span of a message
+	TraceParentHeader = "traceparent"
+	// TraceStateHeader is the W3C Trace Context header carrying the vendor-specific trace information
+	TraceStateHeader = "tracestate"
+)
+
+// TraceContext is the W3C Trace Context propagated with a message, which links the spans of the services the message
+// goes through into a distributed trace.
+type TraceContext struct {
+	// TraceParent is the traceparent header, i.e. 00-<trace-id>-<parent-id>-<trace-flags>
+	TraceParent string
+	// TraceState is the tracestate header, which is ignored unless TraceParent is valid
+	TraceState string
+}
+
+// IsValid reports whether TraceParent is a valid traceparent header as specified by W3C Trace Context.
+func (tc TraceContext) IsValid() bool {
+	fields := strings.Split(tc.TraceParent, "-")
+	if len(fields) < 4 {
+		return false
+	}
+
+	version, traceID, parentID, flags := fields[0], fields[1], fields[2], fields[3]
+	switch {
+	case !isLowerHex(version, 2) || version == "ff":
+		return false
+	case version == "00" && len(fields) != 4:
+		// Only the future versions may append fields
+		return false
+	case !isLowerHex(traceID, 32) || strings.Trim(traceID, "0") == "":
+		return false
+	case !isLowerHex(parentID, 16) || strings.Trim(parentID, "0") == "":
+		return false
+	default:
+		return isLowerHex(flags, 2)
+	}
+}
+
+// TraceID returns the trace-id of TraceParent, or an empty string if TraceParent isn't valid.
+func (tc TraceContext) TraceID() string {
+	if !tc.IsValid() {
+		return ""
+	}
+
+	return strings.Split(tc.TraceParent, "-")[1]
+}
+
+// SpanID returns the parent-id of TraceParent, i.e. the span the message was sent from, or an empty string if
+// TraceParent isn't valid.
+func (tc TraceContext) SpanID() string {
+	if !tc.IsValid() {
+		return ""
+	}
+
+	return strings.Split(tc.TraceParent, "-")[2]
+}
+
+// valid returns the TraceContext if TraceParent is valid, an empty one otherwise.
+func (tc TraceContext) valid() TraceContext {
+	if !tc.IsValid() {
+		return TraceContext{}
+	}
+
+	return tc
+}
+
+// TraceContext returns the trace context of the message, empty if the message doesn't carry a valid one.
+func (m MessageEnvelope) TraceContext() TraceContext {
+	return TraceContext{TraceParent: m.TraceParent, TraceState: m.TraceState}.valid()
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying the trace context, i.e. the trace context of a received
+// message, which NewMessageEnvelope propagates to the messages created with the returned context.
+func ContextWithTraceContext(ctx context.Context, traceContext TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext)
+}
+
+// TraceContextFromContext returns the trace context carried by ctx, set by ContextWithTraceContext or otherwise
+// under the TraceParentHeader and TraceStateHeader keys like the other headers. The trace context is empty if ctx
+// doesn't carry a valid one.
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	if traceContext, ok := ctx.Value(traceContextKey{}).(TraceContext); ok {
+		return traceContext.valid()
+	}
+
+	return TraceContext{
+		TraceParent: fromContext(ctx, TraceParentHeader),
+		TraceState:  fromContext(ctx, TraceStateHeader),
+	}.valid()
+}
+
+func isLowerHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2024 IOTech Ltd
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceState  = "vendor=value"
+)
+
+func TestTraceContextIsValid(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceParent string
+		valid       bool
+	}{
+		{"valid", testTraceParent, true},
+		{"future version with more fields", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"empty", "", false},
+		{"version 00 with more fields", testTraceParent + "-extra", false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"upper case", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"zero trace-id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"zero parent-id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"short trace-id", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false},
+		{"invalid flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-x1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.valid, TraceContext{TraceParent: test.traceParent}.IsValid())
+		})
+	}
+
+	traceContext := TraceContext{TraceParent: testTraceParent}
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceContext.TraceID())
+	assert.Equal(t, "00f067aa0ba902b7", traceContext.SpanID())
+	assert.Empty(t, TraceContext{}.TraceID())
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	expected := TraceContext{TraceParent: testTraceParent, TraceState: testTraceState}
+
+	ctx := ContextWithTraceContext(context.Background(), expected)
+	assert.Equal(t, expected, TraceContextFromContext(ctx))
+
+	// lint:ignore SA1029 legacy
+	// nolint:staticcheck // See golangci-lint #741
+	ctx = context.WithValue(context.Background(), TraceParentHeader, testTraceParent)
+	// lint:ignore SA1029 legacy
+	// nolint:staticcheck // See golangci-lint #741
+	ctx = context.WithValue(ctx, TraceStateHeader, testTraceState)
+	assert.Equal(t, expected, TraceContextFromContext(ctx))
+
+	ctx = ContextWithTraceContext(context.Background(), TraceContext{TraceParent: "invalid", TraceState: testTraceState})
+	assert.Equal(t, TraceContext{}, TraceContextFromContext(ctx), "the trace state must be ignored without a valid trace parent")
+	assert.Equal(t, TraceContext{}, TraceContextFromContext(context.Background()))
+}
+
+func TestNewMessageEnvelopeTraceContext(t *testing.T) {
+	expected := TraceContext{TraceParent: testTraceParent, TraceState: testTraceState}
+
+	envelope := NewMessageEnvelope([]byte(testPayload), ContextWithTraceContext(context.Background(), expected))
+	assert.Equal(t, testTraceParent, envelope.TraceParent)
+	assert.Equal(t, testTraceState, envelope.TraceState)
+	assert.Equal(t, expected, envelope.TraceContext())
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	var decoded MessageEnvelope
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, expected, decoded.TraceContext())
+
+	data, err = json.Marshal(NewMessageEnvelope([]byte(testPayload), context.Background()))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "traceParent", "the trace context must be omitted when the message isn't traced")
+}
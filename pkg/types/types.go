@@ -22,6 +22,27 @@ type TopicChannel struct {
 	Topic string
 	// Messages is the returned message channel for the subscriber
 	Messages chan MessageEnvelope
+	// Backpressure defines what happens to the messages received while Messages is full, they are waited for by default
+	Backpressure Backpressure
+}
+
+// MessageHandler processes a message received for a subscription created with SubscribeFunc. The error returned
+// by the handler is passed to the ErrorHandler of the subscription.
+type MessageHandler func(message MessageEnvelope) error
+
+// SubscribeOptions defines how the messages of a subscription created with SubscribeFunc are dispatched.
+type SubscribeOptions struct {
+	// Concurrency is the number of workers invoking the handler for the subscription. Values lower than 2
+	// invoke the handler serially in the order the messages are received.
+	Concurrency int
+	// ErrorHandler receives the errors returned by the handler and the errors encountered receiving messages for
+	// the subscription. Errors are discarded when not set.
+	ErrorHandler func(err error)
+	// BufferSize is the number of received messages buffered while the workers are busy, Concurrency when lower
+	BufferSize int
+	// Backpressure defines what happens to the messages received while the buffer is full, they are waited for by
+	// default. The errors of the OverflowError policy are passed to the ErrorHandler.
+	Backpressure Backpressure
 }
 
 // MessageBusConfig defines the messaging information need to connect to the message bus